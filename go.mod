@@ -3,11 +3,9 @@ module gote
 go 1.24.4
 
 require (
+	github.com/kljensen/snowball v0.10.0
 	github.com/yuin/goldmark v1.7.13
 	golang.org/x/term v0.39.0
 )
 
-require (
-	github.com/kljensen/snowball v0.10.0 // indirect
-	golang.org/x/sys v0.40.0 // indirect
-)
+require golang.org/x/sys v0.40.0 // indirect
@@ -3,6 +3,8 @@ package cli
 import (
 	"strconv"
 	"strings"
+
+	"gote/src/data"
 )
 
 // Args provides a simple, consistent way to parse command-line arguments.
@@ -38,7 +40,11 @@ func ParseArgs(args []string) Args {
 				i++
 				values = append(values, args[i])
 			}
-			a.flags[name] = values
+			// Repeated flags accumulate (-p a -p b)
+			a.flags[name] = append(a.flags[name], values...)
+			if a.flags[name] == nil {
+				a.flags[name] = values
+			}
 		} else {
 			a.Positional = append(a.Positional, arg)
 		}
@@ -90,7 +96,8 @@ func (a Args) IntOr(def int, names ...string) int {
 	return def
 }
 
-// List returns all values for the given flag names.
+// List returns all values for the given flag names. A repeated flag
+// (-p a -p b) returns the values of every occurrence.
 func (a Args) List(names ...string) []string {
 	for _, name := range names {
 		if vals, ok := a.flags[name]; ok {
@@ -138,8 +145,7 @@ func ParseTagString(s string) []string {
 	parts := strings.Split(s, ".")
 	var tags []string
 	for _, p := range parts {
-		t := strings.ToLower(strings.TrimSpace(p))
-		if t != "" {
+		if t := data.NormalizeTag(p); t != "" {
 			tags = append(tags, t)
 		}
 	}
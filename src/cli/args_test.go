@@ -91,6 +91,7 @@ func TestParseArgs_ListFlags(t *testing.T) {
 		{"stops at next flag", []string{"-t", "foo", "bar", "-n", "5"}, "t", []string{"foo", "bar"}},
 		{"empty list", []string{"-t"}, "t", []string{}},
 		{"missing flag", []string{"foo"}, "t", nil},
+		{"repeated flag", []string{"-p", "a=1", "-n", "5", "-p", "b=2"}, "p", []string{"a=1", "b=2"}},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"gote/src/core"
+)
+
+// appendFlagArity lists the flags AppendCommand understands; everything
+// else, dashes included, is text to add
+var appendFlagArity = map[string]string{
+	"timestamp": "bool", "ts": "bool",
+	"create": "bool", "c": "bool",
+}
+
+// AppendCommand runs "gote append <note> [text]", or "gote prepend" if
+// prepend is set. With no text the text is read from stdin, so command
+// output can be logged straight into a note.
+func AppendCommand(rawArgs []string, prepend bool) {
+	args := splitFlagArgs(rawArgs, appendFlagArity)
+	verb := "append"
+	if prepend {
+		verb = "prepend"
+	}
+	if args.First() == "" {
+		fmt.Printf("Usage: gote %s <note> [text] [--timestamp] [--create]\n", verb)
+		fmt.Println("       Reads the text from stdin when none is given")
+		return
+	}
+
+	_, ui, ok := LoadConfigAndUI()
+	if !ok {
+		return
+	}
+	noteName, err := ResolveNoteName(args.First())
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+
+	text := strings.Join(args.Rest(), " ")
+	if text == "" {
+		if text, err = readStdin(); err != nil {
+			ui.Error(err.Error())
+			return
+		}
+	}
+
+	name, err := core.AddToNote(noteName, text, core.AddOptions{
+		Prepend:   prepend,
+		Timestamp: args.Has("timestamp", "ts"),
+		Create:    args.Has("create", "c"),
+	})
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	if prepend {
+		ui.Success("Prepended to " + name)
+	} else {
+		ui.Success("Appended to " + name)
+	}
+}
+
+// newNoteFromStdin runs "gote new <note> --stdin": the note is created with
+// what's piped in, after the template if one is given, and not opened
+func newNoteFromStdin(noteName, templateName, mode string, ui *UI) {
+	text, err := readStdin()
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	name, err := core.CreateNoteWithText(noteName, templateName, mode, text)
+	if errors.Is(err, core.ErrNoteExists) {
+		ui.Error(err.Error() + " (use gote append to add to it)")
+		return
+	} else if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	ui.Success("Created " + name)
+}
+
+// readStdin reads all of stdin, telling the user how to finish when it's
+// a terminal rather than a pipe
+func readStdin() (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintln(os.Stderr, "Reading from stdin; finish with Ctrl-D")
+	}
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("error reading stdin: %w", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,437 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/term"
+
+	"gote/src/data"
+)
+
+// CatCommand prints a note rendered for the terminal: styled and wrapped to
+// its width, through $PAGER when longer than the screen, or as plain text
+// when stdout isn't a terminal
+func CatCommand(rawArgs []string) {
+	args := ParseArgs(rawArgs)
+	noteName := args.Joined()
+
+	_, ui, ok := LoadConfigAndUI()
+	if !ok {
+		return
+	}
+	if noteName == "" {
+		ui.Info("Usage: gote cat <note> [--raw] [--no-pager]")
+		return
+	}
+	noteName, err := ResolveNoteName(noteName)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+
+	index, err := data.LoadIndex()
+	if err != nil {
+		ui.Error("Error loading index: " + err.Error())
+		return
+	}
+	_, meta, exists := data.LookupNoteFuzzy(index, noteName)
+	if !exists {
+		ui.Error("Note not found: " + noteName)
+		return
+	}
+	content, err := os.ReadFile(meta.FilePath)
+	if err != nil {
+		ui.Error("Error reading note: " + err.Error())
+		return
+	}
+	if args.Has("raw") {
+		os.Stdout.Write(content)
+		return
+	}
+
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		fmt.Print(renderMarkdown(content, 0, false))
+		return
+	}
+	width, height, err := term.GetSize(fd)
+	if err != nil || width <= 0 {
+		width, height = 80, 0
+	}
+	out := renderMarkdown(content, width, true)
+	if height > 0 && strings.Count(out, "\n") >= height && !args.Has("no-pager") {
+		if err := runPager(out); err == nil {
+			return
+		}
+	}
+	fmt.Print(out)
+}
+
+// runPager shows text in $PAGER, or less. Like git, it lets less pass colors
+// through and quit at once if the text fits after all.
+func runPager(text string) error {
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less"}
+	}
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// renderMarkdown renders a note for the terminal, wrapped to width (0 leaves
+// lines unwrapped), with ANSI styling if color is set. Front matter is left
+// out and a leading tag line is shown dimmed.
+func renderMarkdown(content []byte, width int, color bool) string {
+	_, body, _ := data.SplitFrontMatter(string(content))
+	r := &termRenderer{width: width, color: color}
+
+	var lines []string
+	if first, rest, _ := strings.Cut(body, "\n"); data.ParseTags(strings.TrimSpace(first)) != nil {
+		lines = append(lines, r.style(Dim, strings.TrimSpace(first)))
+		body = rest
+	}
+
+	r.source = []byte(body)
+	doc := newMarkdown().Parser().Parse(text.NewReader(r.source))
+	if blocks := r.blocks(doc, width); len(blocks) > 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, blocks...)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// termRenderer turns a goldmark AST into lines of terminal text
+type termRenderer struct {
+	source []byte
+	width  int
+	color  bool
+}
+
+// style wraps text in an ANSI code. Styles already inside text are restored
+// after their reset, so nested styles add up.
+func (r *termRenderer) style(code, text string) string {
+	if !r.color || text == "" {
+		return text
+	}
+	return code + strings.ReplaceAll(text, Reset, Reset+code) + Reset
+}
+
+// blocks renders the block children of parent, a blank line between them
+// except after the text of a tight list item
+func (r *termRenderer) blocks(parent ast.Node, width int) []string {
+	var out []string
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		lines := r.block(n, width)
+		if len(lines) == 0 {
+			continue
+		}
+		if len(out) > 0 && n.PreviousSibling().Kind() != ast.KindTextBlock {
+			out = append(out, "")
+		}
+		out = append(out, lines...)
+	}
+	return out
+}
+
+func (r *termRenderer) block(n ast.Node, width int) []string {
+	switch n := n.(type) {
+	case *ast.Heading:
+		title := r.inline(n)
+		code := Bold
+		if n.Level <= 2 {
+			code = BoldCyan
+		}
+		lines := wrapANSI(r.style(code, title), width)
+		// Top headings are underlined, so they stand out in plain text too
+		if n.Level <= 2 {
+			rule := "═"
+			if n.Level == 2 {
+				rule = "─"
+			}
+			w := displayWidth(title)
+			if width > 0 {
+				w = min(w, width)
+			}
+			lines = append(lines, r.style(Dim, strings.Repeat(rule, w)))
+		}
+		return lines
+	case *ast.Paragraph, *ast.TextBlock:
+		return wrapANSI(r.inline(n), width)
+	case *ast.ThematicBreak:
+		w := width
+		if w <= 0 {
+			w = 40
+		}
+		return []string{r.style(Dim, strings.Repeat("─", w))}
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		// Code is indented, never wrapped
+		var lines []string
+		if fenced, ok := n.(*ast.FencedCodeBlock); ok && fenced.Info != nil {
+			if lang := string(fenced.Language(r.source)); lang != "" {
+				lines = append(lines, "    "+r.style(Dim, lang))
+			}
+		}
+		for _, line := range r.rawLines(n) {
+			lines = append(lines, "    "+r.style(Cyan, line))
+		}
+		return lines
+	case *ast.HTMLBlock:
+		var lines []string
+		for _, line := range r.rawLines(n) {
+			lines = append(lines, r.style(Dim, line))
+		}
+		return lines
+	case *ast.Blockquote:
+		bar := r.style(Dim, "│")
+		lines := r.blocks(n, shrink(width, 2))
+		for i, line := range lines {
+			if line == "" {
+				lines[i] = bar
+			} else {
+				lines[i] = bar + " " + line
+			}
+		}
+		return lines
+	case *ast.List:
+		return r.list(n, width)
+	case *east.Table:
+		return r.table(n)
+	}
+	return r.blocks(n, width)
+}
+
+// list renders a list with bullets or numbers, its items' further lines
+// indented under their text
+func (r *termRenderer) list(l *ast.List, width int) []string {
+	var out []string
+	num := l.Start
+	for item := l.FirstChild(); item != nil; item = item.NextSibling() {
+		marker := "• "
+		if l.IsOrdered() {
+			marker = fmt.Sprintf("%d%c ", num, l.Marker)
+			num++
+		}
+		indent := strings.Repeat(" ", displayWidth(marker))
+		if !l.IsTight && len(out) > 0 {
+			out = append(out, "")
+		}
+		for i, line := range r.blocks(item, shrink(width, len(indent))) {
+			switch {
+			case i == 0:
+				line = r.style(Cyan, marker) + line
+			case line != "":
+				line = indent + line
+			}
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// table renders a table with its columns padded and aligned. Wide tables
+// aren't wrapped.
+func (r *termRenderer) table(t *east.Table) []string {
+	var rows [][]string
+	var widths []int
+	for row := t.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			text := strings.ReplaceAll(r.inline(cell), "\n", " ")
+			col := len(cells)
+			if col == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[col] = max(widths[col], displayWidth(text))
+			cells = append(cells, text)
+		}
+		rows = append(rows, cells)
+	}
+
+	sep := r.style(Dim, " │ ")
+	var lines []string
+	for i, cells := range rows {
+		padded := make([]string, len(widths))
+		for col := range widths {
+			cell := ""
+			if col < len(cells) {
+				cell = cells[col]
+			}
+			align := east.AlignNone
+			if col < len(t.Alignments) {
+				align = t.Alignments[col]
+			}
+			padded[col] = pad(cell, widths[col], align)
+			if i == 0 {
+				padded[col] = r.style(Bold, padded[col])
+			}
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(padded, sep), " "))
+		if i == 0 {
+			rules := make([]string, len(widths))
+			for col, w := range widths {
+				rules[col] = strings.Repeat("─", w)
+			}
+			lines = append(lines, r.style(Dim, strings.Join(rules, "─┼─")))
+		}
+	}
+	return lines
+}
+
+// inline renders the inline children of parent as one styled string.
+// Hard line breaks become newlines.
+func (r *termRenderer) inline(parent ast.Node) string {
+	var b strings.Builder
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		switch n := n.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(r.source))
+			if n.HardLineBreak() {
+				b.WriteString("\n")
+			} else if n.SoftLineBreak() {
+				b.WriteString(" ")
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *ast.CodeSpan:
+			b.WriteString(r.style(Cyan, r.inline(n)))
+		case *ast.Emphasis:
+			code := Italic
+			if n.Level >= 2 {
+				code = Bold
+			}
+			b.WriteString(r.style(code, r.inline(n)))
+		case *east.Strikethrough:
+			b.WriteString(r.style(Strike, r.inline(n)))
+		case *east.TaskCheckBox:
+			box := "[ ]"
+			if n.IsChecked {
+				box = "[x]"
+			}
+			b.WriteString(r.style(Cyan, box) + " ")
+		case *ast.Link:
+			label := r.inline(n)
+			b.WriteString(r.style(Underline, label))
+			if dest := string(n.Destination); dest != "" && dest != label {
+				b.WriteString(r.style(Dim, " ("+dest+")"))
+			}
+		case *ast.AutoLink:
+			b.WriteString(r.style(Underline, string(n.Label(r.source))))
+		case *ast.Image:
+			b.WriteString(r.style(Dim, "[image: "+r.inline(n)+"] ("+string(n.Destination)+")"))
+		case *ast.RawHTML:
+			for i := 0; i < n.Segments.Len(); i++ {
+				seg := n.Segments.At(i)
+				b.WriteString(r.style(Dim, string(seg.Value(r.source))))
+			}
+		default:
+			b.WriteString(r.inline(n))
+		}
+	}
+	return b.String()
+}
+
+// rawLines are the source lines of a code or HTML block, without newlines
+func (r *termRenderer) rawLines(n ast.Node) []string {
+	var b strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		b.Write(seg.Value(r.source))
+	}
+	if html, ok := n.(*ast.HTMLBlock); ok && html.HasClosure() {
+		b.Write(html.ClosureLine.Value(r.source))
+	}
+	return strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+}
+
+// wrapANSI splits text into lines of at most width visible characters,
+// breaking between words. Styles open at a break are closed at the end of
+// the line and reopened on the next, so prefixes added to each line stay
+// unstyled. Width 0 only splits at newlines.
+func wrapANSI(text string, width int) []string {
+	var lines []string
+	active := "" // styles open at the end of the previous line
+	flush := func(line string) {
+		state := ""
+		for _, code := range ansiRegex.FindAllString(line, -1) {
+			if code == Reset {
+				state = ""
+			} else {
+				state += code
+			}
+		}
+		if state != "" {
+			line += Reset
+		}
+		active = state
+		lines = append(lines, line)
+	}
+
+	for _, para := range strings.Split(text, "\n") {
+		if width <= 0 {
+			flush(active + para)
+			continue
+		}
+		line, n := active, 0
+		for _, word := range strings.Fields(para) {
+			w := displayWidth(word)
+			if n > 0 && n+1+w > width {
+				flush(line)
+				line, n = active, 0
+			}
+			if n > 0 {
+				line += " "
+				n++
+			}
+			line += word
+			n += w
+		}
+		flush(line)
+	}
+	return lines
+}
+
+// displayWidth is the number of characters text takes up on screen, not
+// counting ANSI codes
+func displayWidth(text string) int {
+	return utf8.RuneCountInString(ansiRegex.ReplaceAllString(text, ""))
+}
+
+// pad pads text with spaces to width, per a table column's alignment
+func pad(text string, width int, align east.Alignment) string {
+	gap := max(width-displayWidth(text), 0)
+	switch align {
+	case east.AlignRight:
+		return strings.Repeat(" ", gap) + text
+	case east.AlignCenter:
+		return strings.Repeat(" ", gap/2) + text + strings.Repeat(" ", gap-gap/2)
+	}
+	return text + strings.Repeat(" ", gap)
+}
+
+// shrink narrows a wrap width by n columns, leaving 0 (no wrapping) alone
+func shrink(width, n int) int {
+	if width <= 0 {
+		return 0
+	}
+	return max(width-n, 10)
+}
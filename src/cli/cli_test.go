@@ -2,12 +2,17 @@ package cli
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"gote/src/core"
 	"gote/src/data"
 )
 
@@ -258,6 +263,31 @@ func TestTagCommand(t *testing.T) {
 		}
 	})
 
+	t.Run("edits parse the same with and without --dry-run", func(t *testing.T) {
+		read := func() string {
+			content, _ := os.ReadFile(filepath.Join(notesDir, "note3.md"))
+			return string(content)
+		}
+		output := captureOutput(func() {
+			TagCommand([]string{"rename", ".personal", ".home", "--dry-run"}, ActionDefaults{})
+		})
+		if !strings.Contains(output, "Would change 1 notes") || !strings.HasPrefix(read(), ".personal") {
+			t.Errorf("dry run:\n%s", output)
+		}
+		captureOutput(func() {
+			TagCommand([]string{"rename", ".personal", ".home"}, ActionDefaults{})
+		})
+		if !strings.HasPrefix(read(), ".home\n") {
+			t.Errorf("rename not applied: %q", read())
+		}
+		captureOutput(func() {
+			TagCommand([]string{"delete", ".home"}, ActionDefaults{})
+		})
+		if read() != "Content" {
+			t.Errorf("delete not applied: %q", read())
+		}
+	})
+
 	// Note: Tag filtering tests are skipped because they now use interactive menus
 	// that wait for user input. The core filtering logic is tested in core package.
 }
@@ -351,6 +381,19 @@ func TestTrashCommands(t *testing.T) {
 		}
 	})
 
+	t.Run("RecoverCommand without a name asks first", func(t *testing.T) {
+		output := withStdin("q\n", func() {
+			RecoverCommand(nil)
+		})
+
+		if strings.Contains(output, "recovered") {
+			t.Errorf("recovered without being asked: %s", output)
+		}
+		if !strings.Contains(output, "trash-me") {
+			t.Errorf("expected the trashed note in a menu, got: %s", output)
+		}
+	})
+
 	t.Run("RecoverCommand recovers note", func(t *testing.T) {
 		output := captureOutput(func() {
 			RecoverCommand([]string{"trash-me"})
@@ -409,6 +452,97 @@ func TestSearchCommand(t *testing.T) {
 			t.Errorf("Expected no results message, got: %s", output)
 		}
 	})
+
+	t.Run("syntax error is reported", func(t *testing.T) {
+		output := captureOutput(func() {
+			SearchCommand([]string{"(alpha"}, ActionDefaults{})
+		})
+
+		if !strings.Contains(output, "missing closing )") {
+			t.Errorf("Expected syntax error message, got: %s", output)
+		}
+	})
+}
+
+func TestBuildSearchQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"plain words", []string{"weekly", "sync"}, "weekly sync"},
+		{"exclusion is not a flag", []string{"plan", "-draft"}, "plan -draft"},
+		{"shell-quoted query", []string{"weekly sync"}, "weekly sync"},
+		{"README phrase and exclusion", []string{`"release plan" -draft`}, `"release plan" -draft`},
+		{"README grouping", []string{"standup (tag:work OR tag:team)"}, "standup (tag:work OR tag:team)"},
+		{"README field filters", []string{"title:retro created:2410..2412"}, "title:retro created:2410..2412"},
+		{"README properties", []string{"-p", "status=open", "-p", "owner=alice"}, "prop:status=open prop:owner=alice"},
+		{"multi-word title flag", []string{"--title", "weekly sync"}, `title:"weekly sync"`},
+		{"tags flag", []string{"plan", "-t", ".work.home"}, "plan (tag:work OR tag:home)"},
+		{"exact tags", []string{"-t", ".work/acme", "--exact"}, "(tag:=work/acme)"},
+		{"date flag", []string{"-w", "2410", "2412", "-m"}, "modified:2410..2412"},
+		{"title flag", []string{"--title", "retro", "-n", "5"}, "title:retro"},
+		{"notebook flag", []string{"plan", "--notebook", "work/team"}, "plan notebook:work/team"},
+		{"property flags", []string{"-p", "status=open", "-p", "owner=alice smith"}, `prop:status=open prop:"owner=alice smith"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := splitSearchArgs(tt.args)
+			got := searchQuery(args, args.TagList("t", "tags"))
+			if got != tt.want {
+				t.Errorf("query = %q, want %q", got, tt.want)
+			}
+			if _, err := core.ParseQuery(got); err != nil {
+				t.Errorf("ParseQuery(%q) failed: %v", got, err)
+			}
+		})
+	}
+}
+
+func TestPeriodOffset(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    int
+		wantErr bool
+	}{
+		{nil, 0, false},
+		{[]string{"-1"}, -1, false},
+		{[]string{"+2"}, 2, false},
+		{[]string{"--prev"}, -1, false},
+		{[]string{"--prev", "3"}, -3, false},
+		{[]string{"--next"}, 1, false},
+		{[]string{"--next", "x"}, 0, true},
+		{[]string{"soon"}, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := periodOffset(ParseArgs(tt.args))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("periodOffset(%v) = %d, %v; want %d", tt.args, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormatSnippet(t *testing.T) {
+	snip := core.Snippet{Line: 4, Text: "buy milk today", Matches: [][2]int{{4, 8}}}
+	if got := formatSnippet(snip, "minimal"); got != "4: buy *milk* today" {
+		t.Errorf("minimal = %q", got)
+	}
+	got := formatSnippet(snip, "tui")
+	if !strings.Contains(got, BoldCyan+"milk"+Reset) {
+		t.Errorf("tui snippet not highlighted: %q", got)
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	in := `<p>Planning &amp; <a href="plan.html">plans</a></p>`
+	want := `<p><mark>Planning</mark> &amp; <a href="plan.html"><mark>plans</mark></a></p>`
+	if got := highlightHTML(in, []string{"plan"}); got != want {
+		t.Errorf("highlightHTML =\n%s\nwant\n%s", got, want)
+	}
+	if got := highlightHTML(in, []string{"amp"}); got != in {
+		t.Errorf("entity should not be highlighted: %s", got)
+	}
 }
 
 // Note: RecentCommand now uses interactive menus. Core logic tested in core package.
@@ -454,3 +588,467 @@ func TestInfoCommand(t *testing.T) {
 		}
 	})
 }
+
+func TestExtractOutputFlags(t *testing.T) {
+	defer func() { outputFormat = FormatText }()
+
+	tests := []struct {
+		args   []string
+		rest   []string
+		format string
+	}{
+		{[]string{"r", "5"}, []string{"r", "5"}, FormatText},
+		{[]string{"--json", "r"}, []string{"r"}, FormatJSON},
+		{[]string{"s", "alpha", "--json"}, []string{"s", "alpha"}, FormatJSON},
+		{[]string{"--format", "tsv", "tag"}, []string{"tag"}, FormatTSV},
+		{[]string{"pinned", "--format=json"}, []string{"pinned"}, FormatJSON},
+	}
+	for _, tt := range tests {
+		rest, err := ExtractOutputFlags(tt.args)
+		if err != nil {
+			t.Errorf("ExtractOutputFlags(%v): %v", tt.args, err)
+			continue
+		}
+		if strings.Join(rest, " ") != strings.Join(tt.rest, " ") || outputFormat != tt.format {
+			t.Errorf("ExtractOutputFlags(%v) = %v, %s; want %v, %s", tt.args, rest, outputFormat, tt.rest, tt.format)
+		}
+	}
+
+	for _, args := range [][]string{{"r", "--format"}, {"--format", "xml"}} {
+		if _, err := ExtractOutputFlags(args); err == nil {
+			t.Errorf("ExtractOutputFlags(%v) should fail", args)
+		}
+	}
+}
+
+func TestMachineOutput(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+	defer func() { outputFormat, errorReported = FormatText, false }()
+
+	createTestNote(t, notesDir, "alpha", ".work\nAlpha content")
+	createTestNote(t, notesDir, "beta", "Beta content")
+	data.IndexNotes(notesDir)
+	core.PinNote("alpha")
+
+	t.Run("recent as json", func(t *testing.T) {
+		outputFormat = FormatJSON
+		output := captureOutput(func() {
+			RecentCommand(nil, ActionDefaults{})
+		})
+
+		var records []NoteRecord
+		if err := json.Unmarshal([]byte(output), &records); err != nil {
+			t.Fatalf("output is not a json array: %v\n%s", err, output)
+		}
+		if len(records) != 2 {
+			t.Fatalf("got %d records, want 2", len(records))
+		}
+		for _, r := range records {
+			if r.FilePath == "" || r.Created == "" {
+				t.Errorf("record missing index fields: %+v", r)
+			}
+			if r.Pinned != (r.Title == "alpha") {
+				t.Errorf("%s: pinned = %v", r.Title, r.Pinned)
+			}
+		}
+	})
+
+	t.Run("search as json", func(t *testing.T) {
+		outputFormat = FormatJSON
+		output := captureOutput(func() {
+			SearchCommand([]string{"alpha"}, ActionDefaults{})
+		})
+
+		var records []NoteRecord
+		if err := json.Unmarshal([]byte(output), &records); err != nil {
+			t.Fatalf("output is not a json array: %v\n%s", err, output)
+		}
+		if len(records) != 1 || records[0].Title != "alpha" || records[0].Score == 0 {
+			t.Errorf("unexpected search records: %+v", records)
+		}
+	})
+
+	t.Run("shell-quoted query keeps its grouping", func(t *testing.T) {
+		outputFormat = FormatJSON
+		output := captureOutput(func() {
+			SearchCommand([]string{"content (tag:work OR tag:team)"}, ActionDefaults{})
+		})
+
+		var records []NoteRecord
+		if err := json.Unmarshal([]byte(output), &records); err != nil {
+			t.Fatalf("output is not a json array: %v\n%s", err, output)
+		}
+		if len(records) != 1 || records[0].Title != "alpha" {
+			t.Errorf("unexpected search records: %+v", records)
+		}
+	})
+
+	t.Run("no matches is an empty array", func(t *testing.T) {
+		outputFormat = FormatJSON
+		output := captureOutput(func() {
+			SearchCommand([]string{"nonexistent"}, ActionDefaults{})
+		})
+
+		if strings.TrimSpace(output) != "[]" {
+			t.Errorf("expected [], got: %s", output)
+		}
+	})
+
+	t.Run("tags as tsv", func(t *testing.T) {
+		outputFormat = FormatTSV
+		output := captureOutput(func() {
+			TagCommand(nil, ActionDefaults{})
+		})
+
+		lines := strings.Split(strings.TrimSpace(output), "\n")
+		if len(lines) != 2 || lines[0] != "tag\tcount\ttotal" || lines[1] != "work\t1\t1" {
+			t.Errorf("unexpected tsv: %q", output)
+		}
+	})
+
+	t.Run("templates have their own records", func(t *testing.T) {
+		os.MkdirAll(data.TemplatesDir(), 0755)
+		os.WriteFile(filepath.Join(data.TemplatesDir(), "standup.md"), []byte("---\ntags: .meeting\npin: true\n---\nbody\n"), 0644)
+		outputFormat = FormatJSON
+		output := captureOutput(func() {
+			TemplateCommand(nil)
+		})
+
+		var records []TemplateRecord
+		if err := json.Unmarshal([]byte(output), &records); err != nil {
+			t.Fatalf("output is not a json array: %v\n%s", err, output)
+		}
+		if len(records) != 1 || records[0].Name != "standup" || !records[0].Pin || strings.Join(records[0].Tags, ",") != "meeting" {
+			t.Errorf("unexpected template records: %+v", records)
+		}
+		if strings.Contains(output, `"created"`) {
+			t.Errorf("templates printed as notes:\n%s", output)
+		}
+	})
+
+	t.Run("recover with several matches lists them and fails", func(t *testing.T) {
+		createTestNote(t, notesDir, "twin", "one")
+		data.IndexNotes(notesDir)
+		core.DeleteNote("twin")
+		createTestNote(t, notesDir, "twin", "two")
+		data.IndexNotes(notesDir)
+		core.DeleteNote("twin")
+		errorReported = false
+		outputFormat = FormatJSON
+		output := captureOutput(func() {
+			RecoverCommand([]string{"twin"})
+		})
+
+		var records []TrashRecord
+		if err := json.Unmarshal([]byte(output), &records); err != nil {
+			t.Fatalf("output is not a json array: %v\n%s", err, output)
+		}
+		if len(records) != 2 || records[0].Name != "twin" || records[0].ID == "" {
+			t.Errorf("unexpected trash records: %+v", records)
+		}
+		if ExitCode() != 1 {
+			t.Error("an ambiguous recover should fail")
+		}
+		if _, err := os.Stat(filepath.Join(notesDir, "twin.md")); err == nil {
+			t.Error("recovered without a choice")
+		}
+	})
+
+	t.Run("errors set the exit code", func(t *testing.T) {
+		errorReported = false
+		outputFormat = FormatJSON
+		output := captureOutput(func() {
+			InfoCommand([]string{"nonexistent"})
+		})
+
+		if output != "" {
+			t.Errorf("errors should go to stderr, got: %s", output)
+		}
+		if ExitCode() != 1 {
+			t.Errorf("ExitCode() = %d, want 1", ExitCode())
+		}
+	})
+}
+
+func TestExportSite(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	createTestNote(t, notesDir, "guide", "---\npublish: yes\n---\n.docs/howto\n# Guide\nSee [[faq#Big Question|the FAQ]] and [[diary]].\n")
+	createTestNote(t, notesDir, "faq", ".docs\n## Big Question\nanswers\n")
+	createTestNote(t, notesDir, "diary", "private thoughts")
+	os.MkdirAll(filepath.Join(notesDir, "work", "attachments"), 0755)
+	os.WriteFile(filepath.Join(notesDir, "work", "attachments", "a b.png"), []byte("png"), 0644)
+	os.WriteFile(filepath.Join(notesDir, "work", "chart.svg"), []byte("svg"), 0644)
+	os.WriteFile(filepath.Join(notesDir, "work", "secret.png"), []byte("no"), 0644)
+	createTestNote(t, notesDir, "work/report", ".docs\n![shot](<attachments/a b.png>) ![[chart.svg]] ![[faq]] ![[gone.png]]\n")
+	index, _ := data.LoadIndex()
+	data.IndexAllFTS(notesDir, index)
+
+	notes, _ := core.SelectNotes(core.NoteFilter{Tags: []string{"docs"}})
+	dir := filepath.Join(t.TempDir(), "site")
+	if err := exportSite(dir, notesDir, notes); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(rel string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(dir, rel))
+		if err != nil {
+			t.Fatalf("missing %s", rel)
+		}
+		return string(content)
+	}
+	guide := read("notes/guide.html")
+	if !strings.Contains(guide, `<a href="../notes/faq.html#big-question">the FAQ</a> and diary.`) {
+		t.Errorf("links not rewritten:\n%s", guide)
+	}
+	if strings.Contains(guide, ".docs/howto\n") || !strings.Contains(guide, `href="../tags/docs/howto.html"`) {
+		t.Error("tag line should become tag links")
+	}
+	if !strings.Contains(read("notes/faq.html"), `id="big-question"`) {
+		t.Error("headings need ids")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes", "diary.html")); err == nil {
+		t.Error("unselected note exported")
+	}
+	if docs := read("tags/docs.html"); !strings.Contains(docs, "guide") || !strings.Contains(docs, "faq") {
+		t.Errorf("tag page should roll up sub-tags:\n%s", docs)
+	}
+	report := read("notes/work/report.html")
+	for _, want := range []string{`<img src="attachments/a%20b.png" alt="shot">`, `<img src="../../notes/work/chart.svg" alt="chart.svg">`, `<a href="../../notes/faq.html">faq</a>`, " gone.png"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %s:\n%s", want, report)
+		}
+	}
+	if read("notes/work/attachments/a b.png") != "png" || read("notes/work/chart.svg") != "svg" {
+		t.Error("attachments not copied")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes", "work", "secret.png")); err == nil {
+		t.Error("unreferenced file copied")
+	}
+	if idx := read("index.html"); !strings.Contains(idx, "3 notes") {
+		t.Errorf("index:\n%s", idx)
+	}
+	if search := read("search-index.js"); !strings.Contains(search, `"answer":[[`) || strings.Contains(search, "thought") {
+		t.Errorf("search index:\n%s", search)
+	}
+
+	// Exporting again drops pages no longer selected
+	notes, _ = core.SelectNotes(core.NoteFilter{Properties: []string{"publish=yes"}})
+	if err := exportSite(dir, notesDir, notes); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes", "faq.html")); err == nil {
+		t.Error("stale page kept")
+	}
+
+	other := t.TempDir()
+	os.WriteFile(filepath.Join(other, "keep.txt"), nil, 0644)
+	if err := exportSite(other, notesDir, notes); err == nil {
+		t.Error("export into a non-empty directory should fail")
+	}
+}
+
+func TestNoteServer(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	createTestNote(t, notesDir, "guide", ".docs/howto\n# Guide\nSee [[faq]] and ![](img/shot.png), ![[shot.png]].\n")
+	createTestNote(t, notesDir, "faq", ".docs\nanswers here\n")
+	os.MkdirAll(filepath.Join(notesDir, "img"), 0755)
+	os.WriteFile(filepath.Join(notesDir, "img", "shot.png"), []byte("png"), 0644)
+	index, _ := data.LoadIndex()
+	data.IndexAllFTS(notesDir, index)
+
+	srv := httptest.NewServer(newNoteServer(notesDir, "127.0.0.1:0").routes())
+	defer srv.Close()
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if _, body := get("/"); !strings.Contains(body, `href="/notes/guide.html"`) || !strings.Contains(body, "new EventSource") {
+		t.Errorf("recent page:\n%s", body)
+	}
+	if _, body := get("/notes/guide.html"); !strings.Contains(body, `<a href="/notes/faq.html">faq</a>`) || !strings.Contains(body, `var note = "guide"`) || !strings.Contains(body, `<img src="/notes/img/shot.png" alt="shot.png">`) {
+		t.Errorf("note page:\n%s", body)
+	}
+	if _, body := get("/notes/faq.html?hl=answers"); !strings.Contains(body, "<mark>answers</mark>") {
+		t.Errorf("highlight missing:\n%s", body)
+	}
+	if _, body := get("/tags/docs.html"); !strings.Contains(body, "guide") || !strings.Contains(body, "faq") {
+		t.Errorf("tag page:\n%s", body)
+	}
+	if _, body := get("/search.html?q=answers"); !strings.Contains(body, `href="/notes/faq.html?hl=answers"`) || strings.Contains(body, `href="/notes/guide.html?`) {
+		t.Errorf("search page:\n%s", body)
+	}
+	if status, body := get("/notes/img/shot.png"); status != http.StatusOK || body != "png" {
+		t.Errorf("attachment: %d %q", status, body)
+	}
+	for _, path := range []string{"/notes/missing.html", "/tags/nope.html", "/notes/img"} {
+		if status, _ := get(path); status != http.StatusNotFound {
+			t.Errorf("%s: status %d, want 404", path, status)
+		}
+	}
+	if _, body := get("/ping"); body != "gote" {
+		t.Errorf("ping: %q", body)
+	}
+
+	// Requests for other host names are refused (DNS rebinding)
+	for host, want := range map[string]int{
+		"evil.example:7777": http.StatusForbidden,
+		"localhost:7777":    http.StatusOK,
+		"[::1]:7777":        http.StatusOK,
+		"127.0.0.1":         http.StatusOK,
+	} {
+		req, _ := http.NewRequest("GET", srv.URL+"/ping", nil)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("Host %s: status %d, want %d", host, resp.StatusCode, want)
+		}
+	}
+	lan := newNoteServer(notesDir, "192.168.1.5:7777")
+	if !lan.allowedHost("192.168.1.5:7777") || lan.allowedHost("192.168.1.6:7777") {
+		t.Error("a server on a LAN address should answer for that address only")
+	}
+}
+
+func TestLSPServer(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	createTestNote(t, notesDir, "faq", ".docs/howto\n# FAQ\n\n## Big Question\nanswers here\n")
+	os.MkdirAll(filepath.Join(notesDir, "work"), 0755)
+	createTestNote(t, notesDir, "work/standup", ".work\nstandup notes\n")
+	index, _ := data.LoadIndex()
+	data.IndexAllFTS(notesDir, index)
+
+	doc := ".do\nSee [[faq#Big Question]] and [[missing]], ![[pic.png]].\nAsk [[st\n"
+	uri := pathToURI(filepath.Join(notesDir, "draft.md"))
+	var in bytes.Buffer
+	send := func(id int, method string, params any) {
+		msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+		if id > 0 {
+			msg["id"] = id
+		}
+		body, _ := json.Marshal(msg)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	at := func(line, char int) map[string]any {
+		return map[string]any{"textDocument": map[string]string{"uri": uri}, "position": map[string]int{"line": line, "character": char}}
+	}
+	send(1, "initialize", map[string]any{})
+	send(0, "initialized", map[string]any{})
+	send(0, "textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": uri, "text": doc}})
+	send(2, "textDocument/completion", at(2, 9))
+	send(3, "textDocument/completion", at(0, 3))
+	send(4, "textDocument/definition", at(1, 8))
+	send(5, "textDocument/hover", at(1, 8))
+	send(6, "workspace/symbol", map[string]string{"query": "answers"})
+	send(7, "textDocument/formatting", at(0, 0))
+	send(8, "shutdown", nil)
+	send(0, "exit", nil)
+
+	var out bytes.Buffer
+	s := newLSPServer(notesDir, &in, &out)
+	if err := s.serve(); err != nil || !s.shutdown {
+		t.Fatalf("serve: %v, shutdown %v", err, s.shutdown)
+	}
+
+	wire := out.String()
+	replies := make(map[int]json.RawMessage)
+	var diagnostics []lspDiagnostic
+	reader := newLSPServer(notesDir, &out, io.Discard)
+	for {
+		msg, err := reader.read()
+		if err != nil {
+			break
+		}
+		if msg.ID != nil {
+			var id int
+			json.Unmarshal(*msg.ID, &id)
+			raw, _ := json.Marshal(msg)
+			replies[id] = raw
+		} else if msg.Method == "textDocument/publishDiagnostics" {
+			var p struct{ Diagnostics []lspDiagnostic }
+			json.Unmarshal(msg.Params, &p)
+			diagnostics = p.Diagnostics
+		}
+	}
+
+	if len(diagnostics) != 1 || diagnostics[0].Message != `No note named "missing"` ||
+		diagnostics[0].Range != (lspRange{lspPosition{1, 29}, lspPosition{1, 40}}) {
+		t.Errorf("diagnostics = %+v", diagnostics)
+	}
+	if r := string(replies[2]); !strings.Contains(r, `"newText":"work/standup]]"`) || !strings.Contains(r, `"start":{"character":6,"line":2}`) {
+		t.Errorf("note completion = %s", r)
+	}
+	if r := string(replies[3]); !strings.Contains(r, `"newText":"docs/howto"`) || strings.Contains(r, "standup") {
+		t.Errorf("tag completion = %s", r)
+	}
+	if r := string(replies[4]); !strings.Contains(r, `"start":{"character":0,"line":3}`) || !strings.HasSuffix(r, `/faq.md"}}`) {
+		t.Errorf("definition = %s", r)
+	}
+	if r := string(replies[5]); !strings.Contains(r, `**faq**  .docs/howto`) || !strings.Contains(r, "answers here") {
+		t.Errorf("hover = %s", r)
+	}
+	if r := string(replies[6]); !strings.Contains(r, `"name":"faq"`) || strings.Contains(r, "standup") {
+		t.Errorf("symbols = %s", r)
+	}
+	if r := string(replies[7]); !strings.Contains(r, `"code":-32601`) {
+		t.Errorf("unsupported method = %s", r)
+	}
+	if !strings.Contains(wire, `"id":8,"result":null`) {
+		t.Error("shutdown should reply with a null result")
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	source := "---\ntitle: x\n---\n.work\n# Plan\n\nSome **bold** and a [link](https://example.com).\n\n" +
+		"- [ ] open\n- [x] done\n  1. nested\n\n```\ncode line\n```\n\n| A | B |\n|---|--:|\n| x | 10 |\n| yy | 2 |\n"
+
+	t.Run("plain text", func(t *testing.T) {
+		got := renderMarkdown([]byte(source), 0, false)
+		want := ".work\n\nPlan\n════\n\nSome bold and a link (https://example.com).\n\n" +
+			"• [ ] open\n• [x] done\n  1. nested\n\n    code line\n\nA  │  B\n───┼───\nx  │ 10\nyy │  2\n"
+		if got != want {
+			t.Errorf("renderMarkdown =\n%s\nwant\n%s", got, want)
+		}
+		if strings.Contains(got, "\033[") {
+			t.Error("plain output has ANSI codes")
+		}
+	})
+
+	t.Run("styled", func(t *testing.T) {
+		got := renderMarkdown([]byte("**bold `code` more**\n"), 0, true)
+		want := Bold + "bold " + Cyan + "code" + Reset + Bold + " more" + Reset + "\n"
+		if got != want {
+			t.Errorf("renderMarkdown = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestWrapANSI(t *testing.T) {
+	got := wrapANSI("one two "+Bold+"three four"+Reset+" five", 9)
+	want := []string{"one two", Bold + "three" + Reset, Bold + "four" + Reset + " five"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("wrapANSI = %q, want %q", got, want)
+	}
+
+	if got := wrapANSI("a  b\nc", 0); len(got) != 2 || got[0] != "a  b" {
+		t.Errorf("width 0 should only split lines, got %q", got)
+	}
+}
@@ -1,9 +1,11 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +15,10 @@ import (
 )
 
 func NoteCommand(args []string) {
+	// --stdin takes no value, so it mustn't swallow the note name after it
+	stdin := slices.Contains(args, "--stdin")
+	args = slices.DeleteFunc(slices.Clone(args), func(arg string) bool { return arg == "--stdin" })
+
 	parsedArgs := ParseArgs(args)
 	dateFlag := parsedArgs.Has("d", "date")
 	datetimeFlag := parsedArgs.Has("dt", "datetime")
@@ -21,8 +27,10 @@ func NoteCommand(args []string) {
 	templateName := parsedArgs.String("t", "template")
 	noteName := parsedArgs.Joined()
 
-	if noteName == "" {
+	// A template with a name pattern needs no note name
+	if noteName == "" && !templateFlag {
 		fmt.Println("Usage: gote <note name> [-d|--date] [-dt|--datetime] [-nt|--no-timestamp] [-t|--template <name>]")
+		fmt.Println("       gote new <note name> --stdin [flags]   Create the note from stdin without opening it")
 		return
 	}
 
@@ -31,35 +39,38 @@ func NoteCommand(args []string) {
 		return
 	}
 
+	// For a new note, flags override the template's and config's timestamp mode
+	mode := ""
+	if noTimestampFlag {
+		mode = "none"
+	} else if dateFlag {
+		mode = "date"
+	} else if datetimeFlag {
+		mode = "datetime"
+	}
+
+	if stdin {
+		if templateFlag && templateName == "" {
+			ui.Error("--stdin can't ask for a template; give its name after -t")
+			return
+		}
+		newNoteFromStdin(noteName, templateName, mode, ui)
+		return
+	}
+
 	// Check if note already exists - if so, just open it
 	index, err := data.LoadIndex()
 	if err != nil {
 		fmt.Println("Error loading index:", err)
 		return
 	}
-	if _, exists := index[noteName]; exists {
+	if _, _, exists := data.LookupNote(index, noteName); exists && noteName != "" {
 		if err := core.CreateOrOpenNote(noteName); err != nil {
 			fmt.Println("Error:", err)
 		}
 		return
 	}
 
-	// Note doesn't exist - apply timestamp if enabled (unless bypassed)
-	if !noTimestampFlag {
-		mode := cfg.TimestampNotes
-		if dateFlag {
-			mode = "date"
-		} else if datetimeFlag {
-			mode = "datetime"
-		}
-
-		if mode == "date" {
-			noteName = time.Now().Format("060102") + " " + noteName
-		} else if mode == "datetime" {
-			noteName = time.Now().Format("060102-150405") + " " + noteName
-		}
-	}
-
 	// Handle template flag
 	if templateFlag {
 		if templateName == "" {
@@ -69,12 +80,19 @@ func NoteCommand(args []string) {
 				return // User cancelled
 			}
 		}
-		if err := core.CreateNoteFromTemplate(noteName, templateName); err != nil {
+		_, err := core.CreateNoteFromTemplate(noteName, templateName, mode, templatePrompt(ui))
+		if errors.Is(err, core.ErrTemplateCancelled) {
+			ui.Info("Cancelled.")
+		} else if err != nil {
 			ui.Error(err.Error())
 		}
 		return
 	}
 
+	if mode == "" {
+		mode = cfg.TimestampNotes
+	}
+	noteName = core.TimestampNoteName(noteName, mode, time.Now())
 	if err := core.CreateOrOpenNote(noteName); err != nil {
 		ui.Error(err.Error())
 	}
@@ -175,6 +193,8 @@ func IndexCommand(rawArgs []string) {
 		_ = os.Remove(data.IndexPath())
 		_ = os.Remove(data.TagsPath())
 		_ = os.Remove(data.FTSPath())
+		_ = os.Remove(data.FTSLogPath())
+		_ = os.Remove(data.LinksPath())
 		if err := data.IndexNotes(cfg.NoteDir); err != nil {
 			ui.Error(err.Error())
 		} else {
@@ -188,6 +208,13 @@ func IndexCommand(rawArgs []string) {
 
 func TagCommand(rawArgs []string, defaults ActionDefaults) {
 	args := ParseArgs(rawArgs)
+	if isTagEdit(args) {
+		cfg, ui, ok := LoadConfigAndUI()
+		if ok {
+			tagEdit(args, cfg, ui)
+		}
+		return
+	}
 	preSelected := resolvePreSelectedAction(&args, defaults)
 	sub := args.First()
 
@@ -196,10 +223,11 @@ func TagCommand(rawArgs []string, defaults ActionDefaults) {
 		return
 	}
 
-	// If first arg starts with ".", it's a tag filter
-	if strings.HasPrefix(sub, ".") {
+	// If first arg starts with ".", it's a tag filter. --exact may come
+	// first and take the tags as its values.
+	if strings.HasPrefix(sub, ".") || strings.HasPrefix(args.String("exact"), ".") {
 		var allTags []string
-		for _, arg := range args.Positional {
+		for _, arg := range append(args.Positional, args.List("exact")...) {
 			if strings.HasPrefix(arg, ".") {
 				allTags = append(allTags, ParseTagString(arg)...)
 			}
@@ -210,53 +238,14 @@ func TagCommand(rawArgs []string, defaults ActionDefaults) {
 		}
 
 		pageSize := args.IntOr(cfg.PageSize(), "n", "limit")
-		results, err := core.FilterNotesByTags(allTags, -1)
-		if err != nil {
-			ui.Error(err.Error())
-			return
-		}
-		if len(results) == 0 {
-			ui.Empty("No notes found with all specified tags.")
-			return
-		}
-
-		titles, paths := searchResultsToMenu(results)
-		result := displayMenu(MenuConfig{
-			Title:             "Tagged Notes",
-			Items:             titles,
-			ItemPaths:         paths,
-			PreSelectedAction: preSelected,
-			ShowPin:           true,
-			PageSize:          pageSize,
-		}, ui, cfg.Interface)
-
-		executeMenuAction(result, paths, ui)
+		tagNotesMenu(allTags, args.Has("exact"), args.String("notebook", "nb"), preSelected, pageSize, cfg, ui)
 		return
 	}
 
 	// Handle subcommands
 	switch sub {
-	case "":
-		tags, err := data.LoadTags()
-		if err != nil {
-			ui.Error(err.Error())
-			return
-		}
-		if len(tags) == 0 {
-			ui.Empty("No tags found.")
-			return
-		}
-		var lines []string
-		for tagName, tag := range tags {
-			lines = append(lines, fmt.Sprintf("%s (%d)", tagName, tag.Count))
-		}
-		if cfg.IsTUI() {
-			ui.Box("Tags", lines, 0)
-		} else {
-			for _, line := range lines {
-				fmt.Println(line)
-			}
-		}
+	case "", "tree":
+		tagTree(args.Has("expand", "e"), cfg, ui)
 	case "edit":
 		if err := data.OpenFileInEditor(data.TagsPath(), cfg.Editor); err != nil {
 			ui.Error(err.Error())
@@ -280,6 +269,10 @@ func TagCommand(rawArgs []string, defaults ActionDefaults) {
 			ui.Error(err.Error())
 			return
 		}
+		if machineOutput() {
+			writeTags(tags)
+			return
+		}
 		if len(tags) == 0 {
 			ui.Empty("No tags found.")
 			return
@@ -298,7 +291,7 @@ func TagCommand(rawArgs []string, defaults ActionDefaults) {
 		}
 	default:
 		fmt.Println("Unknown subcommand:", sub)
-		fmt.Println("Usage: gote tag [.tag1.tag2 | edit | format | popular]")
+		fmt.Println("Usage: gote tag [.tag1.tag2 [--exact] | tree [-e] | rename <old> <new> | merge <tag>... --into <tag> | delete <tag> | edit | format | popular]")
 	}
 }
 
@@ -324,6 +317,7 @@ func ConfigCommand(rawArgs []string) {
 				{"Interface", cfg.Interface},
 				{"Timestamp notes", timestampVal},
 				{"Default page size", fmt.Sprintf("%d", cfg.PageSize())},
+				{"History versions", fmt.Sprintf("%d", cfg.HistoryLimit())},
 			})
 		} else {
 			fmt.Println("Config settings:")
@@ -364,7 +358,23 @@ Options:
 
   defaultPageSize  Number of results to show by default
                    Default: 10
-                   Can be overridden with -n flag`)
+                   Can be overridden with -n flag
+
+  historyVersions  Snapshots kept per note in ~/.gote/history
+                   Default: 50
+
+  historyDays      Drop snapshots older than this many days
+                   Default: 0 (keep forever)
+
+  trashDays        Permanently delete trashed notes after this many days
+                   Default: 0 (keep until gote trash empty)
+
+  journalNotebook  Notebook for today/week/month notes ("/" = top level)
+                   Default: journal
+
+  dailyTemplate    Templates for new daily, weekly and monthly notes
+  weeklyTemplate   Default: a template named daily, weekly or monthly
+  monthlyTemplate  if there is one, else a plain heading`)
 	default:
 		fmt.Println("Unknown subcommand:", sub)
 		fmt.Println("Usage: gote config [show|edit|format|help]")
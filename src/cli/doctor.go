@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"gote/src/core"
+)
+
+// DoctorCommand checks gote's data files for inconsistencies and, with
+// --fix, repairs them. Exits with status 1 if problems remain.
+func DoctorCommand(rawArgs []string) {
+	args := ParseArgs(rawArgs)
+	fix := args.Has("fix")
+
+	// Not LoadConfigAndUI: loading a damaged config.json resets it, and the
+	// doctor should report that first
+	ui := NewUI("")
+	problems, err := core.Doctor(fix)
+
+	remaining := 0
+	for _, p := range problems {
+		status := ""
+		if p.Fixed {
+			status = " (fixed)"
+		} else {
+			remaining++
+		}
+		if !args.Has("q", "quiet") {
+			fmt.Printf("%-16s %s: %s%s\n", p.Kind, p.Subject, p.Detail, status)
+		}
+	}
+	if err != nil {
+		ui.Error(err.Error())
+		os.Exit(1)
+	}
+
+	switch {
+	case len(problems) == 0:
+		ui.Success("No problems found.")
+	case remaining == 0:
+		ui.Success(fmt.Sprintf("Problems fixed: %d.", len(problems)))
+	case fix:
+		ui.Error(fmt.Sprintf("Problems not fixed: %d of %d.", remaining, len(problems)))
+	default:
+		ui.Info(fmt.Sprintf("Problems found: %d. Run 'gote doctor --fix' to repair them.", remaining))
+	}
+	if remaining > 0 {
+		os.Exit(1)
+	}
+}
@@ -3,25 +3,75 @@ package cli
 import (
 	"archive/tar"
 	"compress/gzip"
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"gote/src/core"
 	"gote/src/data"
 )
 
+// exportFlagArity lists the flags ExportCommand understands, so that the
+// output file can follow them ("gote export -t .work work.tar.gz")
+var exportFlagArity = map[string]string{
+	"t": "tags", "tag": "tags",
+	"q": "value", "query": "value",
+	"w": "dates", "when": "dates",
+	"m": "bool", "modified": "bool",
+	"p": "value", "prop": "value",
+	"notebook": "value", "nb": "value",
+	"exact": "bool",
+	"html":  "value",
+}
+
 func ExportCommand(rawArgs []string) {
-	args := ParseArgs(rawArgs)
+	args := splitFlagArgs(rawArgs, exportFlagArity)
 	cfg, ui, ok := LoadConfigAndUI()
 	if !ok {
 		return
 	}
 
+	if args.Has("html") {
+		exportHTML(args, ui)
+		return
+	}
+
 	outPath := args.First()
 	if outPath == "" {
 		outPath = "gote-export.tar.gz"
 	}
 
+	filter := exportFilter(args)
+	if filter.Notebook != "" {
+		if err := data.ValidateNotebookName(filter.Notebook); err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		if info, err := os.Stat(filepath.Join(cfg.NoteDir, filepath.FromSlash(filter.Notebook))); err != nil || !info.IsDir() {
+			ui.Error("Notebook not found: " + filter.Notebook)
+			return
+		}
+	}
+
+	// A filtered export carries just the chosen notes and their metadata
+	var notes map[string]data.NoteMeta
+	if !filter.Empty() {
+		var err error
+		if notes, err = core.SelectNotes(filter); err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		if len(notes) == 0 {
+			ui.Empty("No notes match; nothing exported.")
+			return
+		}
+	}
+
 	f, err := os.Create(outPath)
 	if err != nil {
 		ui.Error("could not create output file: " + err.Error())
@@ -32,16 +82,22 @@ func ExportCommand(rawArgs []string) {
 	gw := gzip.NewWriter(f)
 	tw := tar.NewWriter(gw)
 
-	if err := addDirToTar(tw, data.GoteDir(), "gote"); err != nil {
-		ui.Error("export failed: " + err.Error())
-		return
-	}
-
-	if _, statErr := os.Stat(cfg.NoteDir); statErr == nil {
-		if err := addDirToTar(tw, cfg.NoteDir, "notes"); err != nil {
+	if notes != nil {
+		if err := addNotesToTar(tw, notes); err != nil {
+			ui.Error("export failed: " + err.Error())
+			return
+		}
+	} else {
+		if err := addDirToTar(tw, data.GoteDir(), "gote"); err != nil {
 			ui.Error("export failed: " + err.Error())
 			return
 		}
+		if _, statErr := os.Stat(cfg.NoteDir); statErr == nil {
+			if err := addDirToTar(tw, cfg.NoteDir, "notes"); err != nil {
+				ui.Error("export failed: " + err.Error())
+				return
+			}
+		}
 	}
 
 	if err := tw.Close(); err != nil {
@@ -54,9 +110,116 @@ func ExportCommand(rawArgs []string) {
 	}
 
 	absPath, _ := filepath.Abs(outPath)
+	if notes != nil {
+		ui.Success(fmt.Sprintf("Exported %d notes to %s", len(notes), absPath))
+		return
+	}
 	ui.Success("Exported to " + absPath)
 }
 
+// exportFilter reads the -t/--tag, -q/--query, -w/--when, -p/--prop and
+// --notebook filters shared by both kinds of export
+func exportFilter(args Args) core.NoteFilter {
+	return core.NoteFilter{
+		Tags:       args.TagList("t", "tag"),
+		Exact:      args.Has("exact"),
+		Properties: append(args.List("p"), args.List("prop")...),
+		Notebook:   strings.Trim(args.String("notebook", "nb"), "/"),
+		Query:      args.String("q", "query"),
+		Dates:      args.List("w", "when"),
+		ByModified: args.Has("m", "modified"),
+	}
+}
+
+// exportHTML runs "gote export --html <dir>", publishing the notes that pass
+// the export filters as a static site
+func exportHTML(args Args, ui *UI) {
+	dir := args.String("html")
+	if dir == "" {
+		dir = args.First()
+	}
+	if dir == "" {
+		dir = "gote-site"
+	}
+
+	notes, err := core.SelectNotes(exportFilter(args))
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	if len(notes) == 0 {
+		ui.Empty("No notes match; nothing exported.")
+		return
+	}
+
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		ui.Error("error loading config: " + err.Error())
+		return
+	}
+	if err := exportSite(dir, cfg.NoteDir, notes); err != nil {
+		ui.Error("export failed: " + err.Error())
+		return
+	}
+	absPath, _ := filepath.Abs(filepath.Join(dir, "index.html"))
+	ui.Success(fmt.Sprintf("Exported %d notes to %s", len(notes), absPath))
+}
+
+// addNotesToTar writes each note as notes/<name>.md, followed by their index
+// entries in metadata.json so an import can keep their dates
+func addNotesToTar(tw *tar.Writer, notes map[string]data.NoteMeta) error {
+	names := make([]string, 0, len(notes))
+	for name := range notes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metadata := make(map[string]data.NoteMeta, len(notes))
+	for _, name := range names {
+		meta := notes[name]
+		info, err := os.Stat(meta.FilePath)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = "notes/" + name + ".md"
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		src, err := os.Open(meta.FilePath)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+
+		meta.FilePath = "" // meaningless outside this vault
+		metadata[name] = meta
+	}
+
+	raw, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     core.ExportMetadataFile,
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+		Size:     int64(len(raw)),
+		ModTime:  time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err = tw.Write(raw)
+	return err
+}
+
 func addDirToTar(tw *tar.Writer, srcDir, prefix string) error {
 	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
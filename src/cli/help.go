@@ -12,10 +12,14 @@ func PrintDefaultHelp() {
 Usage:
   gote <note>                     Create or open note
   gote <note> -t [template]       Create from template
+  gote -t <template>              Create from template, named by its name pattern
   gote -d/-dt/-nt <note>          Date/datetime/no-timestamp prefix
   gote                            Open quick note
   gote -                          Open last opened note
   gote quick save <name> | qs     Save quick note as named note
+  gote new <note> --stdin         Create note from stdin, no editor (-t, -d work too)
+  gote append | app <note> [text] Add text or stdin to the end (--timestamp, --create)
+  gote prepend | pre <note> [text] Add text or stdin to the top
   Note: "-" works as last note alias (e.g., gote view -, gote delete -)
 
 Recent: (gote recent | r)
@@ -28,13 +32,20 @@ Search: (gote search | s)
   gote so/sd/sp/sv <query>        + open/delete/pin/view mode
   gote search -t .tag1.tag2       Search by tags
   gote search -w <date> [date]    Search by date (created)
+  gote search -p key=value        Search by front matter property (repeatable)
   gote search -w <date> -m        Search by date (modified)
+  Query syntax: "exact phrase", -exclude, a OR b, ( ), title:x, tag:x,
+                notebook:x, created:2410..2412, modified:241015
 
 Tags: (gote tag | t)
-  gote tag                        List all tags
-  gote tag .tag1.tag2             Filter by tags
+  gote tag [-e]                   Tag tree (-e: start expanded)
+  gote tag .tag1.tag2 [--exact]   Filter by tags (work includes work/acme)
   gote to/td/tp/tv .tags          + open/delete/pin/view mode
   gote tag popular                Most used tags
+  gote tag rename <old> <new>     Rename a tag in every note
+  gote tag merge <a> <b> --into <c>  Merge tags into one
+  gote tag delete <tag>           Remove a tag from every note
+    --exact: leave tags below it; --dry-run: list changes only
 
 Pins: (gote pin | p)
   gote pin <note>                 Pin a note
@@ -44,22 +55,60 @@ Pins: (gote pin | p)
 
 Trash: (gote delete | d)
   gote delete <note>              Move to trash
-  gote trash                      List trash
+  gote trash                      Browse trash, pick a note to recover
   gote trash empty                Empty trash
-  gote recover <note>             Restore from trash
+  gote recover <note>             Restore from trash (menu if several match)
+
+Notebooks: (gote nb)
+  gote work/standup               Notes in folders are named by path
+  gote nb                         List notebooks
+  gote nb <notebook>              Browse a notebook
+  gote nb create <notebook>       Create a notebook
+  gote nb move <note> --to <nb>   Move a note (--to / for top level)
+  --notebook <nb>                 Filter recent, search and tag results
+
+Links:
+  gote links | ln <note>          Notes linked from a note ([[note]])
+  gote backlinks | bl <note>      Notes linking to a note
+
+Journal:
+  gote today | week | month       Open (or create) this period's note
+  gote today -1 | week --next     Move by periods (--prev/--next [n], -n, +n)
+  gote journal | j [daily|...]    List periodic notes
+
+Tasks: (gote todo | tasks)
+  gote todo [note]                Open tasks (- [ ]); pick one to check it off
+  gote todo --done | --all        Finished tasks (pick to reopen), or both
+  gote todo -t .tag [--exact]     Tasks tagged #tag, or in notes tagged .tag
 
 Other:
   gote get | g                    Interactive select
-  gote template | tmpl [name]     List/edit templates
+  gote template | tmpl [name]     List/edit templates ({{title}}, {{date "2006-01-02"}}, {{prompt "Q"}}...)
   gote index | idx                Rebuild index (includes FTS)
   gote index fts                  Rebuild FTS index only
+  gote watch [--poll] [-i 2s]     Keep the index in sync with outside edits
+  gote doctor [--fix]             Check (and repair) index, search, tags, pins, trash
   gote config | c                 Show config
   gote config edit | ce           Edit config
   gote info | i <note>            Note metadata
   gote view | v <note>            Preview in browser
-  gote rename | mv <note> -n <new>  Rename note
+  gote cat <note>                 Render in the terminal (--raw, --no-pager)
+  gote serve [--addr host:port]   Local web UI with live reload (--open)
+  gote lsp                        Language server for editors (stdio)
+  gote history | hist <note>      List saved versions
+  gote history diff <note> <v1> [v2]  Diff versions (v2 defaults to current)
+  gote history restore <note> <v> Restore a version
+  gote rename | mv <note> -n <new>  Rename note (updates links)
   gote export [file]              Export all notes + data to .tar.gz
+  gote export [file] <filters>    Export matching notes only: -t .tag, -q "query",
+                                  -w <dates> [-m], -p key=value, --notebook <nb>
+  gote export --html <dir>        Static HTML site (same filters)
   gote import <file>              Import from exported .tar.gz
+  gote import <file> --merge      Add notes to this vault (--on-conflict skip|rename|overwrite)
+  gote import --from obsidian|folder|enex <path>  Migrate notes (--notebook <nb>)
+  gote <listing> --json           Print results as JSON instead of a menu
+  gote <listing> --format tsv     ... or as TSV (recent, search, tag, pinned,
+                                  trash, info, todo, links, journal, nb)
   gote help | h                   Show this help
   gote -v                         Show version`)
 }
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gote/src/core"
+)
+
+// HistoryCommand lists, diffs and restores saved versions of a note
+func HistoryCommand(rawArgs []string) {
+	args := ParseArgs(rawArgs)
+	sub := args.First()
+
+	cfg, ui, ok := LoadConfigAndUI()
+	if !ok {
+		return
+	}
+
+	switch sub {
+	case "":
+		printHistoryUsage()
+	case "diff":
+		noteName, versions := splitTrailingVersions(args.Rest(), 2)
+		if noteName == "" || len(versions) == 0 {
+			fmt.Println("Usage: gote history diff <note> <v1> [v2]")
+			return
+		}
+		noteName, err := ResolveNoteName(noteName)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		v2 := 0
+		if len(versions) == 2 {
+			v2 = versions[1]
+		}
+		diff, err := core.DiffHistory(noteName, versions[0], v2)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		if diff == "" {
+			ui.Empty("No differences.")
+			return
+		}
+		printDiff(diff, cfg.IsTUI())
+	case "restore":
+		noteName, versions := splitTrailingVersions(args.Rest(), 1)
+		if noteName == "" || len(versions) != 1 {
+			fmt.Println("Usage: gote history restore <note> <v>")
+			return
+		}
+		noteName, err := ResolveNoteName(noteName)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		if err := core.RestoreVersion(noteName, versions[0]); err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		ui.Success(fmt.Sprintf("Restored %s to v%d", noteName, versions[0]))
+	default:
+		noteName, err := ResolveNoteName(args.Joined())
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		entries, err := core.ListHistory(noteName)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		if len(entries) == 0 {
+			ui.Empty("No history for: " + noteName)
+			return
+		}
+		var lines []string
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			lines = append(lines, fmt.Sprintf("v%-3d %s  %5d words (%+d)", e.Version, e.Timestamp, e.Words, e.Delta))
+		}
+		ui.Box("History: "+noteName, lines, 0)
+	}
+}
+
+func printHistoryUsage() {
+	fmt.Println("Usage: gote history <note>")
+	fmt.Println("       gote history diff <note> <v1> [v2]")
+	fmt.Println("       gote history restore <note> <v>")
+}
+
+// splitTrailingVersions peels up to max trailing version numbers (e.g. "3" or "v3")
+// off args and returns the remaining words joined as the note name.
+func splitTrailingVersions(args []string, max int) (string, []int) {
+	var versions []int
+	end := len(args)
+	for end > 1 && len(versions) < max {
+		v, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(args[end-1]), "v"))
+		if err != nil || v <= 0 {
+			break
+		}
+		versions = append([]int{v}, versions...)
+		end--
+	}
+	return strings.Join(args[:end], " "), versions
+}
+
+// printDiff prints a unified diff, colouring hunks in TUI mode
+func printDiff(diff string, color bool) {
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		if !color {
+			fmt.Println(line)
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			fmt.Println(Cyan + line + Reset)
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			fmt.Println(Bold + line + Reset)
+		case strings.HasPrefix(line, "-"):
+			fmt.Println(Dim + line + Reset)
+		case strings.HasPrefix(line, "+"):
+			fmt.Println(Bold + line + Reset)
+		default:
+			fmt.Println(line)
+		}
+	}
+}
@@ -9,14 +9,26 @@ import (
 	"path/filepath"
 	"strings"
 
+	"golang.org/x/term"
+
+	"gote/src/core"
 	"gote/src/data"
 )
 
+// importFlagArity lists the flags ImportCommand understands
+var importFlagArity = map[string]string{
+	"merge":       "bool",
+	"from":        "value",
+	"on-conflict": "value",
+	"notebook":    "value", "nb": "value",
+}
+
 func ImportCommand(rawArgs []string) {
-	args := ParseArgs(rawArgs)
+	args := splitFlagArgs(rawArgs, importFlagArity)
 	srcPath := args.First()
 	if srcPath == "" {
-		fmt.Println("Usage: gote import <file.tar.gz>")
+		fmt.Println("Usage: gote import <file.tar.gz> [--merge [--on-conflict skip|rename|overwrite]]")
+		fmt.Println("       gote import --from obsidian|folder|enex <path> [--notebook <nb>] [--on-conflict ...]")
 		return
 	}
 
@@ -25,6 +37,17 @@ func ImportCommand(rawArgs []string) {
 		return
 	}
 
+	if source := args.String("from"); source != "" {
+		policy, ask := conflictPrompt(args.String("on-conflict"), ui)
+		result, err := core.ImportFrom(source, srcPath, args.String("notebook", "nb"), policy, ask)
+		showImportResult(result, err, ui)
+		return
+	}
+	if args.Has("merge") {
+		mergeImport(srcPath, args.String("on-conflict"), ui)
+		return
+	}
+
 	// Confirm if destination already has notes
 	index, _ := data.LoadIndex()
 	if len(index) > 0 {
@@ -55,6 +78,7 @@ func ImportCommand(rawArgs []string) {
 
 	tr := tar.NewReader(gr)
 	noteCount := 0
+	hasData := false // notebook exports carry notes only
 
 	for {
 		hdr, err := tr.Next()
@@ -80,6 +104,7 @@ func ImportCommand(rawArgs []string) {
 				continue
 			}
 			destPath = filepath.Join(goteDir, filepath.FromSlash(rel))
+			hasData = true
 		default:
 			continue
 		}
@@ -132,5 +157,99 @@ func ImportCommand(rawArgs []string) {
 		}
 	}
 
+	// Without gote's data files the imported notes still need indexing
+	if !hasData {
+		if err := data.IndexNotes(noteDir); err != nil {
+			ui.Error("error indexing imported notes: " + err.Error())
+			return
+		}
+	}
+
 	ui.Success(fmt.Sprintf("Imported %d notes.", noteCount))
 }
+
+// mergeImport runs "gote import --merge", adding an archive's notes to this
+// vault
+func mergeImport(srcPath, policy string, ui *UI) {
+	f, err := os.Open(srcPath)
+	if err != nil {
+		ui.Error("could not open file: " + err.Error())
+		return
+	}
+	defer f.Close()
+
+	policy, ask := conflictPrompt(policy, ui)
+	result, err := core.MergeImport(f, policy, ask)
+	showImportResult(result, err, ui)
+}
+
+// conflictPrompt returns the conflict policy to import with, and the prompt
+// that asks about each conflict when none was given. Without a terminal,
+// conflicts are skipped.
+func conflictPrompt(policy string, ui *UI) (string, core.ConflictFunc) {
+	if policy == "" {
+		policy = core.ConflictSkip
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			policy = core.ConflictAsk
+		}
+	}
+	choices := map[string]string{"s": core.ConflictSkip, "r": core.ConflictRename, "o": core.ConflictOverwrite}
+	always := ""
+	return policy, func(name string) (string, error) {
+		if always != "" {
+			return always, nil
+		}
+		for {
+			input, ok := ui.ReadLine(fmt.Sprintf("%s already exists with different content. [s]kip [r]ename [o]verwrite (S/R/O for all): ", name))
+			if !ok {
+				return core.ConflictSkip, nil
+			}
+			if choice, found := choices[strings.ToLower(input)]; found {
+				if input != strings.ToLower(input) {
+					always = choice
+				}
+				return choice, nil
+			}
+		}
+	}
+}
+
+// showImportResult reports what a merge or migration did with each note
+func showImportResult(result core.ImportResult, err error, ui *UI) {
+	if err != nil {
+		ui.Error(err.Error())
+		if result.Imported() == 0 {
+			return
+		}
+	}
+
+	var lines []string
+	for _, group := range []struct {
+		label string
+		names []string
+	}{
+		{"added", result.Added},
+		{"renamed", result.Renamed},
+		{"overwritten", result.Overwritten},
+		{"skipped", result.Skipped},
+		{"duplicate", result.Duplicates},
+	} {
+		for _, name := range group.names {
+			lines = append(lines, group.label+": "+name)
+		}
+	}
+	if len(lines) > 0 {
+		ui.Box("Import", lines, 0)
+	}
+	if len(result.Problems) > 0 {
+		ui.Box("Could not convert", result.Problems, 0)
+	}
+
+	summary := fmt.Sprintf("Imported %d notes (%d added, %d renamed, %d overwritten); %d unchanged, %d duplicates, %d skipped.",
+		result.Imported(), len(result.Added), len(result.Renamed), len(result.Overwritten),
+		len(result.Unchanged), len(result.Duplicates), len(result.Skipped))
+	if len(result.Attachments) > 0 {
+		summary += fmt.Sprintf(" Copied %d attachments.", len(result.Attachments))
+	}
+	ui.Success(summary)
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"gote/src/core"
@@ -95,7 +96,12 @@ func InfoCommand(rawArgs []string) {
 		return
 	}
 
-	if cfg.IsTUI() {
+	switch record := noteRecords([]string{meta.Title}); {
+	case outputFormat == FormatJSON:
+		writeJSON(record[0])
+	case outputFormat == FormatTSV:
+		writeNotes(record)
+	case cfg.IsTUI():
 		kvPairs := [][2]string{
 			{"Path", meta.FilePath},
 			{"Created", meta.Created},
@@ -105,8 +111,28 @@ func InfoCommand(rawArgs []string) {
 		if len(meta.Tags) > 0 {
 			kvPairs = append(kvPairs, [2]string{"Tags", strings.Join(meta.Tags, ", ")})
 		}
+		if len(meta.Tasks) > 0 {
+			done := 0
+			for _, task := range meta.Tasks {
+				if task.Done {
+					done++
+				}
+			}
+			kvPairs = append(kvPairs, [2]string{"Tasks", fmt.Sprintf("%d/%d done", done, len(meta.Tasks))})
+		}
+		if len(meta.Aliases) > 0 {
+			kvPairs = append(kvPairs, [2]string{"Aliases", strings.Join(meta.Aliases, ", ")})
+		}
+		keys := make([]string, 0, len(meta.Properties))
+		for key := range meta.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			kvPairs = append(kvPairs, [2]string{key, meta.Properties[key]})
+		}
 		ui.InfoBox(meta.Title, kvPairs)
-	} else {
+	default:
 		b, err := json.MarshalIndent(meta, "", "  ")
 		if err != nil {
 			fmt.Println("Error marshaling note metadata:", err)
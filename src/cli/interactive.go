@@ -24,12 +24,13 @@ const maxSelectablePageSize = 24 // len(selectKeys)
 // MenuConfig configures the unified menu display
 type MenuConfig struct {
 	Title             string
-	Items             []string          // Note titles to display
-	ItemPaths         map[string]string // Title -> FilePath mapping
-	PreSelectedAction string            // "open", "delete", etc. or "" for full menu
-	ShowPin           bool              // Show pin action
-	ShowUnpin         bool              // Show unpin action (mutually exclusive with ShowPin)
-	HideView          bool              // Hide view action (for non-note items like templates)
+	Items             []string            // Note titles to display
+	ItemPaths         map[string]string   // Title -> FilePath mapping
+	PreSelectedAction string              // "open", "delete", etc. or "" for full menu
+	ShowPin           bool                // Show pin action
+	ShowUnpin         bool                // Show unpin action (mutually exclusive with ShowPin)
+	HideView          bool                // Hide view action (for non-note items like templates)
+	ItemDetails       map[string][]string // Title -> extra lines shown under the item (e.g. search snippets)
 	PageSize          int
 }
 
@@ -43,6 +44,12 @@ type MenuResult struct {
 // In pre-selected mode: user types item letter + Enter
 // In full menu mode: user types action+item combo + Enter (e.g., "oa" to open item a)
 func displayMenu(cfg MenuConfig, ui *UI, mode string) MenuResult {
+	// Commands print their own records in json and tsv mode; a menu that
+	// gets here has nothing to print
+	if machineOutput() {
+		reportError(cfg.Title + " is interactive and has no json or tsv output")
+		return MenuResult{}
+	}
 	if len(cfg.Items) == 0 {
 		fmt.Println("No results found.")
 		return MenuResult{}
@@ -95,7 +102,13 @@ func displayMenu(cfg MenuConfig, ui *UI, mode string) MenuResult {
 		// Display
 		if mode == "tui" {
 			ui.Clear()
-			ui.SelectableList(cfg.Title, pageItems, -1, keys)
+			var details [][]string
+			if cfg.ItemDetails != nil {
+				for _, item := range pageItems {
+					details = append(details, cfg.ItemDetails[item])
+				}
+			}
+			ui.SelectableListWithDetails(cfg.Title, pageItems, details, -1, keys)
 			// Navigation + actions
 			fmt.Printf("\n %s(%d/%d)%s", Dim, page+1, totalPages, Reset)
 			if totalPages > 1 {
@@ -111,6 +124,9 @@ func displayMenu(cfg MenuConfig, ui *UI, mode string) MenuResult {
 			}
 			for i, item := range pageItems {
 				fmt.Printf("[%c] %s\n", keys[i], item)
+				for _, d := range cfg.ItemDetails[item] {
+					fmt.Println("    " + d)
+				}
 			}
 			fmt.Print(": ")
 		} else {
@@ -119,6 +135,9 @@ func displayMenu(cfg MenuConfig, ui *UI, mode string) MenuResult {
 			}
 			for i, item := range pageItems {
 				fmt.Printf("[%c] %s\n", keys[i], item)
+				for _, d := range cfg.ItemDetails[item] {
+					fmt.Println("    " + d)
+				}
 			}
 			fmt.Println()
 			fmt.Printf("(%d/%d)────────────────────────\n", page+1, totalPages)
@@ -245,11 +264,7 @@ func executeMenuAction(result MenuResult, paths map[string]string, ui *UI) {
 			ui.Info("Cancelled")
 			return
 		}
-		if err := core.DeleteNote(result.Note); err != nil {
-			ui.Error(err.Error())
-			return
-		}
-		ui.Success("Moved to trash: " + result.Note)
+		showDeleteResult(core.DeleteNote(result.Note), "Moved to trash: "+result.Note, ui)
 	case "rename":
 		newName := ui.ReadInputWithDefault("New name: ", result.Note)
 		if newName == "" || newName == result.Note {
@@ -334,28 +349,43 @@ func RecentCommand(rawArgs []string, defaults ActionDefaults) {
 		return
 	}
 	pageSize := args.IntOr(cfg.PageSize(), "n", "limit")
+	limited := args.Has("n", "limit")
 
 	// Support bare number as first positional arg (e.g., "gote r 5")
 	if pageSize == cfg.PageSize() && args.First() != "" {
 		if v, err := strconv.Atoi(args.First()); err == nil && v > 0 {
 			pageSize = v
+			limited = true
 		}
 	}
 
 	notes, err := core.GetRecentNotes(-1)
 	if err != nil {
-		fmt.Println("Error getting recent notes:", err)
+		ui.Error("Error getting recent notes: " + err.Error())
 		return
 	}
 
 	// Build items and paths
+	notebook := args.String("notebook", "nb")
 	var titles []string
 	paths := make(map[string]string)
 	for _, note := range notes {
+		if !data.InNotebook(note.Title, notebook) {
+			continue
+		}
 		titles = append(titles, note.Title)
 		paths[note.Title] = note.FilePath
 	}
 
+	// In json and tsv output the page size limits the notes listed
+	if machineOutput() {
+		if limited && pageSize < len(titles) {
+			titles = titles[:pageSize]
+		}
+		writeNotes(noteRecords(titles))
+		return
+	}
+
 	result := displayMenu(MenuConfig{
 		Title:             "Recent Notes",
 		Items:             titles,
@@ -379,8 +409,181 @@ func searchResultsToMenu(results []core.SearchResult) ([]string, map[string]stri
 	return titles, paths
 }
 
+// searchResultDetails formats each result's snippets as "line: text" with the
+// matches highlighted: ANSI styles, or *plain* markers in minimal mode
+func searchResultDetails(results []core.SearchResult, mode string) map[string][]string {
+	details := make(map[string][]string)
+	for _, r := range results {
+		for _, snip := range r.Snippets {
+			details[r.Title] = append(details[r.Title], formatSnippet(snip, mode))
+		}
+	}
+	return details
+}
+
+func formatSnippet(snip core.Snippet, mode string) string {
+	on, off := BoldCyan, Reset
+	lineNo := fmt.Sprintf("%s%d:%s ", Dim, snip.Line, Reset)
+	if mode == "minimal" {
+		on, off = "*", "*"
+		lineNo = fmt.Sprintf("%d: ", snip.Line)
+	}
+
+	var b strings.Builder
+	b.WriteString(lineNo)
+	last := 0
+	for _, m := range snip.Matches {
+		if m[0] < last || m[1] > len(snip.Text) {
+			continue
+		}
+		b.WriteString(snip.Text[last:m[0]])
+		b.WriteString(on + snip.Text[m[0]:m[1]] + off)
+		last = m[1]
+	}
+	b.WriteString(snip.Text[last:])
+	return strings.TrimRight(strings.ReplaceAll(b.String(), "\t", "    "), " ")
+}
+
+// executeSearchAction is executeMenuAction for search results: open jumps to
+// the first matching line and view highlights the query terms
+func executeSearchAction(result MenuResult, results []core.SearchResult, terms []string, ui *UI) {
+	if result.Note == "" || result.Action == "" {
+		return
+	}
+
+	var hit core.SearchResult
+	for _, r := range results {
+		if r.Title == result.Note {
+			hit = r
+			break
+		}
+	}
+
+	switch result.Action {
+	case "open":
+		core.OpenAndReindexNoteAtLine(hit.FilePath, hit.Title, hit.FirstMatchLine())
+	case "view":
+		if err := ViewNoteInBrowserHighlighted(hit.FilePath, hit.Title, terms); err != nil {
+			ui.Error(err.Error())
+		}
+	default:
+		_, paths := searchResultsToMenu(results)
+		executeMenuAction(result, paths, ui)
+	}
+}
+
+// searchFlagArity lists the flags SearchCommand understands. Anything else
+// starting with "-" is part of the query (e.g. -exclude).
+var searchFlagArity = map[string]string{
+	"n": "value", "limit": "value",
+	"t": "tags", "tags": "tags",
+	"w": "dates", "when": "dates",
+	"m": "bool", "modified": "bool",
+	"title":    "bool",
+	"exact":    "bool",
+	"notebook": "value", "nb": "value",
+	"p": "value", "prop": "value",
+}
+
+// splitSearchArgs separates known search flags from query words, so that
+// query operators like -exclude are not mistaken for flags.
+func splitSearchArgs(rawArgs []string) Args {
+	return splitFlagArgs(rawArgs, searchFlagArity)
+}
+
+// splitFlagArgs parses the flags in arity, each taking only the values its
+// arity allows; everything else is positional.
+func splitFlagArgs(rawArgs []string, flagArity map[string]string) Args {
+	var flagArgs, words []string
+	for i := 0; i < len(rawArgs); i++ {
+		arg := rawArgs[i]
+		arity, isFlag := flagArity[strings.TrimLeft(arg, "-")]
+		if !strings.HasPrefix(arg, "-") || arg == "-" || !isFlag {
+			words = append(words, arg)
+			continue
+		}
+		flagArgs = append(flagArgs, arg)
+		switch arity {
+		case "value":
+			if i+1 < len(rawArgs) && !strings.HasPrefix(rawArgs[i+1], "-") {
+				i++
+				flagArgs = append(flagArgs, rawArgs[i])
+			}
+		case "tags":
+			for i+1 < len(rawArgs) && strings.HasPrefix(rawArgs[i+1], ".") {
+				i++
+				flagArgs = append(flagArgs, rawArgs[i])
+			}
+		case "dates":
+			for i+1 < len(rawArgs) && looksLikeDate(rawArgs[i+1]) {
+				i++
+				flagArgs = append(flagArgs, rawArgs[i])
+			}
+		}
+	}
+	args := ParseArgs(flagArgs)
+	args.Positional = words
+	return args
+}
+
+// quoteQueryArg quotes a multi-word flag value, so `--title "weekly sync"`
+// is one phrase and `-p "owner=alice smith"` one property. Query text given
+// as arguments is never quoted: it may hold operators and fields of its own.
+func quoteQueryArg(arg string) string {
+	if !strings.ContainsAny(arg, " \t") || strings.Contains(arg, `"`) {
+		return arg
+	}
+	if colon := strings.Index(arg, ":"); colon > 0 && core.IsQueryField(arg[:colon]) {
+		return arg[:colon+1] + `"` + arg[colon+1:] + `"`
+	}
+	return `"` + arg + `"`
+}
+
+// searchQuery is the query for the words and flags of gote search
+func searchQuery(args Args, tags []string) string {
+	return buildSearchQuery(args, strings.Join(args.Positional, " "), tags)
+}
+
+// buildSearchQuery combines the query text with the -t, -w, --title and
+// --notebook flags into a single query string
+func buildSearchQuery(args Args, text string, tags []string) string {
+	var clauses []string
+	if text != "" {
+		if args.Has("title") {
+			clauses = append(clauses, "title:"+quoteQueryArg(text))
+		} else {
+			clauses = append(clauses, text)
+		}
+	}
+	if len(tags) > 0 {
+		var tagClauses []string
+		field := "tag:"
+		if args.Has("exact") {
+			field = "tag:=" // the tag itself, not the tags below it
+		}
+		for _, tag := range tags {
+			tagClauses = append(tagClauses, field+quoteQueryArg(tag))
+		}
+		clauses = append(clauses, "("+strings.Join(tagClauses, " OR ")+")")
+	}
+	if dates := args.List("w", "when"); len(dates) > 0 {
+		field := "created:"
+		if args.Has("modified", "m") {
+			field = "modified:"
+		}
+		clauses = append(clauses, field+strings.Join(dates, ".."))
+	}
+	if notebook := args.String("notebook", "nb"); notebook != "" {
+		clauses = append(clauses, "notebook:"+quoteQueryArg(notebook))
+	}
+	for _, prop := range append(args.List("p"), args.List("prop")...) {
+		clauses = append(clauses, quoteQueryArg("prop:"+prop))
+	}
+	return strings.Join(clauses, " ")
+}
+
 func SearchCommand(rawArgs []string, defaults ActionDefaults) {
-	args := ParseArgs(rawArgs)
+	args := splitSearchArgs(rawArgs)
 
 	cfg, ui, ok := LoadConfigAndUI()
 	if !ok {
@@ -414,80 +617,67 @@ func SearchCommand(rawArgs []string, defaults ActionDefaults) {
 	}
 
 	preSelected := resolvePreSelectedAction(&args, defaults)
-
 	pageSize := args.IntOr(cfg.PageSize(), "n", "limit")
-	tags := args.TagList("t", "tags")
-	dateValues := args.List("w", "when")
-
-	var results []core.SearchResult
-	var err error
 
-	// Date search mode: -w <date> [<date>] [--modified]
-	if len(dateValues) > 0 && looksLikeDate(dateValues[0]) {
-		useCreated := !args.Has("modified", "m")
-		results, err = core.SearchNotesByDate(dateValues, useCreated, -1)
-		if err != nil {
-			ui.Error(err.Error())
+	// Tag search with no tags given: prompt for them
+	tags := args.TagList("t", "tags")
+	if args.Has("t", "tags") && len(tags) == 0 {
+		if machineOutput() {
+			ui.Error("no tags given")
 			return
 		}
-		if len(results) == 0 {
-			ui.Empty("No notes found in that date range.")
+		fmt.Print("Tags: ")
+		reader := bufio.NewReader(os.Stdin)
+		input, err := reader.ReadString('\n')
+		if err != nil {
 			return
 		}
-	} else if args.Has("t", "tags") {
-		// Tag search mode
+		tags = ParseTagString(strings.TrimSpace(input))
 		if len(tags) == 0 {
-			fmt.Print("Tags: ")
-			reader := bufio.NewReader(os.Stdin)
-			input, err := reader.ReadString('\n')
-			if err != nil {
-				return
-			}
-			tags = ParseTagString(strings.TrimSpace(input))
-			if len(tags) == 0 {
-				return
-			}
-		}
-		results, err = core.SearchNotesByTags(tags, -1)
-		if err != nil {
-			ui.Error(err.Error())
 			return
 		}
-		if len(results) == 0 {
-			ui.Empty("No notes found for the given tags.")
+	}
+
+	query := searchQuery(args, tags)
+	if query == "" {
+		if machineOutput() {
+			ui.Error("no search query given")
 			return
 		}
-	} else {
-		// Search mode: full-text by default, --title for title-only
-		titleOnly := args.Has("title")
-		query := strings.ToLower(args.Joined())
-		if query == "" {
-			fmt.Print("Search: ")
-			reader := bufio.NewReader(os.Stdin)
-			input, err := reader.ReadString('\n')
-			if err != nil {
-				return
-			}
-			query = strings.ToLower(strings.TrimSpace(input))
-			if query == "" {
-				return
-			}
-		}
-		if titleOnly {
-			results, err = core.SearchNotesByTitle(query, -1)
-		} else {
-			results, err = core.SearchNotesCombined(query, -1)
-		}
+		fmt.Print("Search: ")
+		reader := bufio.NewReader(os.Stdin)
+		input, err := reader.ReadString('\n')
 		if err != nil {
-			ui.Error(err.Error())
 			return
 		}
-		if len(results) == 0 {
-			ui.Empty("No matching notes found.")
+		query = buildSearchQuery(args, strings.TrimSpace(input), tags)
+		if query == "" {
 			return
 		}
 	}
 
+	q, err := core.ParseQuery(query)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	results, err := q.Search(-1)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	if machineOutput() {
+		if args.Has("n", "limit") && pageSize < len(results) {
+			results = results[:pageSize]
+		}
+		writeNotes(searchRecords(results))
+		return
+	}
+	if len(results) == 0 {
+		ui.Empty("No matching notes found.")
+		return
+	}
+
 	titles, paths := searchResultsToMenu(results)
 	result := displayMenu(MenuConfig{
 		Title:             "Search Results",
@@ -495,10 +685,11 @@ func SearchCommand(rawArgs []string, defaults ActionDefaults) {
 		ItemPaths:         paths,
 		PreSelectedAction: preSelected,
 		ShowPin:           true,
+		ItemDetails:       searchResultDetails(results, cfg.Interface),
 		PageSize:          pageSize,
 	}, ui, cfg.Interface)
 
-	executeMenuAction(result, paths, ui)
+	executeSearchAction(result, results, q.Terms(), ui)
 }
 
 // GetCommand provides an interactive flow: choose source -> select note with actions
@@ -512,6 +703,7 @@ func GetCommand() {
 	// Step 1: Choose source
 	var results []core.SearchResult
 	var title string
+	var terms []string
 sourceLoop:
 	for {
 		if cfg.IsTUI() {
@@ -554,11 +746,17 @@ sourceLoop:
 			if query == "" {
 				return
 			}
-			results, err = core.SearchNotesCombined(query, -1)
+			q, err := core.ParseQuery(query)
+			if err != nil {
+				ui.Error(err.Error())
+				return
+			}
+			results, err = q.Search(-1)
 			if err != nil {
 				ui.Error(err.Error())
 				return
 			}
+			terms = q.Terms()
 			title = "Search Results"
 			break sourceLoop
 		case "p":
@@ -590,7 +788,7 @@ sourceLoop:
 			if len(tags) == 0 {
 				return
 			}
-			results, err = core.FilterNotesByTags(tags, -1)
+			results, err = core.FilterNotesByTags(tags, -1, false)
 			if err != nil {
 				ui.Error(err.Error())
 				return
@@ -608,12 +806,13 @@ sourceLoop:
 	// Step 2: Display notes with full menu
 	titles, paths := searchResultsToMenu(results)
 	result := displayMenu(MenuConfig{
-		Title:     title,
-		Items:     titles,
-		ItemPaths: paths,
-		ShowPin:   true,
-		PageSize:  pageSize,
+		Title:       title,
+		Items:       titles,
+		ItemPaths:   paths,
+		ShowPin:     true,
+		ItemDetails: searchResultDetails(results, cfg.Interface),
+		PageSize:    pageSize,
 	}, ui, cfg.Interface)
 
-	executeMenuAction(result, paths, ui)
+	executeSearchAction(result, results, terms, ui)
 }
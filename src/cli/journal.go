@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gote/src/core"
+)
+
+func TodayCommand(rawArgs []string) {
+	periodicCommand(core.PeriodDay, rawArgs)
+}
+
+func WeekCommand(rawArgs []string) {
+	periodicCommand(core.PeriodWeek, rawArgs)
+}
+
+func MonthCommand(rawArgs []string) {
+	periodicCommand(core.PeriodMonth, rawArgs)
+}
+
+// periodicCommand opens the note for the current period, or one before or
+// after it
+func periodicCommand(period string, rawArgs []string) {
+	args := ParseArgs(rawArgs)
+
+	_, ui, ok := LoadConfigAndUI()
+	if !ok {
+		return
+	}
+
+	offset, err := periodOffset(args)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	_, err = core.OpenPeriodicNote(period, offset, templatePrompt(ui))
+	if errors.Is(err, core.ErrTemplateCancelled) {
+		ui.Info("Cancelled.")
+	} else if err != nil {
+		ui.Error(err.Error())
+	}
+}
+
+// periodOffset reads how many periods to move from signed numbers ("-1",
+// "+2") and --prev/--next, which take an optional count
+func periodOffset(args Args) (int, error) {
+	offset := 0
+	for _, arg := range args.Positional {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return 0, fmt.Errorf("invalid offset: %s", arg)
+		}
+		offset += n
+	}
+	// "-1" parses as a flag named 1
+	for name, values := range args.flags {
+		if n, err := strconv.Atoi(name); err == nil {
+			if len(values) > 0 {
+				return 0, fmt.Errorf("invalid offset: %s", values[0])
+			}
+			offset -= n
+		}
+	}
+	count := func(names ...string) (int, error) {
+		values := args.List(names...)
+		if len(values) == 0 {
+			return 1, nil
+		}
+		n, err := strconv.Atoi(values[0])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid count: %s", values[0])
+		}
+		return n, nil
+	}
+	if args.Has("prev") {
+		n, err := count("prev")
+		if err != nil {
+			return 0, err
+		}
+		offset -= n
+	}
+	if args.Has("next") {
+		n, err := count("next")
+		if err != nil {
+			return 0, err
+		}
+		offset += n
+	}
+	return offset, nil
+}
+
+func JournalCommand(rawArgs []string, defaults ActionDefaults) {
+	args := ParseArgs(rawArgs)
+	preSelected := resolvePreSelectedAction(&args, defaults)
+
+	cfg, ui, ok := LoadConfigAndUI()
+	if !ok {
+		return
+	}
+
+	period := ""
+	switch args.First() {
+	case "":
+	case "day", "days", "daily":
+		period = core.PeriodDay
+	case "week", "weeks", "weekly":
+		period = core.PeriodWeek
+	case "month", "months", "monthly":
+		period = core.PeriodMonth
+	default:
+		fmt.Println("Unknown subcommand:", args.First())
+		fmt.Println("Usage: gote journal [daily | weekly | monthly]")
+		return
+	}
+
+	notes, err := core.ListPeriodicNotes(period)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	if len(notes) == 0 && !machineOutput() {
+		ui.Empty("No journal notes yet. Start one with gote today.")
+		return
+	}
+
+	var titles []string
+	paths := make(map[string]string)
+	details := make(map[string][]string)
+	for _, n := range notes {
+		titles = append(titles, n.Name)
+		paths[n.Name] = n.FilePath
+		details[n.Name] = []string{periodLabel(n)}
+	}
+	if machineOutput() {
+		writeNotes(noteRecords(titles))
+		return
+	}
+	result := displayMenu(MenuConfig{
+		Title:             "Journal",
+		Items:             titles,
+		ItemPaths:         paths,
+		ItemDetails:       details,
+		PreSelectedAction: preSelected,
+		ShowPin:           true,
+		PageSize:          args.IntOr(cfg.PageSize(), "n", "limit"),
+	}, ui, cfg.Interface)
+	executeMenuAction(result, paths, ui)
+}
+
+// periodLabel describes a periodic note's period, e.g. "Week 42, Oct 12-18 2026"
+func periodLabel(n core.PeriodicNote) string {
+	switch n.Period {
+	case core.PeriodWeek:
+		_, week := n.Start.ISOWeek()
+		end := n.Start.AddDate(0, 0, 6)
+		return fmt.Sprintf("Week %d, %s-%s", week, n.Start.Format("Jan 2"), end.Format("Jan 2 2006"))
+	case core.PeriodMonth:
+		return n.Start.Format("January 2006")
+	}
+	return n.Start.Format("Monday, January 2 2006")
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"gote/src/core"
+)
+
+// LinksCommand lists the notes a note links to
+func LinksCommand(rawArgs []string) {
+	linkMenu(rawArgs, "links", "Links", core.GetLinks)
+}
+
+// BacklinksCommand lists the notes that link to a note
+func BacklinksCommand(rawArgs []string) {
+	linkMenu(rawArgs, "backlinks", "Backlinks", core.GetBacklinks)
+}
+
+func linkMenu(rawArgs []string, cmd, title string, fetch func(string) (string, []core.SearchResult, error)) {
+	args := ParseArgs(rawArgs)
+	noteName := args.Joined()
+
+	cfg, ui, ok := LoadConfigAndUI()
+	if !ok {
+		return
+	}
+
+	if noteName == "" {
+		ui.Info("Usage: gote " + cmd + " <note name>")
+		return
+	}
+
+	noteName, err := ResolveNoteName(noteName)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+
+	// fetch may settle on the closest title, so show the note it picked
+	noteName, results, err := fetch(noteName)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	if machineOutput() {
+		writeNotes(searchRecords(results))
+		return
+	}
+	if len(results) == 0 {
+		ui.Empty("No " + cmd + " found for: " + noteName)
+		return
+	}
+
+	titles, paths := searchResultsToMenu(results)
+	result := displayMenu(MenuConfig{
+		Title:     title + ": " + noteName,
+		Items:     titles,
+		ItemPaths: paths,
+		ShowPin:   true,
+		PageSize:  args.IntOr(cfg.PageSize(), "n", "limit"),
+	}, ui, cfg.Interface)
+
+	executeMenuAction(result, paths, ui)
+}
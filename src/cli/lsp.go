@@ -0,0 +1,704 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"gote/src/core"
+	"gote/src/data"
+)
+
+// lspSymbolLimit caps the notes a workspace symbol search returns
+const lspSymbolLimit = 100
+
+// lspPreviewLines is how much of a note a hover shows
+const lspPreviewLines = 12
+
+// LSPCommand runs a Language Server Protocol server on stdin and stdout for
+// editors to complete, follow and check links and tags against the index.
+// stdout carries the protocol, so messages go to stderr.
+func LSPCommand(rawArgs []string) {
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error loading config:", err)
+		os.Exit(1)
+	}
+	s := newLSPServer(cfg.NoteDir, os.Stdin, os.Stdout)
+	if err := s.serve(); err != nil {
+		fmt.Fprintln(os.Stderr, "gote lsp:", err)
+		os.Exit(1)
+	}
+	if !s.shutdown {
+		os.Exit(1) // exit without shutdown, as the protocol asks
+	}
+}
+
+// lspServer answers one editor's requests in the order they arrive
+type lspServer struct {
+	noteDir string
+	in      *bufio.Reader
+	out     io.Writer
+
+	docs     map[string]string // open documents' text by URI
+	shutdown bool
+
+	indexMod time.Time // modification time of the index file loaded
+	index    map[string]data.NoteMeta
+}
+
+func newLSPServer(noteDir string, in io.Reader, out io.Writer) *lspServer {
+	return &lspServer{noteDir: noteDir, in: bufio.NewReader(in), out: out, docs: make(map[string]string)}
+}
+
+// JSON-RPC messages and the parts of the protocol gote uses
+
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspCompletionItem struct {
+	Label    string      `json:"label"`
+	Kind     int         `json:"kind"`
+	Detail   string      `json:"detail,omitempty"`
+	TextEdit lspTextEdit `json:"textEdit"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspSymbol struct {
+	Name          string      `json:"name"`
+	Kind          int         `json:"kind"`
+	Location      lspLocation `json:"location"`
+	ContainerName string      `json:"containerName,omitempty"`
+}
+
+type lspTextDocumentPosition struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+// Protocol constants gote sends
+const (
+	lspSyncFull          = 1
+	lspCompletionKeyword = 14
+	lspCompletionFile    = 17
+	lspSeverityWarning   = 2
+	lspSymbolFile        = 1
+)
+
+// serve reads and answers messages until the editor sends exit or closes
+// the stream
+func (s *lspServer) serve() error {
+	for {
+		msg, err := s.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, rerr := s.handle(msg)
+		if msg.ID == nil {
+			if rerr != nil {
+				fmt.Fprintf(os.Stderr, "gote lsp: %s: %s\n", msg.Method, rerr.Message)
+			}
+			continue
+		}
+		reply := rpcMessage{JSONRPC: "2.0", ID: msg.ID, Error: rerr}
+		if rerr == nil {
+			reply.Result = json.RawMessage("null")
+			if result != nil {
+				reply.Result = result
+			}
+		}
+		if err := s.write(reply); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads one message: headers, a blank line, then Content-Length bytes
+// of JSON
+func (s *lspServer) read() (rpcMessage, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return rpcMessage{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, _ := strings.Cut(line, ":")
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return rpcMessage{}, fmt.Errorf("bad Content-Length: %s", value)
+			}
+		}
+	}
+	if length < 0 {
+		return rpcMessage{}, errors.New("message without Content-Length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return rpcMessage{}, err
+	}
+	var msg rpcMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return rpcMessage{}, fmt.Errorf("bad message: %w", err)
+	}
+	return msg, nil
+}
+
+func (s *lspServer) write(msg rpcMessage) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// notify sends a notification, which gets no reply
+func (s *lspServer) notify(method string, params any) {
+	raw, _ := json.Marshal(params)
+	if err := s.write(rpcMessage{Method: method, Params: raw}); err != nil {
+		fmt.Fprintln(os.Stderr, "gote lsp:", err)
+	}
+}
+
+// handle runs a request or notification, returning a request's result
+func (s *lspServer) handle(msg rpcMessage) (any, *rpcError) {
+	decode := func(v any) *rpcError {
+		if err := json.Unmarshal(msg.Params, v); err != nil {
+			return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		return nil
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    lspSyncFull,
+					"save":      true,
+				},
+				"completionProvider":      map[string]any{"triggerCharacters": []string{"[", "."}},
+				"definitionProvider":      true,
+				"hoverProvider":           true,
+				"workspaceSymbolProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "gote"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		s.publishDiagnostics(p.TextDocument.URI)
+		return nil, nil
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+		}
+		s.publishDiagnostics(p.TextDocument.URI)
+		return nil, nil
+	case "textDocument/didSave":
+		var p lspTextDocumentPosition
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		s.saved(p.TextDocument.URI)
+		return nil, nil
+	case "textDocument/didClose":
+		var p lspTextDocumentPosition
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		delete(s.docs, p.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", map[string]any{
+			"uri":         p.TextDocument.URI,
+			"diagnostics": []lspDiagnostic{},
+		})
+		return nil, nil
+
+	case "textDocument/completion":
+		var p lspTextDocumentPosition
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		return s.completion(p.TextDocument.URI, p.Position), nil
+	case "textDocument/definition":
+		var p lspTextDocumentPosition
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		return s.definition(p.TextDocument.URI, p.Position), nil
+	case "textDocument/hover":
+		var p lspTextDocumentPosition
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		return s.hover(p.TextDocument.URI, p.Position), nil
+	case "workspace/symbol":
+		var p struct {
+			Query string `json:"query"`
+		}
+		if err := decode(&p); err != nil {
+			return nil, err
+		}
+		symbols, err := s.symbols(p.Query)
+		if err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+		return symbols, nil
+	}
+
+	if msg.ID != nil {
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "unsupported method " + msg.Method}
+	}
+	return nil, nil // notifications gote has no use for, such as initialized
+}
+
+// loadIndex is the index, read again only when the file has changed
+func (s *lspServer) loadIndex() map[string]data.NoteMeta {
+	info, err := os.Stat(data.IndexPath())
+	if err == nil && s.index != nil && info.ModTime().Equal(s.indexMod) {
+		return s.index
+	}
+	index, err := data.LoadIndex()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gote lsp: loading index:", err)
+		return s.index
+	}
+	s.index = index
+	if info != nil {
+		s.indexMod = info.ModTime()
+	}
+	return index
+}
+
+// text is an open document's text, or the file's if it isn't open
+func (s *lspServer) text(uri string) string {
+	if text, ok := s.docs[uri]; ok {
+		return text
+	}
+	content, _ := os.ReadFile(uriToPath(uri))
+	return string(content)
+}
+
+// noteName is the name of the note a document is, which relative Markdown
+// links start from
+func (s *lspServer) noteName(uri string) string {
+	return data.NoteName(s.noteDir, uriToPath(uri))
+}
+
+// lineOf is line n of text without its line ending
+func lineOf(text string, n int) string {
+	for i := 0; i < n; i++ {
+		_, rest, ok := strings.Cut(text, "\n")
+		if !ok {
+			return ""
+		}
+		text = rest
+	}
+	first, _, _ := strings.Cut(text, "\n")
+	return strings.TrimSuffix(first, "\r")
+}
+
+// saved reindexes a note saved in the notes folder, so links to a new note
+// stop being reported and its name completes, then checks open documents again
+func (s *lspServer) saved(uri string) {
+	path := uriToPath(uri)
+	rel, err := filepath.Rel(s.noteDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") || filepath.Ext(path) != ".md" {
+		return
+	}
+	if err := data.IndexNote(path); err != nil {
+		fmt.Fprintln(os.Stderr, "gote lsp: indexing:", err)
+		return
+	}
+	uris := make([]string, 0, len(s.docs))
+	for open := range s.docs {
+		uris = append(uris, open)
+	}
+	sort.Strings(uris)
+	for _, open := range uris {
+		s.publishDiagnostics(open)
+	}
+}
+
+// completion offers note names inside [[ ]] and tags on a note's tag line
+func (s *lspServer) completion(uri string, pos lspPosition) []lspCompletionItem {
+	text := s.text(uri)
+	current := lineOf(text, pos.Line)
+	col := byteOffset(current, pos.Character)
+	before := current[:col]
+	items := []lspCompletionItem{}
+
+	if open := strings.LastIndex(before, "[["); open >= 0 && !strings.Contains(before[open:], "]]") {
+		if strings.ContainsAny(before[open+2:], "|#") {
+			return items // past the name, in an alias or heading
+		}
+		edit := lspRange{Start: lspPosition{pos.Line, utf16Len(current[:open+2])}, End: pos}
+		closing := "]]"
+		if strings.HasPrefix(current[col:], "]]") {
+			closing = ""
+		}
+		index := s.loadIndex()
+		for name, meta := range index {
+			items = append(items, lspCompletionItem{
+				Label:    name,
+				Kind:     lspCompletionFile,
+				Detail:   tagList(meta.Tags),
+				TextEdit: lspTextEdit{Range: edit, NewText: name + closing},
+			})
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+		return items
+	}
+
+	if pos.Line != tagLineNumber(text) || !strings.HasPrefix(before, ".") {
+		return items
+	}
+	start := strings.LastIndex(before, ".") + 1
+	if strings.ContainsAny(before[start:], " \t") {
+		return items
+	}
+	tags, err := data.LoadTags()
+	if err != nil {
+		return items
+	}
+	edit := lspRange{Start: lspPosition{pos.Line, utf16Len(current[:start])}, End: pos}
+	for tag, meta := range tags {
+		items = append(items, lspCompletionItem{
+			Label:    tag,
+			Kind:     lspCompletionKeyword,
+			Detail:   fmt.Sprintf("%d notes", meta.Total),
+			TextEdit: lspTextEdit{Range: edit, NewText: tag},
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items
+}
+
+// tagLineNumber is the line a note's .tag line goes on: the first after any
+// front matter
+func tagLineNumber(text string) int {
+	if block, _, ok := data.SplitFrontMatter(text); ok {
+		return len(block) + 2
+	}
+	return 0
+}
+
+// linkAt is the link under the cursor, if there is one
+func (s *lspServer) linkAt(uri string, pos lspPosition) (data.LinkSpan, string, bool) {
+	current := lineOf(s.text(uri), pos.Line)
+	col := byteOffset(current, pos.Character)
+	for _, span := range data.FindLinks(current, s.noteName(uri)) {
+		if span.Start <= col && col < span.End {
+			return span, current, true
+		}
+	}
+	return data.LinkSpan{}, "", false
+}
+
+// definition goes to the note a link names, at the heading it names if any
+func (s *lspServer) definition(uri string, pos lspPosition) *lspLocation {
+	span, _, ok := s.linkAt(uri, pos)
+	if !ok {
+		return nil
+	}
+	_, meta, ok := data.LookupNote(s.loadIndex(), span.Target)
+	if !ok {
+		return nil
+	}
+	target := lspPosition{Line: headingLine(meta.FilePath, span.Anchor)}
+	return &lspLocation{URI: pathToURI(meta.FilePath), Range: lspRange{Start: target, End: target}}
+}
+
+// headingLine is the line of the heading in a note that anchor names, or 0
+func headingLine(path, anchor string) int {
+	if anchor == "" {
+		return 0
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	want := headingID(anchor)
+	for i, text := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(text, "#")
+		if len(trimmed) < len(text) && headingID(trimmed) == want {
+			return i
+		}
+	}
+	return 0
+}
+
+// hover shows the index entry of a linked note and the start of its text
+func (s *lspServer) hover(uri string, pos lspPosition) any {
+	span, current, ok := s.linkAt(uri, pos)
+	if !ok {
+		return nil
+	}
+	rng := lspRange{
+		Start: lspPosition{pos.Line, utf16Len(current[:span.Start])},
+		End:   lspPosition{pos.Line, utf16Len(current[:span.End])},
+	}
+	name, meta, ok := data.LookupNote(s.loadIndex(), span.Target)
+	var b strings.Builder
+	if !ok {
+		fmt.Fprintf(&b, "No note named **%s**", span.Target)
+	} else {
+		fmt.Fprintf(&b, "**%s**", name)
+		if len(meta.Tags) > 0 {
+			b.WriteString("  " + tagList(meta.Tags))
+		}
+		b.WriteString("\n\n" + noteFacts(meta))
+		if preview := notePreview(meta.FilePath); preview != "" {
+			b.WriteString("\n\n---\n\n" + preview)
+		}
+	}
+	return map[string]any{
+		"contents": map[string]string{"kind": "markdown", "value": b.String()},
+		"range":    rng,
+	}
+}
+
+// noteFacts is a line of a note's dates, size, tasks and aliases
+func noteFacts(meta data.NoteMeta) string {
+	facts := []string{
+		"Created " + formatIndexTime(meta.Created),
+		"Modified " + formatIndexTime(meta.Modified),
+		fmt.Sprintf("%d words", meta.WordCount),
+	}
+	if len(meta.Tasks) > 0 {
+		done := 0
+		for _, task := range meta.Tasks {
+			if task.Done {
+				done++
+			}
+		}
+		facts = append(facts, fmt.Sprintf("%d/%d tasks done", done, len(meta.Tasks)))
+	}
+	if len(meta.Links) > 0 {
+		facts = append(facts, fmt.Sprintf("%d links", len(meta.Links)))
+	}
+	if len(meta.Aliases) > 0 {
+		facts = append(facts, "aka "+strings.Join(meta.Aliases, ", "))
+	}
+	return strings.Join(facts, " · ")
+}
+
+// formatIndexTime shows an index timestamp ("260117.093000") as a date
+func formatIndexTime(stamp string) string {
+	t, err := time.ParseInLocation("060102.150405", stamp, time.Local)
+	if err != nil {
+		return stamp
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+func tagList(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "." + strings.Join(tags, " .")
+}
+
+// notePreview is the start of a note's text, after its front matter and
+// tag line
+func notePreview(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	_, body, _ := data.SplitFrontMatter(string(content))
+	if first, rest, _ := strings.Cut(body, "\n"); data.ParseTags(strings.TrimSpace(first)) != nil {
+		body = rest
+	}
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) > lspPreviewLines {
+		lines = append(lines[:lspPreviewLines], "…")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// symbols finds notes for a workspace symbol search as gote search does
+func (s *lspServer) symbols(query string) ([]lspSymbol, error) {
+	symbols := []lspSymbol{}
+	if strings.TrimSpace(query) == "" {
+		return symbols, nil
+	}
+	results, err := core.SearchNotesCombined(query, lspSymbolLimit)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		symbols = append(symbols, lspSymbol{
+			Name:          r.Title,
+			Kind:          lspSymbolFile,
+			Location:      lspLocation{URI: pathToURI(r.FilePath)},
+			ContainerName: data.NotebookOf(r.Title),
+		})
+	}
+	return symbols, nil
+}
+
+// publishDiagnostics warns about each link in a document to a note that
+// doesn't exist. Embedded files such as images aren't notes and are skipped.
+func (s *lspServer) publishDiagnostics(uri string) {
+	index := s.loadIndex()
+	diagnostics := []lspDiagnostic{}
+	from := s.noteName(uri)
+	for n, text := range strings.Split(s.text(uri), "\n") {
+		text = strings.TrimSuffix(text, "\r")
+		for _, span := range data.FindLinks(text, from) {
+			if ext := filepath.Ext(span.Target); span.Embed && ext != "" && ext != ".md" {
+				continue
+			}
+			if _, _, ok := data.LookupNote(index, span.Target); ok {
+				continue
+			}
+			diagnostics = append(diagnostics, lspDiagnostic{
+				Range: lspRange{
+					Start: lspPosition{n, utf16Len(text[:span.Start])},
+					End:   lspPosition{n, utf16Len(text[:span.End])},
+				},
+				Severity: lspSeverityWarning,
+				Source:   "gote",
+				Message:  fmt.Sprintf("No note named %q", span.Target),
+			})
+		}
+	}
+	s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": diagnostics})
+}
+
+// utf16Len is the length of s in UTF-16 code units, which LSP positions count
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// byteOffset is the byte offset in line of an LSP character position
+func byteOffset(line string, char int) int {
+	units := 0
+	for i, r := range line {
+		if units >= char {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(line)
+}
+
+// uriToPath turns a file:// URI into a path
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/") // /C:/notes
+	}
+	return filepath.FromSlash(path)
+}
+
+// pathToURI turns a path into a file:// URI
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
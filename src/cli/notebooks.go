@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"gote/src/core"
+)
+
+// NotebookCommand lists notebooks, creates them and moves notes between them
+func NotebookCommand(rawArgs []string) {
+	args := ParseArgs(rawArgs)
+	sub := args.First()
+
+	cfg, ui, ok := LoadConfigAndUI()
+	if !ok {
+		return
+	}
+
+	switch sub {
+	case "":
+		notebooks, err := core.ListNotebooks()
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		if len(notebooks) == 0 {
+			ui.Empty("No notebooks. Create one with: gote nb create <name>")
+			return
+		}
+		var lines []string
+		for _, nb := range notebooks {
+			line := fmt.Sprintf("%s (%d)", nb.Name, nb.Notes)
+			if nb.Total != nb.Notes {
+				line = fmt.Sprintf("%s (%d, %d with sub-notebooks)", nb.Name, nb.Notes, nb.Total)
+			}
+			lines = append(lines, line)
+		}
+		if cfg.IsTUI() {
+			ui.Box("Notebooks", lines, 0)
+		} else {
+			for _, line := range lines {
+				fmt.Println(line)
+			}
+		}
+	case "create", "new":
+		name := strings.Join(args.Rest(), " ")
+		if name == "" {
+			ui.Info("Usage: gote nb create <notebook>")
+			return
+		}
+		if err := core.CreateNotebook(name); err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		ui.Success("Created notebook: " + strings.Trim(name, "/"))
+	case "move", "mv":
+		noteName := strings.Join(args.Rest(), " ")
+		if noteName == "" || !args.Has("to") {
+			ui.Info("Usage: gote nb move <note> --to <notebook>   (--to / for the top level)")
+			return
+		}
+		noteName, err := ResolveNoteName(noteName)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		newName, err := core.MoveNote(noteName, strings.Join(args.List("to"), " "))
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		ui.Success(fmt.Sprintf("Moved '%s' to '%s'", noteName, newName))
+	case "help":
+		fmt.Println(`Notebooks are folders under your notes directory.
+A note in one is named by its path, e.g. "gote work/standup".
+
+gote nb                              List notebooks with note counts
+gote nb <notebook>                   Browse notes in a notebook
+gote nb create <notebook>            Create a notebook (nested: a/b)
+gote nb move <note> --to <notebook>  Move a note (--to / for top level)
+
+--notebook <name> filters recent, search and tag results.`)
+	default:
+		// Browse a notebook's notes
+		notebook := strings.Trim(args.Joined(), "/")
+		results, err := core.SearchQuery("notebook:"+quoteQueryArg(notebook), -1)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		if machineOutput() {
+			writeNotes(searchRecords(results))
+			return
+		}
+		if len(results) == 0 {
+			ui.Empty("No notes in notebook: " + notebook)
+			return
+		}
+		titles, paths := searchResultsToMenu(results)
+		result := displayMenu(MenuConfig{
+			Title:     "Notebook: " + notebook,
+			Items:     titles,
+			ItemPaths: paths,
+			ShowPin:   true,
+			PageSize:  args.IntOr(cfg.PageSize(), "n", "limit"),
+		}, ui, cfg.Interface)
+
+		executeMenuAction(result, paths, ui)
+	}
+}
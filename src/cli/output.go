@@ -0,0 +1,314 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gote/src/core"
+	"gote/src/data"
+)
+
+// Output formats for listings, chosen with the global --json and --format flags
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatTSV  = "tsv"
+)
+
+var outputFormat = FormatText
+
+// errorReported is set when an error is reported in json or tsv mode, so
+// gote can exit with a failure status
+var errorReported bool
+
+// ExtractOutputFlags removes the global --json and --format <fmt> flags from
+// args, wherever they appear, and selects the output format they ask for
+func ExtractOutputFlags(args []string) ([]string, error) {
+	format := FormatText
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--json":
+			format = FormatJSON
+		case arg == "--format":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("--format needs a value: text, json or tsv")
+			}
+			i++
+			format = args[i]
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	switch format {
+	case FormatText, FormatJSON, FormatTSV:
+	default:
+		return nil, fmt.Errorf("unknown output format %q (use text, json or tsv)", format)
+	}
+	outputFormat = format
+	return rest, nil
+}
+
+// machineOutput reports whether listings print records instead of menus
+func machineOutput() bool {
+	return outputFormat != FormatText
+}
+
+// ExitCode is the status gote exits with: 1 if an error was reported while
+// writing json or tsv output, else 0
+func ExitCode() int {
+	if errorReported {
+		return 1
+	}
+	return 0
+}
+
+// NoteRecord is a note in json and tsv output: its index entry, whether it
+// is pinned, and for search results its score and matching lines. The schema
+// is documented in the README; fields may be added but are never renamed or
+// removed, so it is spelled out here rather than following the index format.
+type NoteRecord struct {
+	Title       string            `json:"title"`
+	FilePath    string            `json:"filePath"`
+	Created     string            `json:"created"`
+	Modified    string            `json:"modified"`
+	LastVisited string            `json:"lastVisited,omitempty"`
+	WordCount   int               `json:"wordCount"`
+	CharCount   int               `json:"charCount"`
+	Tags        []string          `json:"tags"`
+	Links       []string          `json:"links,omitempty"`
+	Tasks       []TaskRecord      `json:"tasks,omitempty"`
+	Aliases     []string          `json:"aliases,omitempty"`
+	Properties  map[string]string `json:"properties,omitempty"`
+	Pinned      bool              `json:"pinned"`
+	Score       int               `json:"score,omitempty"`
+	Snippets    []SnippetRecord   `json:"snippets,omitempty"`
+}
+
+// TaskRecord is a checkbox task inside a NoteRecord
+type TaskRecord struct {
+	Line int      `json:"line"`
+	Text string   `json:"text"`
+	Done bool     `json:"done"`
+	Tags []string `json:"tags,omitempty"`
+	Due  string   `json:"due,omitempty"`
+}
+
+// SnippetRecord is a line of a note that matched a search
+type SnippetRecord struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// newNoteRecord copies the documented fields of an index entry
+func newNoteRecord(meta data.NoteMeta, pinned bool) NoteRecord {
+	r := NoteRecord{
+		Title:       meta.Title,
+		FilePath:    meta.FilePath,
+		Created:     meta.Created,
+		Modified:    meta.Modified,
+		LastVisited: meta.LastVisited,
+		WordCount:   meta.WordCount,
+		CharCount:   meta.CharCount,
+		Tags:        meta.Tags,
+		Links:       meta.Links,
+		Aliases:     meta.Aliases,
+		Properties:  meta.Properties,
+		Pinned:      pinned,
+	}
+	if r.Tags == nil {
+		r.Tags = []string{} // [] rather than null in json
+	}
+	for _, t := range meta.Tasks {
+		r.Tasks = append(r.Tasks, TaskRecord{Line: t.Line, Text: t.Text, Done: t.Done, Tags: t.Tags, Due: t.Due})
+	}
+	return r
+}
+
+// noteRecords looks up the named notes in the index. Names it doesn't have
+// get a record with just their title.
+func noteRecords(names []string) []NoteRecord {
+	index, _ := data.LoadIndex()
+	pins, _ := data.LoadPins()
+	records := make([]NoteRecord, 0, len(names))
+	for _, name := range names {
+		meta, ok := index[name]
+		if !ok {
+			meta = data.NoteMeta{Title: name}
+		}
+		_, pinned := pins[name]
+		records = append(records, newNoteRecord(meta, pinned))
+	}
+	return records
+}
+
+// searchRecords turns search results into records with their scores and snippets
+func searchRecords(results []core.SearchResult) []NoteRecord {
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.Title
+	}
+	records := noteRecords(names)
+	for i, r := range results {
+		records[i].Score = r.Score
+		if records[i].FilePath == "" {
+			records[i].FilePath = r.FilePath
+		}
+		for _, snip := range r.Snippets {
+			records[i].Snippets = append(records[i].Snippets, SnippetRecord{Line: snip.Line, Text: snip.Text})
+		}
+	}
+	return records
+}
+
+// writeNotes prints notes as a JSON array, or as TSV with a header row
+func writeNotes(records []NoteRecord) {
+	if outputFormat == FormatJSON {
+		writeJSON(records)
+		return
+	}
+	rows := make([][]string, len(records))
+	for i, r := range records {
+		rows[i] = []string{r.Title, r.FilePath, r.Created, r.Modified, strings.Join(r.Tags, ","),
+			strconv.FormatBool(r.Pinned), strconv.Itoa(r.Score)}
+	}
+	writeTSV([]string{"title", "filePath", "created", "modified", "tags", "pinned", "score"}, rows)
+}
+
+// writeTags prints tags.json entries
+func writeTags(tags []data.TagMeta) {
+	if outputFormat == FormatJSON {
+		if tags == nil {
+			tags = []data.TagMeta{}
+		}
+		writeJSON(tags)
+		return
+	}
+	rows := make([][]string, len(tags))
+	for i, t := range tags {
+		rows[i] = []string{t.Tag, strconv.Itoa(t.Count), strconv.Itoa(t.Total)}
+	}
+	writeTSV([]string{"tag", "count", "total"}, rows)
+}
+
+// TrashRecord is a trash manifest entry in json and tsv output
+type TrashRecord struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	File      string     `json:"file"`
+	DeletedAt time.Time  `json:"deletedAt"`
+	Meta      NoteRecord `json:"meta"`
+	Pinned    bool       `json:"pinned"`
+}
+
+// writeTrash prints trash entries as stored in the trash manifest
+func writeTrash(entries []data.TrashEntry) {
+	if outputFormat == FormatJSON {
+		records := make([]TrashRecord, 0, len(entries))
+		for _, e := range entries {
+			records = append(records, TrashRecord{ID: e.ID, Name: e.Name, File: e.File, DeletedAt: e.DeletedAt,
+				Meta: newNoteRecord(e.Meta, e.Pinned), Pinned: e.Pinned})
+		}
+		writeJSON(records)
+		return
+	}
+	rows := make([][]string, len(entries))
+	for i, e := range entries {
+		rows[i] = []string{e.ID, e.Name, e.DeletedAt.Format(time.RFC3339), e.Meta.FilePath}
+	}
+	writeTSV([]string{"id", "name", "deletedAt", "filePath"}, rows)
+}
+
+// TemplateRecord is a template and its settings in json and tsv output
+type TemplateRecord struct {
+	Name      string   `json:"name"`
+	FilePath  string   `json:"filePath"`
+	NoteName  string   `json:"noteName,omitempty"`
+	Tags      []string `json:"tags"`
+	Timestamp string   `json:"timestamp,omitempty"`
+	Pin       bool     `json:"pin"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// writeTemplates prints templates with the settings in their front matter
+func writeTemplates(infos []core.TemplateInfo) {
+	records := make([]TemplateRecord, 0, len(infos))
+	for _, info := range infos {
+		r := TemplateRecord{
+			Name:      info.Name,
+			FilePath:  filepath.Join(data.TemplatesDir(), info.Name+".md"),
+			NoteName:  info.Settings.Name,
+			Tags:      info.Settings.Tags,
+			Timestamp: info.Settings.Timestamp,
+			Pin:       info.Settings.Pin,
+		}
+		if r.Tags == nil {
+			r.Tags = []string{}
+		}
+		if info.Err != nil {
+			r.Error = info.Err.Error()
+		}
+		records = append(records, r)
+	}
+	if outputFormat == FormatJSON {
+		writeJSON(records)
+		return
+	}
+	rows := make([][]string, len(records))
+	for i, r := range records {
+		rows[i] = []string{r.Name, r.FilePath, r.NoteName, strings.Join(r.Tags, ","), r.Timestamp,
+			strconv.FormatBool(r.Pin), r.Error}
+	}
+	writeTSV([]string{"name", "filePath", "noteName", "tags", "timestamp", "pin", "error"}, rows)
+}
+
+// writeTasks prints checkbox tasks with the note each is in
+func writeTasks(tasks []core.TaskItem) {
+	if outputFormat == FormatJSON {
+		if tasks == nil {
+			tasks = []core.TaskItem{}
+		}
+		writeJSON(tasks)
+		return
+	}
+	rows := make([][]string, len(tasks))
+	for i, t := range tasks {
+		rows[i] = []string{t.Note, strconv.Itoa(t.Line), strconv.FormatBool(t.Done), t.Due, strings.Join(t.Tags, ","), t.Text}
+	}
+	writeTSV([]string{"note", "line", "done", "due", "tags", "text"}, rows)
+}
+
+func writeJSON(v any) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		reportError("encoding output: " + err.Error())
+		return
+	}
+	fmt.Println(string(b))
+}
+
+// writeTSV prints a header row and rows. Tabs and newlines inside fields
+// become spaces so every record stays on one line.
+func writeTSV(header []string, rows [][]string) {
+	clean := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+	fmt.Println(strings.Join(header, "\t"))
+	for _, row := range rows {
+		for i, field := range row {
+			row[i] = clean.Replace(field)
+		}
+		fmt.Println(strings.Join(row, "\t"))
+	}
+}
+
+// reportError prints an error on stderr and records it for the exit status
+func reportError(text string) {
+	fmt.Fprintln(os.Stderr, "Error:", text)
+	errorReported = true
+}
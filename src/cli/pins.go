@@ -93,6 +93,10 @@ func PinnedCommand(rawArgs []string, defaults ActionDefaults) {
 		ui.Error(err.Error())
 		return
 	}
+	if machineOutput() {
+		writeNotes(noteRecords(pins))
+		return
+	}
 	if len(pins) == 0 {
 		ui.Empty("No pinned notes.")
 		return
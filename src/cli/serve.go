@@ -0,0 +1,379 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gote/src/core"
+	"gote/src/data"
+)
+
+// DefaultServeAddr is where gote serve listens unless given --addr
+const DefaultServeAddr = "127.0.0.1:7777"
+
+// serveRecentLimit caps the notes listed on the server's front page
+const serveRecentLimit = 100
+
+// ServeCommand runs a local web server that renders notes on demand, with
+// recent notes, tag pages and search. Pages reload when their note changes
+// on disk, and the index is kept in sync as gote watch keeps it.
+func ServeCommand(rawArgs []string) {
+	args := ParseArgs(rawArgs)
+
+	cfg, ui, ok := LoadConfigAndUI()
+	if !ok {
+		return
+	}
+	addr := args.String("addr", "a")
+	if addr == "" {
+		addr = DefaultServeAddr
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	home := "http://" + ln.Addr().String() + "/"
+	if tcp, ok := ln.Addr().(*net.TCPAddr); ok && !tcp.IP.IsLoopback() {
+		ui.Info("Warning: " + ln.Addr().String() + " is not a loopback address; anyone who can reach it can read your notes")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ns := newNoteServer(cfg.NoteDir, ln.Addr().String())
+	go func() {
+		err := core.Watch(ctx, core.WatchOptions{
+			OnEvent: ns.broadcast,
+			OnError: func(err error) { ui.Error(err.Error()) },
+		})
+		if err != nil {
+			ui.Error("live reload is off: " + err.Error())
+		}
+	}()
+
+	// gote view opens notes here while the server runs
+	if err := os.WriteFile(data.ServerAddrPath(), []byte(ln.Addr().String()), 0644); err != nil {
+		ui.Error("could not record the server address: " + err.Error())
+	}
+	defer os.Remove(data.ServerAddrPath())
+
+	server := &http.Server{
+		Handler:     ns.routes(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	ui.Info("Serving notes at " + home + " Press Ctrl-C to stop.")
+	if args.Has("open", "o") {
+		openInBrowser(home)
+	}
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		ui.Error(err.Error())
+		return
+	}
+	ui.Info("Stopped serving.")
+}
+
+// runningServer is the address of a gote serve that answers, or ""
+func runningServer() string {
+	addr, err := os.ReadFile(data.ServerAddrPath())
+	if err != nil {
+		return ""
+	}
+	client := http.Client{Timeout: 500 * time.Millisecond}
+	resp, err := client.Get("http://" + string(addr) + "/ping")
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 16))
+	if resp.StatusCode != http.StatusOK || string(body) != "gote" {
+		return ""
+	}
+	return string(addr)
+}
+
+// noteServer renders the vault's notes as the pages of an exported site
+// would be, but from the index as it is at each request
+type noteServer struct {
+	noteDir string
+	hosts   map[string]bool // Host header names it answers besides loopback
+
+	mu      sync.Mutex
+	clients map[chan core.WatchEvent]struct{} // pages waiting for changes
+}
+
+// newNoteServer serves the notes in noteDir from the listen address addr
+func newNoteServer(noteDir, addr string) *noteServer {
+	return &noteServer{noteDir: noteDir, hosts: serverHosts(addr), clients: make(map[chan core.WatchEvent]struct{})}
+}
+
+// serverHosts is the names a server listening on addr is reached by: the
+// listen host and localhost, and every address of this machine when it
+// listens on all of them
+func serverHosts(addr string) map[string]bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	hosts := map[string]bool{"localhost": true, strings.ToLower(host): true}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		addrs, _ := net.InterfaceAddrs()
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok {
+				hosts[ipNet.IP.String()] = true
+			}
+		}
+	}
+	return hosts
+}
+
+// allowedHost reports whether a request's Host header names this server.
+// A page from another site can point its own domain at 127.0.0.1 (DNS
+// rebinding); the Host header it sends still names that domain.
+func (ns *noteServer) allowedHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(hostport, "["), "]")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ns.hosts[ip.String()]
+	}
+	return ns.hosts[strings.ToLower(host)]
+}
+
+func (ns *noteServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", ns.recent)
+	mux.HandleFunc("GET /index.html", ns.recent)
+	mux.HandleFunc("GET /notes/{path...}", ns.note)
+	mux.HandleFunc("GET /tags/index.html", ns.tags)
+	mux.HandleFunc("GET /tags/{tag...}", ns.tag)
+	mux.HandleFunc("GET /search.html", ns.search)
+	mux.HandleFunc("GET /events", ns.events)
+	mux.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "gote")
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ns.allowedHost(r.Host) {
+			http.Error(w, "unknown host: "+r.Host, http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// site is the vault as it is indexed now
+func (ns *noteServer) site(w http.ResponseWriter) (*site, bool) {
+	index, err := data.LoadIndex()
+	if err != nil {
+		http.Error(w, "error loading index: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return newSite("", ns.noteDir, index), true
+}
+
+// render writes a page that reloads itself when note changes, or any note
+// does if note is ""
+func (ns *noteServer) render(w http.ResponseWriter, title, body, note string) {
+	watch := "null"
+	if note != "" {
+		raw, _ := json.Marshal(note)
+		watch = string(raw)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, wrapInHTMLTemplate(html.EscapeString(title), siteNav("/")+body+fmt.Sprintf(liveReload, watch)))
+}
+
+// recent lists the notes most recently opened or modified
+func (ns *noteServer) recent(w http.ResponseWriter, r *http.Request) {
+	s, ok := ns.site(w)
+	if !ok {
+		return
+	}
+	notes, err := core.GetRecentNotes(serveRecentLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	names := make([]string, len(notes))
+	for i, meta := range notes {
+		names[i] = meta.Title
+	}
+	body := fmt.Sprintf("<h1>Recent</h1>\n<p>%d of %d notes, most recently opened first.</p>\n", len(names), len(s.names))
+	ns.render(w, "Recent", body+s.noteList("/", names), "")
+}
+
+// note renders /notes/<name>.html, highlighting the words in ?hl=. Other
+// paths below /notes/ are files in the notes folder, such as the images
+// notes show.
+func (ns *noteServer) note(w http.ResponseWriter, r *http.Request) {
+	rel := r.PathValue("path")
+	if name, ok := strings.CutSuffix(rel, ".html"); ok {
+		s, ok := ns.site(w)
+		if !ok {
+			return
+		}
+		if _, exists := s.notes[name]; exists {
+			body, err := s.notePage(name, "/")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if words := strings.Fields(r.URL.Query().Get("hl")); len(words) > 0 {
+				body = highlightHTML(body, words)
+			}
+			ns.render(w, name, body, name)
+			return
+		}
+	}
+
+	for _, part := range strings.Split(rel, "/") {
+		if strings.HasPrefix(part, ".") {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	if info, err := os.Stat(filepath.Join(ns.noteDir, filepath.FromSlash(rel))); err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFileFS(w, r, os.DirFS(ns.noteDir), rel)
+}
+
+func (ns *noteServer) tags(w http.ResponseWriter, r *http.Request) {
+	s, ok := ns.site(w)
+	if !ok {
+		return
+	}
+	ns.render(w, "Tags", s.tagsPage("/", s.notesByTag()), "")
+}
+
+// tag lists the notes with a tag or one below it
+func (ns *noteServer) tag(w http.ResponseWriter, r *http.Request) {
+	s, ok := ns.site(w)
+	if !ok {
+		return
+	}
+	tag := strings.TrimSuffix(r.PathValue("tag"), ".html")
+	names, exists := s.notesByTag()[tag]
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	ns.render(w, "."+tag, s.tagPage("/", tag, names), "")
+}
+
+// search runs ?q= as gote search does without a query syntax: over titles
+// and full text
+func (ns *noteServer) search(w http.ResponseWriter, r *http.Request) {
+	s, ok := ns.site(w)
+	if !ok {
+		return
+	}
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	var b strings.Builder
+	fmt.Fprintf(&b, `<h1>Search</h1>
+<form action="/search.html"><input name="q" type="search" value="%s" placeholder="Search notes" autofocus style="width: 100%%; padding: 0.5em; font-size: 1rem"></form>
+`, html.EscapeString(q))
+	if q != "" {
+		results, err := core.SearchNotesCombined(q, -1)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(results) == 0 {
+			b.WriteString("<p>No matching notes.</p>\n")
+		}
+		b.WriteString("<ul>\n")
+		for _, result := range results {
+			fmt.Fprintf(&b, `<li><a href="%s?hl=%s">%s</a> <small>%s</small></li>`+"\n",
+				noteURL("/", result.Title), url.QueryEscape(q), html.EscapeString(result.Title), tagLinks("/", s.notes[result.Title].Tags))
+		}
+		b.WriteString("</ul>\n")
+	}
+	ns.render(w, "Search", b.String(), "")
+}
+
+// events streams the watcher's changes to pages as server-sent events
+func (ns *noteServer) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan core.WatchEvent, 16)
+	ns.mu.Lock()
+	ns.clients[ch] = struct{}{}
+	ns.mu.Unlock()
+	defer func() {
+		ns.mu.Lock()
+		delete(ns.clients, ch)
+		ns.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	io.WriteString(w, ": watching\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-ch:
+			raw, _ := json.Marshal(map[string]string{
+				"kind":    ev.Kind,
+				"name":    ev.Name,
+				"oldName": ev.OldName,
+				"url":     noteURL("/", ev.Name),
+			})
+			fmt.Fprintf(w, "data: %s\n\n", raw)
+			flusher.Flush()
+		}
+	}
+}
+
+// broadcast passes a change on to every page listening. A page that isn't
+// keeping up misses it rather than holding up the watcher.
+func (ns *noteServer) broadcast(ev core.WatchEvent) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	for ch := range ns.clients {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// liveReload reloads a page when the note it shows changes, following it if
+// renamed. With no note (null) any change reloads it.
+const liveReload = `<script>
+(function () {
+  var note = %s;
+  new EventSource('/events').onmessage = function (e) {
+    var ev = JSON.parse(e.data);
+    if (note !== null && ev.kind === 'renamed' && ev.oldName === note) location.href = ev.url;
+    else if (note === null || ev.name === note) location.reload();
+  };
+})();
+</script>
+`
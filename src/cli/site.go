@@ -0,0 +1,516 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gote/src/data"
+)
+
+// siteMarker marks a directory as a site gote exported, so a later export
+// may clear out its old pages
+const siteMarker = ".gote-site"
+
+// site renders a set of notes into a static HTML site: an index page, a
+// page per note under notes/, a page per tag under tags/ and a search page.
+// Files the notes show or link to, such as images, go under notes/ at their
+// path in the notes folder, so the notes' relative links reach them.
+type site struct {
+	dir     string
+	noteDir string
+	notes   map[string]data.NoteMeta
+	names   []string // newest first
+
+	files     map[string]bool   // notes folder files the rendered notes use
+	fileNames map[string]string // lower-case base name -> file, "" if several share it
+}
+
+// exportSite writes the notes in noteDir as a site in dir. dir must be new,
+// empty, or a site exported before, whose notes/ and tags/ pages are replaced.
+func exportSite(dir, noteDir string, notes map[string]data.NoteMeta) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(entries) > 0 {
+		if _, err := os.Stat(filepath.Join(dir, siteMarker)); err != nil {
+			return fmt.Errorf("%s is not empty and was not exported by gote", dir)
+		}
+		for _, sub := range []string{"notes", "tags"} {
+			if err := os.RemoveAll(filepath.Join(dir, sub)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, siteMarker), nil, 0644); err != nil {
+		return err
+	}
+
+	s := newSite(dir, noteDir, notes)
+	for _, name := range s.names {
+		if err := s.writeNote(name); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if err := s.copyFiles(); err != nil {
+		return err
+	}
+	if err := s.writeIndex(); err != nil {
+		return err
+	}
+	if err := s.writeTags(); err != nil {
+		return err
+	}
+	return s.writeSearch()
+}
+
+// newSite prepares notes to be rendered, most recently modified first
+func newSite(dir, noteDir string, notes map[string]data.NoteMeta) *site {
+	s := &site{dir: dir, noteDir: noteDir, notes: notes, files: make(map[string]bool)}
+	for name := range notes {
+		s.names = append(s.names, name)
+	}
+	sort.Slice(s.names, func(i, j int) bool {
+		a, b := notes[s.names[i]], notes[s.names[j]]
+		if a.Modified != b.Modified {
+			return a.Modified > b.Modified
+		}
+		return s.names[i] < s.names[j]
+	})
+	return s
+}
+
+// write saves a page at rel, a slash-separated path below the site
+func (s *site) write(rel, title, root, body string) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	page := wrapInHTMLTemplate(html.EscapeString(title), siteNav(root)+body)
+	return os.WriteFile(path, []byte(page), 0644)
+}
+
+// rootOf is the relative path from the page at rel back to the site root
+func rootOf(rel string) string {
+	return strings.Repeat("../", strings.Count(rel, "/"))
+}
+
+func siteNav(root string) string {
+	return fmt.Sprintf(`<nav><a href="%[1]sindex.html">Notes</a> · <a href="%[1]stags/index.html">Tags</a> · <a href="%[1]ssearch.html">Search</a></nav>`+"\n", root)
+}
+
+// escapeURLPath escapes a note or tag name for use in a link
+func escapeURLPath(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
+
+func noteURL(root, name string) string {
+	return root + "notes/" + escapeURLPath(name) + ".html"
+}
+
+func tagURL(root, tag string) string {
+	return root + "tags/" + escapeURLPath(tag) + ".html"
+}
+
+// headingID turns a #heading link anchor into the id goldmark gives the heading
+func headingID(anchor string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(strings.TrimPrefix(anchor, "#"))) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune('-')
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "#" + b.String()
+}
+
+// tagLinks renders tags as links to their pages
+func tagLinks(root string, tags []string) string {
+	links := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		links = append(links, fmt.Sprintf(`<a href="%s">.%s</a>`, tagURL(root, tag), html.EscapeString(tag)))
+	}
+	return strings.Join(links, " ")
+}
+
+func (s *site) writeNote(name string) error {
+	rel := "notes/" + name + ".html"
+	root := rootOf(rel)
+	body, err := s.notePage(name, root)
+	if err != nil {
+		return err
+	}
+	return s.write(rel, name, root, body)
+}
+
+// notePage renders a note as HTML, under links to its tags' pages
+func (s *site) notePage(name, root string) (string, error) {
+	meta := s.notes[name]
+	content, err := os.ReadFile(meta.FilePath)
+	if err != nil {
+		return "", err
+	}
+
+	// Front matter and the .tag line become the tag links above the note
+	_, body := data.ParseFrontMatter(string(content))
+	if first, rest, _ := strings.Cut(body, "\n"); data.ParseTags(strings.TrimRight(first, "\r")) != nil {
+		body = rest
+	}
+	s.collectFiles(name, body)
+	// Embedded files are shown, or linked if they aren't images; embedded
+	// notes become links
+	body = data.ReplaceEmbeds(body, func(target string) string {
+		if ext := path.Ext(target); ext == "" || strings.EqualFold(ext, ".md") {
+			return "[[" + strings.TrimSuffix(target, ext) + "]]"
+		}
+		file, ok := s.embeddedFile(name, target)
+		if !ok {
+			return path.Base(target)
+		}
+		s.files[file] = true
+		link := "[" + path.Base(target) + "](" + root + "notes/" + escapeURLPath(file) + ")"
+		if isImage(file) {
+			return "!" + link
+		}
+		return link
+	})
+	// Links to published notes point at their pages; others become plain text
+	body = data.ReplaceLinks(body, name, func(target, label, anchor string) string {
+		if label == "" {
+			label = target
+		}
+		linked, _, ok := data.LookupNote(s.notes, target)
+		if !ok {
+			return label
+		}
+		return "[" + label + "](" + noteURL(root, linked) + headingID(anchor) + ")"
+	})
+	rendered, err := markdownToHTML([]byte(body))
+	if err != nil {
+		return "", err
+	}
+
+	header := ""
+	if len(meta.Tags) > 0 {
+		header = `<p class="tags">` + tagLinks(root, meta.Tags) + "</p>\n"
+	}
+	return header + rendered, nil
+}
+
+// fileLinkRegex matches the targets of Markdown links and images, written
+// plain or in <angle brackets>
+var fileLinkRegex = regexp.MustCompile(`!?\[[^\[\]]*\]\(\s*(<[^<>\n]+>|[^()\s]+)(?:\s+"[^"]*")?\s*\)`)
+
+// collectFiles records the notes folder files that body, the text of the
+// note name, links to or shows, so an export can copy them
+func (s *site) collectFiles(name, body string) {
+	for _, m := range fileLinkRegex.FindAllStringSubmatch(body, -1) {
+		target := strings.TrimSuffix(strings.TrimPrefix(m[1], "<"), ">")
+		if strings.Contains(target, ":") || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/") {
+			continue // another site, mailto:, a heading, or not below the note
+		}
+		target, _, _ = strings.Cut(target, "#")
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+		if strings.EqualFold(path.Ext(target), ".md") {
+			continue
+		}
+		if file, ok := s.noteFile(path.Join(data.NotebookOf(name), target)); ok {
+			s.files[file] = true
+		}
+	}
+}
+
+// embeddedFile finds the file a ![[target]] embed in the note name shows:
+// target from the note's notebook, from the notes folder, or, as Obsidian
+// does, the one file anywhere with that name
+func (s *site) embeddedFile(name, target string) (string, bool) {
+	if file, ok := s.noteFile(path.Join(data.NotebookOf(name), target)); ok {
+		return file, true
+	}
+	if file, ok := s.noteFile(target); ok {
+		return file, true
+	}
+	if s.fileNames == nil {
+		s.fileNames = make(map[string]string)
+		filepath.WalkDir(s.noteDir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") && p != s.noteDir {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || strings.EqualFold(filepath.Ext(p), ".md") {
+				return nil
+			}
+			rel, _ := filepath.Rel(s.noteDir, p)
+			base := strings.ToLower(d.Name())
+			if _, dup := s.fileNames[base]; dup {
+				s.fileNames[base] = ""
+			} else {
+				s.fileNames[base] = filepath.ToSlash(rel)
+			}
+			return nil
+		})
+	}
+	file := s.fileNames[strings.ToLower(path.Base(target))]
+	return file, file != ""
+}
+
+// noteFile cleans rel, a slash-separated path from the notes folder, and
+// reports whether it names a file there that may be published: not a
+// hidden one or outside the folder
+func (s *site) noteFile(rel string) (string, bool) {
+	rel = strings.TrimPrefix(path.Clean("/"+rel), "/")
+	for _, part := range strings.Split(rel, "/") {
+		if part == "" || strings.HasPrefix(part, ".") {
+			return "", false
+		}
+	}
+	info, err := os.Stat(filepath.Join(s.noteDir, filepath.FromSlash(rel)))
+	return rel, err == nil && !info.IsDir()
+}
+
+// copyFiles copies the files the exported notes use into notes/
+func (s *site) copyFiles() error {
+	for file := range s.files {
+		if err := copyFile(filepath.Join(s.noteDir, filepath.FromSlash(file)), filepath.Join(s.dir, "notes", filepath.FromSlash(file))); err != nil {
+			return fmt.Errorf("copying %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// isImage reports whether a browser can show file in an <img>
+func isImage(file string) bool {
+	switch strings.ToLower(path.Ext(file)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".bmp", ".avif":
+		return true
+	}
+	return false
+}
+
+// noteList renders names as a list linking to each note, with its date and tags
+func (s *site) noteList(root string, names []string) string {
+	var b strings.Builder
+	b.WriteString("<ul>\n")
+	for _, name := range names {
+		meta := s.notes[name]
+		modified := meta.Modified
+		if t, err := time.ParseInLocation("060102.150405", meta.Modified, time.Local); err == nil {
+			modified = t.Format("2006-01-02")
+		}
+		fmt.Fprintf(&b, `<li><a href="%s">%s</a> <small>%s %s</small></li>`+"\n",
+			noteURL(root, name), html.EscapeString(name), modified, tagLinks(root, meta.Tags))
+	}
+	b.WriteString("</ul>\n")
+	return b.String()
+}
+
+func (s *site) writeIndex() error {
+	body := fmt.Sprintf("<h1>Notes</h1>\n<p>%d notes, most recently modified first.</p>\n", len(s.names))
+	return s.write("index.html", "Notes", "", body+s.noteList("", s.names))
+}
+
+// writeTags writes a page for every tag the notes use, and every level
+// above one, listing the notes with it or a tag below it
+func (s *site) writeTags() error {
+	notesByTag := s.notesByTag()
+	if err := s.write("tags/index.html", "Tags", "../", s.tagsPage("../", notesByTag)); err != nil {
+		return err
+	}
+	for tag, names := range notesByTag {
+		rel := "tags/" + tag + ".html"
+		root := rootOf(rel)
+		if err := s.write(rel, "."+tag, root, s.tagPage(root, tag, names)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notesByTag groups the notes by every tag they have and every level above
+// one, newest first
+func (s *site) notesByTag() map[string][]string {
+	notesByTag := make(map[string][]string)
+	for _, name := range s.names {
+		seen := make(map[string]bool)
+		for _, tag := range s.notes[name].Tags {
+			for _, level := range append([]string{tag}, data.TagAncestors(tag)...) {
+				if !seen[level] {
+					seen[level] = true
+					notesByTag[level] = append(notesByTag[level], name)
+				}
+			}
+		}
+	}
+	return notesByTag
+}
+
+// tagsPage renders the tag tree, each tag linking to its page
+func (s *site) tagsPage(root string, notesByTag map[string][]string) string {
+	tags := make([]string, 0, len(notesByTag))
+	for tag := range notesByTag {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	var b strings.Builder
+	b.WriteString("<h1>Tags</h1>\n<ul>\n")
+	for _, tag := range tags {
+		depth := strings.Count(tag, "/")
+		fmt.Fprintf(&b, `<li style="margin-left: %.1fem"><a href="%s">%s</a> (%d)</li>`+"\n",
+			float64(depth)*1.5, tagURL(root, tag), html.EscapeString(tag[strings.LastIndex(tag, "/")+1:]), len(notesByTag[tag]))
+	}
+	b.WriteString("</ul>\n")
+	return b.String()
+}
+
+// tagPage lists the notes with a tag
+func (s *site) tagPage(root, tag string, names []string) string {
+	return fmt.Sprintf("<h1>.%s</h1>\n", html.EscapeString(tag)) + s.noteList(root, names)
+}
+
+// searchIndex is the FTS postings of the exported notes, for search.html
+type searchIndex struct {
+	Docs  []searchDoc         `json:"docs"`
+	Terms map[string][][2]int `json:"terms"` // stemmed term -> [doc, term frequency]
+}
+
+type searchDoc struct {
+	Title string `json:"t"`
+	URL   string `json:"u"`
+}
+
+// writeSearch writes search.html, which searches the exported notes' FTS
+// terms in the browser. The index is a script rather than JSON so the page
+// also works opened from disk.
+func (s *site) writeSearch() error {
+	fts, err := data.LoadFTS()
+	if err != nil {
+		return fmt.Errorf("error loading search index: %w", err)
+	}
+	idx := searchIndex{Terms: make(map[string][][2]int)}
+	docIDs := make(map[string]int)
+	for _, name := range s.names {
+		docIDs[name] = len(idx.Docs)
+		idx.Docs = append(idx.Docs, searchDoc{Title: name, URL: noteURL("", name)})
+	}
+	for term, postings := range fts.Postings {
+		for title, posting := range postings {
+			if id, ok := docIDs[title]; ok {
+				idx.Terms[term] = append(idx.Terms[term], [2]int{id, posting.TF})
+			}
+		}
+	}
+	for _, postings := range idx.Terms {
+		sort.Slice(postings, func(i, j int) bool { return postings[i][0] < postings[j][0] })
+	}
+	raw, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	script := append([]byte("window.goteSearch = "), raw...)
+	if err := os.WriteFile(filepath.Join(s.dir, "search-index.js"), append(script, ";\n"...), 0644); err != nil {
+		return err
+	}
+	return s.write("search.html", "Search", "", searchPage)
+}
+
+// searchPage matches query words against the stemmed terms: a word matches a
+// term that starts with it, that it starts with, or that shares all but its
+// last letter (so "study" finds "studi"). Every word must match.
+const searchPage = `<h1>Search</h1>
+<input id="q" type="search" placeholder="Search notes" autofocus style="width: 100%; padding: 0.5em; font-size: 1rem">
+<ul id="results"></ul>
+<script src="search-index.js"></script>
+<script>
+(function () {
+  var data = window.goteSearch, input = document.getElementById('q'), out = document.getElementById('results');
+  var n = data.docs.length;
+  function matches(term, word) {
+    if (term.indexOf(word) === 0 || (term.length >= 3 && word.indexOf(term) === 0)) return true;
+    var i = 0;
+    while (i < term.length && i < word.length && term[i] === word[i]) i++;
+    return i >= 4 && i >= word.length - 1;
+  }
+  function esc(s) {
+    return s.replace(/[&<>"]/g, function (c) { return {'&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;'}[c]; });
+  }
+  function search(q) {
+    var words = q.toLowerCase().match(/[\p{L}\p{N}]+/gu) || [];
+    var total = null;
+    words.forEach(function (w) {
+      var scores = {};
+      Object.keys(data.terms).forEach(function (term) {
+        if (!matches(term, w)) return;
+        var postings = data.terms[term], idf = Math.log(1 + n / postings.length);
+        postings.forEach(function (p) { scores[p[0]] = (scores[p[0]] || 0) + p[1] * idf; });
+      });
+      data.docs.forEach(function (d, i) {
+        if (d.t.toLowerCase().indexOf(w) >= 0) scores[i] = (scores[i] || 0) + 5;
+      });
+      if (total === null) { total = scores; return; }
+      Object.keys(total).forEach(function (i) {
+        if (scores[i] === undefined) delete total[i]; else total[i] += scores[i];
+      });
+    });
+    return Object.keys(total || {}).sort(function (a, b) { return total[b] - total[a]; });
+  }
+  function render() {
+    out.innerHTML = search(input.value).map(function (i) {
+      var d = data.docs[i];
+      return '<li><a href="' + d.u + '">' + esc(d.t) + '</a></li>';
+    }).join('');
+  }
+  var q = new URLSearchParams(location.search).get('q');
+  if (q) input.value = q;
+  input.addEventListener('input', render);
+  render();
+})();
+</script>
+`
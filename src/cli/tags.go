@@ -0,0 +1,340 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+
+	"gote/src/core"
+	"gote/src/data"
+)
+
+// tagNotesMenu lists the notes with all of tags (or tags below them, unless
+// exact) and runs the chosen action
+func tagNotesMenu(tags []string, exact bool, notebook, preSelected string, pageSize int, cfg data.Config, ui *UI) {
+	results, err := core.FilterNotesByTags(tags, -1, exact)
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	results = core.FilterByNotebook(results, notebook)
+	if machineOutput() {
+		writeNotes(searchRecords(results))
+		return
+	}
+	if len(results) == 0 {
+		ui.Empty("No notes found with all specified tags.")
+		return
+	}
+
+	titles, paths := searchResultsToMenu(results)
+	result := displayMenu(MenuConfig{
+		Title:             "Tagged Notes",
+		Items:             titles,
+		ItemPaths:         paths,
+		PreSelectedAction: preSelected,
+		ShowPin:           true,
+		PageSize:          pageSize,
+	}, ui, cfg.Interface)
+
+	executeMenuAction(result, paths, ui)
+}
+
+// tagTreeRow is a visible line of the tag tree
+type tagTreeRow struct {
+	node  *core.TagNode
+	depth int
+}
+
+// visibleTagRows flattens the tree, skipping the children of collapsed tags
+func visibleTagRows(nodes []*core.TagNode, expanded map[string]bool, depth int) []tagTreeRow {
+	var rows []tagTreeRow
+	for _, n := range nodes {
+		rows = append(rows, tagTreeRow{n, depth})
+		if expanded[n.Tag] {
+			rows = append(rows, visibleTagRows(n.Children, expanded, depth+1)...)
+		}
+	}
+	return rows
+}
+
+// expandAllTags marks every tag with children as expanded
+func expandAllTags(nodes []*core.TagNode, expanded map[string]bool) {
+	for _, n := range nodes {
+		if len(n.Children) > 0 {
+			expanded[n.Tag] = true
+			expandAllTags(n.Children, expanded)
+		}
+	}
+}
+
+// formatTagRow renders a row as e.g. "  ▸ clients (7)". The count includes
+// the notes under the tag; notes tagged with it directly are shown when fewer.
+func formatTagRow(row tagTreeRow, expanded bool, mode string) string {
+	collapsed, open, leaf := "▸", "▾", "·"
+	if mode == "minimal" {
+		collapsed, open, leaf = "+", "-", " "
+	}
+	marker := leaf
+	if len(row.node.Children) > 0 {
+		marker = collapsed
+		if expanded {
+			marker = open
+		}
+	}
+	indent := strings.Repeat("  ", row.depth)
+	if row.node.Count > 0 && row.node.Count < row.node.Total {
+		return fmt.Sprintf("%s%s %s (%d, %d direct)", indent, marker, row.node.Name, row.node.Total, row.node.Count)
+	}
+	return fmt.Sprintf("%s%s %s (%d)", indent, marker, row.node.Name, row.node.Total)
+}
+
+// tagTree shows the tag hierarchy. Choosing a tag expands or collapses it,
+// or lists its notes if nothing is below it; t<key> lists a tag's notes
+// including those below it. Without a terminal the whole tree is printed.
+func tagTree(expandAll bool, cfg data.Config, ui *UI) {
+	// json and tsv output lists every level of the tree, sorted by tag
+	if machineOutput() {
+		tags, err := data.LoadTags()
+		if err != nil {
+			ui.Error("error loading tags: " + err.Error())
+			return
+		}
+		list := make([]data.TagMeta, 0, len(tags))
+		for _, tag := range tags {
+			list = append(list, tag)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Tag < list[j].Tag })
+		writeTags(list)
+		return
+	}
+
+	roots, err := core.TagTree()
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	if len(roots) == 0 {
+		ui.Empty("No tags found.")
+		return
+	}
+
+	expanded := make(map[string]bool)
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	if expandAll || !interactive {
+		expandAllTags(roots, expanded)
+	}
+	if !interactive {
+		var lines []string
+		for _, row := range visibleTagRows(roots, expanded, 0) {
+			lines = append(lines, formatTagRow(row, expanded[row.node.Tag], cfg.Interface))
+		}
+		ui.Box("Tags", lines, 0)
+		return
+	}
+
+	page := 0
+	for {
+		rows := visibleTagRows(roots, expanded, 0)
+		pageSize := min(max(cfg.PageSize(), 1), maxSelectablePageSize)
+		totalPages := (len(rows) + pageSize - 1) / pageSize
+		page = min(page, totalPages-1)
+		start := page * pageSize
+		pageRows := rows[start:min(start+pageSize, len(rows))]
+
+		var items []string
+		var keys []rune
+		for i, row := range pageRows {
+			items = append(items, formatTagRow(row, expanded[row.node.Tag], cfg.Interface))
+			keys = append(keys, selectKeys[i])
+		}
+		if cfg.IsTUI() {
+			ui.Clear()
+			ui.SelectableList("Tags", items, -1, keys)
+			fmt.Printf("\n %s(%d/%d)%s", Dim, page+1, totalPages, Reset)
+			if totalPages > 1 {
+				fmt.Printf(" %s[n]ext [p]rev%s", Dim, Reset)
+			}
+			fmt.Printf(" %s[q]uit%s\n", Dim, Reset)
+			fmt.Printf(" %s<key> expand/collapse  t<key> notes  [+] expand all  [-] collapse all%s\n", Dim, Reset)
+		} else {
+			fmt.Println()
+			for i, item := range items {
+				fmt.Printf("[%c] %s\n", keys[i], item)
+			}
+			fmt.Printf("(%d/%d) ", page+1, totalPages)
+			if totalPages > 1 {
+				fmt.Print("[n]ext [p]rev ")
+			}
+			fmt.Println("[q]uit")
+			fmt.Println("<key> expand/collapse  t<key> notes  [+] expand all  [-] collapse all")
+		}
+		fmt.Print(": ")
+
+		input, ok := ui.ReadMenuInput()
+		if !ok {
+			return
+		}
+		switch input {
+		case "q":
+			if cfg.IsTUI() {
+				ui.Clear()
+			}
+			return
+		case "n":
+			if page < totalPages-1 {
+				page++
+			}
+			continue
+		case "p":
+			if page > 0 {
+				page--
+			}
+			continue
+		case "+":
+			expandAllTags(roots, expanded)
+			continue
+		case "-":
+			expanded = make(map[string]bool)
+			continue
+		}
+
+		listNotes := len(input) == 2 && input[0] == 't'
+		key := input
+		if listNotes {
+			key = input[1:]
+		}
+		if len(key) != 1 {
+			continue
+		}
+		for i, row := range pageRows {
+			if rune(key[0]) != keys[i] {
+				continue
+			}
+			if !listNotes && len(row.node.Children) > 0 {
+				expanded[row.node.Tag] = !expanded[row.node.Tag]
+				break
+			}
+			if cfg.IsTUI() {
+				ui.Clear()
+			}
+			tagNotesMenu([]string{row.node.Tag}, false, "", "", cfg.PageSize(), cfg, ui)
+			return
+		}
+	}
+}
+
+// isTagEdit reports whether args rename, merge or delete tags themselves.
+// These always change tags, dry run or not; gote td lists notes in delete mode.
+func isTagEdit(args Args) bool {
+	switch args.First() {
+	case "rename", "merge", "delete":
+		return true
+	}
+	return false
+}
+
+// tagEditNames returns the tags given after the subcommand, including any
+// taken as values by --dry-run or --exact, without a leading period
+func tagEditNames(args Args) []string {
+	names := append([]string{}, args.Rest()...)
+	names = append(names, args.List("dry-run")...)
+	return trimTagPeriods(append(names, args.List("exact")...))
+}
+
+// trimTagPeriods drops the period a tag may be written with, so .work and
+// work name the same tag
+func trimTagPeriods(names []string) []string {
+	for i, name := range names {
+		names[i] = strings.TrimPrefix(name, ".")
+	}
+	return names
+}
+
+// tagEdit runs "gote tag rename|merge|delete", listing the notes changed
+func tagEdit(args Args, cfg data.Config, ui *UI) {
+	names := tagEditNames(args)
+	exact, dryRun := args.Has("exact"), args.Has("dry-run")
+
+	var changes []core.TagChange
+	var err error
+	var done string
+	switch args.First() {
+	case "rename":
+		if len(names) != 2 {
+			ui.Error("Usage: gote tag rename <old> <new> [--exact] [--dry-run]")
+			return
+		}
+		changes, err = core.RenameTag(names[0], names[1], exact, dryRun)
+		done = fmt.Sprintf("Renamed %s to %s", names[0], names[1])
+	case "merge":
+		into := trimTagPeriods(args.List("into"))
+		names = append(names, into[min(1, len(into)):]...)
+		if len(into) == 0 || len(names) == 0 {
+			ui.Error("Usage: gote tag merge <tag>... --into <tag> [--exact] [--dry-run]")
+			return
+		}
+		changes, err = core.MergeTags(names, into[0], exact, dryRun)
+		done = fmt.Sprintf("Merged %s into %s", strings.Join(names, ", "), into[0])
+	case "delete":
+		if len(names) != 1 {
+			ui.Error("Usage: gote tag delete <tag> [--exact] [--dry-run]")
+			return
+		}
+		changes, err = core.DeleteTag(names[0], exact, dryRun)
+		done = "Deleted " + names[0]
+	}
+
+	var lines, skipped []string
+	for _, c := range changes {
+		if c.Skipped {
+			skipped = append(skipped, c.Note)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s -> %s", c.Note, formatTagLine(c.Before), formatTagLine(c.After)))
+	}
+	if len(lines) > 0 {
+		title := fmt.Sprintf("%d notes", len(lines))
+		if dryRun {
+			title = fmt.Sprintf("Would change %d notes", len(lines))
+		}
+		if cfg.IsTUI() {
+			ui.Box(title, lines, 0)
+		} else {
+			fmt.Println(title + ":")
+			for _, line := range lines {
+				fmt.Println(line)
+			}
+		}
+	}
+	if len(skipped) > 0 {
+		verb := "Left"
+		if dryRun {
+			verb = "Would leave"
+		}
+		ui.Info(fmt.Sprintf("%s %d notes alone, as the tag isn't in their text where gote can rewrite it (try gote index): %s",
+			verb, len(skipped), strings.Join(skipped, ", ")))
+	}
+
+	switch {
+	case err != nil:
+		ui.Error(err.Error())
+	case len(changes) == 0:
+		ui.Empty("No notes use that tag.")
+	case dryRun:
+		ui.Info("Dry run: no notes were changed.")
+	case len(lines) > 0:
+		ui.Success(fmt.Sprintf("%s in %d notes.", done, len(lines)))
+	}
+}
+
+// formatTagLine shows tags the way a .tag line writes them
+func formatTagLine(tags []string) string {
+	if len(tags) == 0 {
+		return "(none)"
+	}
+	return "." + strings.Join(tags, ".")
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"gote/src/core"
 	"gote/src/data"
@@ -43,28 +44,39 @@ func TemplateCommand(rawArgs []string) {
 }
 
 func templateMenu(ui *UI, cfg data.Config) {
-	templates, err := core.ListTemplates()
+	infos, err := core.ListTemplateInfo()
 	if err != nil {
 		ui.Error(err.Error())
 		return
 	}
-	if len(templates) == 0 {
+	if machineOutput() {
+		writeTemplates(infos)
+		return
+	}
+	if len(infos) == 0 {
 		ui.Empty("No templates. Create one with: gote template <name>")
 		return
 	}
 
 	// Build paths map
+	var templates []string
 	paths := make(map[string]string)
-	for _, t := range templates {
-		paths[t] = filepath.Join(data.TemplatesDir(), t+".md")
+	details := make(map[string][]string)
+	for _, info := range infos {
+		templates = append(templates, info.Name)
+		paths[info.Name] = filepath.Join(data.TemplatesDir(), info.Name+".md")
+		if line := templateSettingsLine(info); line != "" {
+			details[info.Name] = []string{line}
+		}
 	}
 
 	result := displayMenu(MenuConfig{
-		Title:     "Templates",
-		Items:     templates,
-		ItemPaths: paths,
-		HideView:  true,
-		PageSize:  cfg.PageSize(),
+		Title:       "Templates",
+		Items:       templates,
+		ItemPaths:   paths,
+		ItemDetails: details,
+		HideView:    true,
+		PageSize:    cfg.PageSize(),
 	}, ui, cfg.Interface)
 
 	executeTemplateAction(result, ui)
@@ -104,16 +116,47 @@ func executeTemplateAction(result MenuResult, ui *UI) {
 		}
 		ui.Success("Renamed to: " + newName)
 	case "info":
-		// Show template path
+		// Show template path and settings
 		path := filepath.Join(data.TemplatesDir(), result.Note+".md")
-		ui.InfoBox(result.Note, [][2]string{
-			{"Path", path},
-		})
+		pairs := [][2]string{{"Path", path}}
+		content, err := data.LoadTemplate(result.Note)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		settings, _, err := data.ParseTemplateSettings(content)
+		if err != nil {
+			pairs = append(pairs, [2]string{"Error", err.Error()})
+		}
+		if settings.Name != "" {
+			pairs = append(pairs, [2]string{"Name", settings.Name})
+		}
+		if len(settings.Tags) > 0 {
+			pairs = append(pairs, [2]string{"Tags", "." + strings.Join(settings.Tags, ".")})
+		}
+		if settings.Timestamp != "" {
+			pairs = append(pairs, [2]string{"Timestamp", settings.Timestamp})
+		}
+		if settings.Pin {
+			pairs = append(pairs, [2]string{"Pin", "yes"})
+		}
+		ui.InfoBox(result.Note, pairs)
 	}
 }
 
 // selectTemplate shows an interactive picker and returns the selected template name
 func selectTemplate(cfg data.Config, ui *UI, pageSize int) string {
+	if machineOutput() {
+		// Nothing to pick with: list the templates and fail
+		infos, err := core.ListTemplateInfo()
+		if err != nil {
+			ui.Error(err.Error())
+			return ""
+		}
+		writeTemplates(infos)
+		ui.Error("no template named; give its name after -t")
+		return ""
+	}
 	templates, err := core.ListTemplates()
 	if err != nil {
 		ui.Error(err.Error())
@@ -144,3 +187,46 @@ func selectTemplate(cfg data.Config, ui *UI, pageSize int) string {
 
 	return result.Note
 }
+
+// templateSettingsLine summarizes a template's front matter settings for a
+// list, e.g. "name: {{date "060102"}} standup · tags: .meeting · pin"
+func templateSettingsLine(info core.TemplateInfo) string {
+	if info.Err != nil {
+		return "error: " + info.Err.Error()
+	}
+	var parts []string
+	s := info.Settings
+	if s.Name != "" {
+		parts = append(parts, "name: "+s.Name)
+	}
+	if len(s.Tags) > 0 {
+		parts = append(parts, "tags: ."+strings.Join(s.Tags, "."))
+	}
+	if s.Timestamp != "" {
+		parts = append(parts, "timestamp: "+s.Timestamp)
+	}
+	if s.Pin {
+		parts = append(parts, "pin")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// templatePrompt asks for a template's {{prompt}} values on the terminal.
+// An empty answer takes the default; EOF cancels.
+func templatePrompt(ui *UI) core.PromptFunc {
+	return func(label, def string) (string, error) {
+		prompt := label + ": "
+		if def != "" {
+			prompt = fmt.Sprintf("%s [%s]: ", label, def)
+		}
+		answer, ok := ui.ReadLine(prompt)
+		if !ok {
+			fmt.Println()
+			return "", core.ErrTemplateCancelled
+		}
+		if answer == "" {
+			return def, nil
+		}
+		return answer, nil
+	}
+}
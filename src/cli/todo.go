@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+
+	"gote/src/core"
+)
+
+func TodoCommand(rawArgs []string) {
+	args := ParseArgs(rawArgs)
+
+	cfg, ui, ok := LoadConfigAndUI()
+	if !ok {
+		return
+	}
+
+	filter := core.TaskFilter{
+		Status:   "open",
+		Tags:     args.TagList("t", "tag"),
+		Exact:    args.Has("exact"),
+		Notebook: args.String("notebook", "nb"),
+	}
+	switch {
+	case args.Has("all", "a"):
+		filter.Status = "all"
+	case args.Has("done"):
+		filter.Status = "done"
+	}
+	if name := args.Joined(); name != "" {
+		resolved, err := ResolveNoteName(name)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		filter.Note = resolved
+	}
+
+	for {
+		tasks, err := core.ListTasks(filter)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		if machineOutput() {
+			writeTasks(tasks)
+			return
+		}
+		if len(tasks) == 0 {
+			ui.Empty("No tasks found.")
+			return
+		}
+
+		labels := make([]string, 0, len(tasks))
+		byLabel := make(map[string]core.TaskItem, len(tasks))
+		details := make(map[string][]string, len(tasks))
+		for _, task := range tasks {
+			label := taskLabel(task)
+			if _, dup := byLabel[label]; dup {
+				label = fmt.Sprintf("%s (%s:%d)", label, task.Note, task.Line)
+			}
+			labels = append(labels, label)
+			byLabel[label] = task
+			details[label] = []string{taskDetail(task)}
+		}
+
+		// Without a terminal there is nothing to toggle; just list them
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			for _, label := range labels {
+				fmt.Printf("%s  %s\n", label, details[label][0])
+			}
+			return
+		}
+
+		done := 0
+		for _, task := range tasks {
+			if task.Done {
+				done++
+			}
+		}
+		result := displayMenu(MenuConfig{
+			Title:             fmt.Sprintf("Tasks (%d open, %d done)", len(tasks)-done, done),
+			Items:             labels,
+			ItemDetails:       details,
+			PreSelectedAction: "toggle",
+			HideView:          true,
+			PageSize:          cfg.PageSize(),
+		}, ui, cfg.Interface)
+		if result.Note == "" {
+			return
+		}
+
+		task := byLabel[result.Note]
+		toggled, err := core.ToggleTask(task.Note, task.Line, task.Text)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		if toggled.Done {
+			ui.Success("Done: " + toggled.Text)
+		} else {
+			ui.Success("Reopened: " + toggled.Text)
+		}
+	}
+}
+
+// taskLabel shows a task with its checkbox, e.g. "[x] call Ann"
+func taskLabel(task core.TaskItem) string {
+	if task.Done {
+		return "[x] " + task.Text
+	}
+	return "[ ] " + task.Text
+}
+
+// taskDetail shows where a task is and when it's due
+func taskDetail(task core.TaskItem) string {
+	detail := fmt.Sprintf("%s:%d", task.Note, task.Line)
+	if task.Due != "" {
+		detail += "  due " + task.Due
+		if !task.Done && task.Due < time.Now().Format("2006-01-02") {
+			detail += " (overdue)"
+		}
+	}
+	return detail
+}
@@ -35,32 +35,107 @@ func DeleteCommand(rawArgs []string) {
 		}
 	}
 
-	if err := core.DeleteNote(resolved); err != nil {
+	showDeleteResult(core.DeleteNote(resolved), "Note moved to trash: "+resolved, ui)
+}
+
+// showDeleteResult reports a delete. A failed purge of old trash doesn't
+// undo the delete, so it's shown after the success message.
+func showDeleteResult(err error, done string, ui *UI) {
+	if err != nil && !core.IsPurgeError(err) {
 		ui.Error(err.Error())
 		return
 	}
-	ui.Success("Note moved to trash: " + resolved)
+	ui.Success(done)
+	if err != nil {
+		ui.Info("Warning: " + err.Error())
+	}
 }
 
 func RecoverCommand(rawArgs []string) {
 	args := ParseArgs(rawArgs)
 	noteName := args.Joined()
 
-	_, ui, ok := LoadConfigAndUI()
+	cfg, ui, ok := LoadConfigAndUI()
 	if !ok {
 		return
 	}
 
+	var entries []data.TrashEntry
+	var err error
 	if noteName == "" {
-		ui.Info("Usage: gote recover <note name>")
+		entries, err = core.ListTrash()
+	} else {
+		entries, err = core.FindTrashed(noteName)
+	}
+	if err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		if noteName == "" {
+			ui.Empty("Trash is empty.")
+		} else {
+			ui.Error("note not found in trash: " + noteName)
+		}
 		return
 	}
 
-	if err := core.RecoverNote(noteName); err != nil {
+	// Only a name that matches one note recovers it straight away; with no
+	// name, even a single trashed note is offered rather than restored
+	entry := entries[0]
+	if noteName == "" || len(entries) > 1 {
+		if machineOutput() {
+			// No menu to choose from: list the candidates and fail
+			writeTrash(entries)
+			if noteName == "" {
+				ui.Error("Usage: gote recover <note>")
+			} else {
+				ui.Error(fmt.Sprintf("%d trashed notes match %q; recover one without --json or --format", len(entries), noteName))
+			}
+			return
+		}
+		var ok bool
+		if entry, ok = selectTrashEntry("Recover", entries, cfg, ui); !ok {
+			return
+		}
+	}
+	recoverTrashEntry(entry, ui)
+}
+
+// selectTrashEntry shows a menu of trashed notes, labelled with when they
+// were deleted so same-named notes can be told apart
+func selectTrashEntry(title string, entries []data.TrashEntry, cfg data.Config, ui *UI) (data.TrashEntry, bool) {
+	labels := make([]string, 0, len(entries))
+	byLabel := make(map[string]data.TrashEntry, len(entries))
+	for _, e := range entries {
+		label := fmt.Sprintf("%s  (deleted %s)", e.Name, e.DeletedAt.Format("2006-01-02 15:04"))
+		if _, dup := byLabel[label]; dup {
+			label += " " + e.ID
+		}
+		labels = append(labels, label)
+		byLabel[label] = e
+	}
+
+	result := displayMenu(MenuConfig{
+		Title:             title,
+		Items:             labels,
+		PreSelectedAction: "recover",
+		HideView:          true,
+		PageSize:          cfg.PageSize(),
+	}, ui, cfg.Interface)
+	if result.Note == "" {
+		return data.TrashEntry{}, false
+	}
+	return byLabel[result.Note], true
+}
+
+func recoverTrashEntry(entry data.TrashEntry, ui *UI) {
+	name, err := core.RecoverTrashEntry(entry.ID)
+	if err != nil {
 		ui.Error(err.Error())
 		return
 	}
-	ui.Success("Note recovered: " + noteName)
+	ui.Success("Note recovered: " + name)
 }
 
 func TrashCommand(rawArgs []string) {
@@ -74,23 +149,22 @@ func TrashCommand(rawArgs []string) {
 
 	switch sub {
 	case "":
-		// List trashed notes
-		notes, err := data.ListTrashedNotes()
+		// Pick a trashed note to recover
+		entries, err := core.ListTrash()
 		if err != nil {
 			ui.Error(err.Error())
 			return
 		}
-		if len(notes) == 0 {
+		if machineOutput() {
+			writeTrash(entries)
+			return
+		}
+		if len(entries) == 0 {
 			ui.Empty("Trash is empty.")
 			return
 		}
-		if cfg.IsTUI() {
-			ui.Box("Trash", notes, 0)
-		} else {
-			fmt.Println("Trashed notes:")
-			for _, note := range notes {
-				fmt.Println(note)
-			}
+		if entry, ok := selectTrashEntry("Trash", entries, cfg, ui); ok {
+			recoverTrashEntry(entry, ui)
 		}
 	case "empty":
 		count, err := data.EmptyTrash()
@@ -128,10 +202,6 @@ func TrashCommand(rawArgs []string) {
 	default:
 		// Treat as note name to delete
 		noteName := args.Joined()
-		if err := core.DeleteNote(noteName); err != nil {
-			ui.Error(err.Error())
-			return
-		}
-		ui.Success("Note moved to trash: " + noteName)
+		showDeleteResult(core.DeleteNote(noteName), "Note moved to trash: "+noteName, ui)
 	}
 }
@@ -12,13 +12,16 @@ import (
 
 // ANSI escape codes
 const (
-	Reset    = "\033[0m"
-	Bold     = "\033[1m"
-	Dim      = "\033[2m"
-	Cyan     = "\033[36m"
-	White    = "\033[37m"
-	BoldCyan = "\033[1;36m"
-	Reverse  = "\033[7m"
+	Reset     = "\033[0m"
+	Bold      = "\033[1m"
+	Dim       = "\033[2m"
+	Italic    = "\033[3m"
+	Underline = "\033[4m"
+	Strike    = "\033[9m"
+	Cyan      = "\033[36m"
+	White     = "\033[37m"
+	BoldCyan  = "\033[1;36m"
+	Reverse   = "\033[7m"
 )
 
 // Screen control
@@ -164,6 +167,19 @@ func (u *UI) Box(title string, lines []string, width int) {
 
 // SelectableList renders an interactive selectable list with box
 func (u *UI) SelectableList(title string, items []string, selected int, keys []rune) {
+	u.SelectableListWithDetails(title, items, nil, selected, keys)
+}
+
+// SelectableListWithDetails is SelectableList with extra indented lines under
+// each item (details[i] belongs to items[i]), e.g. search snippets
+func (u *UI) SelectableListWithDetails(title string, items []string, details [][]string, selected int, keys []rune) {
+	detailsFor := func(i int) []string {
+		if i < len(details) {
+			return details[i]
+		}
+		return nil
+	}
+
 	if !u.IsTUI() {
 		if title != "" {
 			fmt.Println(title + ":")
@@ -174,17 +190,25 @@ func (u *UI) SelectableList(title string, items []string, selected int, keys []r
 			} else {
 				fmt.Println(item)
 			}
+			for _, d := range detailsFor(i) {
+				fmt.Println("    " + d)
+			}
 		}
 		return
 	}
 
 	// Calculate width based on actual item lengths
 	width := visibleLen(title) + 4
-	for _, item := range items {
+	for i, item := range items {
 		itemWidth := visibleLen(item) + 8 // account for " [x] " prefix and padding
 		if itemWidth > width {
 			width = itemWidth
 		}
+		for _, d := range detailsFor(i) {
+			if w := visibleLen(d) + 9; w > width {
+				width = w
+			}
+		}
 	}
 	if width < 30 {
 		width = 30
@@ -213,6 +237,9 @@ func (u *UI) SelectableList(title string, items []string, selected int, keys []r
 			}
 		}
 		lines = append(lines, line)
+		for _, d := range detailsFor(i) {
+			lines = append(lines, "     "+d)
+		}
 	}
 
 	u.Box(title, lines, width+4)
@@ -262,9 +289,12 @@ func (u *UI) NavHintWithModes(page, total int, showOpen bool, showView bool) {
 	fmt.Printf("\n %s%s%s\n", Dim, strings.Join(hints, "  "), Reset)
 }
 
-// Success prints a success message
+// Success prints a success message. In json and tsv mode messages go to
+// stderr, leaving stdout to the records.
 func (u *UI) Success(text string) {
-	if u.IsTUI() {
+	if machineOutput() {
+		fmt.Fprintln(os.Stderr, text)
+	} else if u.IsTUI() {
 		fmt.Printf("%s->%s %s\n", Cyan, Reset, text)
 	} else {
 		fmt.Println(text)
@@ -273,7 +303,9 @@ func (u *UI) Success(text string) {
 
 // Error prints an error message
 func (u *UI) Error(text string) {
-	if u.IsTUI() {
+	if machineOutput() {
+		reportError(text)
+	} else if u.IsTUI() {
 		fmt.Printf("%s!%s %s\n", Bold, Reset, text)
 	} else {
 		fmt.Println("Error:", text)
@@ -282,7 +314,9 @@ func (u *UI) Error(text string) {
 
 // Info prints an info message
 func (u *UI) Info(text string) {
-	if u.IsTUI() {
+	if machineOutput() {
+		fmt.Fprintln(os.Stderr, text)
+	} else if u.IsTUI() {
 		fmt.Printf("%s->%s %s\n", Cyan, Reset, text)
 	} else {
 		fmt.Println(text)
@@ -291,7 +325,9 @@ func (u *UI) Info(text string) {
 
 // Empty prints an empty state message
 func (u *UI) Empty(text string) {
-	if u.IsTUI() {
+	if machineOutput() {
+		fmt.Fprintln(os.Stderr, text)
+	} else if u.IsTUI() {
 		fmt.Printf("%s%s%s\n", Dim, text, Reset)
 	} else {
 		fmt.Println(text)
@@ -375,6 +411,20 @@ func (u *UI) ReadMenuInput() (string, bool) {
 	return strings.ToLower(strings.TrimSpace(input)), true
 }
 
+// ReadLine prints prompt and reads a line of input, keeping its case.
+// Returns "", false on EOF/error.
+func (u *UI) ReadLine(prompt string) (string, bool) {
+	if u.reader == nil {
+		u.reader = bufio.NewReader(os.Stdin)
+	}
+	fmt.Print(prompt)
+	input, err := u.reader.ReadString('\n')
+	if err != nil && input == "" {
+		return "", false
+	}
+	return strings.TrimSpace(input), true
+}
+
 // ReadInputWithDefault prompts for input with an editable default value.
 // Returns empty string on Escape/Ctrl-C (cancel).
 func (u *UI) ReadInputWithDefault(prompt, defaultVal string) string {
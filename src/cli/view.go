@@ -3,15 +3,19 @@ package cli
 import (
 	"bytes"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
 
+	"gote/src/core"
 	"gote/src/data"
 )
 
@@ -43,17 +47,32 @@ func ViewCommand(rawArgs []string) {
 		return
 	}
 
-	meta, exists := index[noteName]
+	actualName, meta, exists := data.LookupNoteFuzzy(index, noteName)
 	if !exists {
 		ui.Error("Note not found: " + noteName)
 		return
 	}
 
-	ViewNoteInBrowser(meta.FilePath, noteName)
+	ViewNoteInBrowser(meta.FilePath, actualName)
 }
 
 // ViewNoteInBrowser opens a note's markdown content as HTML in the browser
 func ViewNoteInBrowser(filePath, title string) error {
+	return ViewNoteInBrowserHighlighted(filePath, title, nil)
+}
+
+// ViewNoteInBrowserHighlighted is ViewNoteInBrowser with words matching the
+// search terms wrapped in <mark>
+func ViewNoteInBrowserHighlighted(filePath, title string, terms []string) error {
+	// A running gote serve shows the note, and keeps it up to date
+	if addr := runningServer(); addr != "" {
+		link := "http://" + addr + noteURL("/", title)
+		if len(terms) > 0 {
+			link += "?hl=" + url.QueryEscape(strings.Join(terms, " "))
+		}
+		return openInBrowser(link)
+	}
+
 	// Read the note content
 	content, err := os.ReadFile(filePath)
 	if err != nil {
@@ -65,6 +84,9 @@ func ViewNoteInBrowser(filePath, title string) error {
 	if err != nil {
 		return fmt.Errorf("error converting markdown: %w", err)
 	}
+	if len(terms) > 0 {
+		htmlContent = highlightHTML(htmlContent, terms)
+	}
 
 	// Create full HTML document
 	fullHTML := wrapInHTMLTemplate(title, htmlContent)
@@ -83,23 +105,64 @@ func ViewNoteInBrowser(filePath, title string) error {
 	return nil
 }
 
-func markdownToHTML(source []byte) (string, error) {
-	md := goldmark.New(
+// newMarkdown is the Markdown dialect gote renders notes in, for both the
+// browser and the terminal
+func newMarkdown() goldmark.Markdown {
+	return goldmark.New(
 		goldmark.WithExtensions(
 			extension.GFM, // GitHub Flavored Markdown (tables, strikethrough, etc.)
 		),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(), // ids for [[note#heading]] links
+		),
 		goldmark.WithRendererOptions(
 			html.WithUnsafe(), // Allow raw HTML in markdown
 		),
 	)
+}
 
+func markdownToHTML(source []byte) (string, error) {
 	var buf bytes.Buffer
-	if err := md.Convert(source, &buf); err != nil {
+	if err := newMarkdown().Convert(source, &buf); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
 }
 
+// highlightHTML wraps matches of terms in <mark>, touching only text between
+// tags so attributes and markup are left intact
+func highlightHTML(htmlContent string, terms []string) string {
+	var b strings.Builder
+	rest := htmlContent
+	for rest != "" {
+		lt := strings.IndexByte(rest, '<')
+		if lt < 0 {
+			lt = len(rest)
+		}
+		text := rest[:lt]
+		last := 0
+		for _, m := range core.MatchRanges(text, terms) {
+			if m[0] < last || (m[0] > 0 && strings.ContainsRune("&#", rune(text[m[0]-1]))) {
+				continue // overlapping, or inside an entity like &amp;
+			}
+			b.WriteString(text[last:m[0]])
+			b.WriteString("<mark>" + text[m[0]:m[1]] + "</mark>")
+			last = m[1]
+		}
+		b.WriteString(text[last:])
+		rest = rest[lt:]
+
+		gt := strings.IndexByte(rest, '>')
+		if gt < 0 {
+			b.WriteString(rest)
+			break
+		}
+		b.WriteString(rest[:gt+1])
+		rest = rest[gt+1:]
+	}
+	return b.String()
+}
+
 func wrapInHTMLTemplate(title, content string) string {
 	return fmt.Sprintf(`<!DOCTYPE html>
 <html lang="en">
@@ -232,6 +295,13 @@ func wrapInHTMLTemplate(title, content string) string {
             border-radius: 8px;
         }
 
+        mark {
+            background: #ffd54f;
+            color: #1a1a1a;
+            border-radius: 2px;
+            padding: 0 0.1em;
+        }
+
         .title {
             color: var(--muted);
             font-size: 0.9rem;
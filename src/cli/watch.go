@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"gote/src/core"
+	"gote/src/data"
+)
+
+// WatchCommand keeps the index in sync with edits made outside gote until
+// interrupted
+func WatchCommand(rawArgs []string) {
+	args := ParseArgs(rawArgs)
+
+	_, ui, ok := LoadConfigAndUI()
+	if !ok {
+		return
+	}
+
+	opts := core.WatchOptions{Poll: args.Has("poll")}
+	if s := args.String("interval", "i"); s != "" {
+		interval, err := parseInterval(s)
+		if err != nil {
+			ui.Error(err.Error())
+			return
+		}
+		opts.PollInterval = interval
+		opts.Poll = true
+	}
+	quiet := args.Has("q", "quiet")
+
+	opts.OnStart = func(dir, backend string) {
+		if backend == "polling" {
+			interval := opts.PollInterval
+			if interval == 0 {
+				interval = data.DefaultPollInterval
+			}
+			backend = fmt.Sprintf("polling every %s", interval)
+		}
+		ui.Info(fmt.Sprintf("Watching %s (%s). Press Ctrl-C to stop.", dir, backend))
+	}
+	opts.OnEvent = func(ev core.WatchEvent) {
+		if quiet {
+			return
+		}
+		stamp := time.Now().Format("15:04:05")
+		switch ev.Kind {
+		case "renamed":
+			fmt.Printf("%s %-8s %s -> %s\n", stamp, ev.Kind, ev.OldName, ev.Name)
+		default:
+			fmt.Printf("%s %-8s %s\n", stamp, ev.Kind, ev.Name)
+		}
+	}
+	opts.OnError = func(err error) {
+		ui.Error(err.Error())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := core.Watch(ctx, opts); err != nil {
+		ui.Error(err.Error())
+		return
+	}
+	ui.Info("Stopped watching.")
+}
+
+// parseInterval accepts a Go duration ("500ms", "2s") or a number of seconds
+func parseInterval(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d, nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("invalid interval: %s (use e.g. 2s or 500ms)", s)
+}
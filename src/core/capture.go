@@ -0,0 +1,162 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gote/src/data"
+)
+
+// ErrNoteExists is returned by CreateNoteWithText for a note that exists
+var ErrNoteExists = errors.New("note already exists")
+
+// CreateNoteWithText creates a new note holding text, without opening the
+// editor. With a templateName the rendered template comes first, its prompts
+// answered with their defaults. The note is named as CreateNoteFromTemplate
+// names it; if that note exists already, ErrNoteExists is returned.
+func CreateNoteWithText(noteName, templateName, timestamp, text string) (string, error) {
+	tmpl := noteTemplate{name: "note"}
+	if templateName != "" {
+		settings, body, bodyLine, err := loadTemplate(templateName)
+		if err != nil {
+			return "", err
+		}
+		tmpl = noteTemplate{name: templateName, settings: settings, body: body, bodyLine: bodyLine}
+	}
+	return newNote(noteName, tmpl, timestamp, time.Now(), nil, &text)
+}
+
+// AddOptions says where and how AddToNote adds text
+type AddOptions struct {
+	Prepend   bool // add at the top, below any front matter and tag line
+	Timestamp bool // put a "## 2026-01-17 09:30" heading above the text
+	Create    bool // create the note if it doesn't exist
+}
+
+// AddToNote adds text to the end of a note, or with Prepend to its start,
+// without opening the editor, then reindexes it. The note's old text is kept
+// in its history. Returns the note's name.
+func AddToNote(noteName, text string, opts AddOptions) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("nothing to add")
+	}
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("error loading config: %w", err)
+	}
+
+	// Hold the index lock from lookup to reindex, so a concurrent add or
+	// edit of the note can't be lost
+	var name string
+	err = data.WithIndexLock(func(index map[string]data.NoteMeta) error {
+		var meta data.NoteMeta
+		var exists bool
+		name, meta, exists = data.LookupNote(index, noteName)
+		notePath := meta.FilePath
+		if !exists {
+			if err := data.ValidateNotePath(noteName); err != nil {
+				return err
+			}
+			// A note not indexed yet is still added to
+			name, notePath = noteName, data.NotePath(cfg.NoteDir, noteName)
+			if _, err := os.Stat(notePath); os.IsNotExist(err) {
+				if !opts.Create {
+					return fmt.Errorf("note not found: %s (use --create to create it)", noteName)
+				}
+				if err := os.MkdirAll(filepath.Dir(notePath), 0755); err != nil {
+					return fmt.Errorf("error creating notebook: %w", err)
+				}
+			}
+		}
+
+		content, err := os.ReadFile(notePath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error reading note: %w", err)
+		}
+		if len(content) > 0 {
+			if err := snapshotNote(name, notePath); err != nil {
+				return err
+			}
+		}
+
+		block := text
+		if !strings.HasSuffix(block, "\n") {
+			block += "\n"
+		}
+		if opts.Timestamp {
+			block = "## " + time.Now().Format("2006-01-02 15:04") + "\n\n" + block
+		}
+		var updated string
+		if opts.Prepend {
+			updated = prependBlock(string(content), block, opts.Timestamp)
+		} else {
+			updated = appendBlock(string(content), block, opts.Timestamp)
+		}
+		if err := os.WriteFile(notePath, []byte(updated), 0644); err != nil {
+			return fmt.Errorf("error writing note: %w", err)
+		}
+		if err := snapshotNote(name, notePath); err != nil {
+			return err
+		}
+
+		info, err := os.Stat(notePath)
+		if err != nil {
+			return fmt.Errorf("error stating note: %w", err)
+		}
+		newMeta, err := data.BuildNoteMeta(notePath, info)
+		if err != nil {
+			return fmt.Errorf("error building note metadata: %w", err)
+		}
+		if exists {
+			newMeta.Created = meta.Created
+			newMeta.LastVisited = meta.LastVisited
+		}
+		index[name] = newMeta
+
+		if err := data.IndexDocFTS(name, notePath, updated); err != nil {
+			return fmt.Errorf("warning: FTS index failed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// appendBlock adds block on a new line after content, after a blank line if
+// separate
+func appendBlock(content, block string, separate bool) string {
+	if content == "" {
+		return block
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if separate && !strings.HasSuffix(content, "\n\n") {
+		content += "\n"
+	}
+	return content + block
+}
+
+// prependBlock adds block at the top of content, but below its front matter
+// and tag line so they stay where gote looks for them. A blank line follows
+// the block if separate.
+func prependBlock(content, block string, separate bool) string {
+	_, body, _ := data.SplitFrontMatter(content)
+	if first, rest, _ := strings.Cut(body, "\n"); data.ParseTags(strings.TrimSpace(first)) != nil {
+		body = rest
+	}
+	head := content[:len(content)-len(body)]
+	if head != "" && !strings.HasSuffix(head, "\n") {
+		head += "\n"
+	}
+	if separate && strings.TrimSpace(body) != "" {
+		block += "\n"
+	}
+	return head + block + body
+}
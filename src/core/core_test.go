@@ -1,9 +1,18 @@
 package core
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"gote/src/data"
 )
@@ -93,6 +102,18 @@ func TestSearchNotesByTitle(t *testing.T) {
 		}
 	})
 
+	t.Run("fuzzy and typo matches", func(t *testing.T) {
+		for _, q := range []string{"prj alpha", "projcet-alpha"} {
+			results, err := SearchNotesByTitle(q, -1)
+			if err != nil {
+				t.Fatalf("SearchNotesByTitle failed: %v", err)
+			}
+			if len(results) == 0 || results[0].Title != "project-alpha" {
+				t.Errorf("SearchNotesByTitle(%q) = %v, want project-alpha first", q, results)
+			}
+		}
+	})
+
 	t.Run("respects limit", func(t *testing.T) {
 		results, err := SearchNotesByTitle("project", 1)
 		if err != nil {
@@ -113,7 +134,7 @@ func TestSearchNotesByTags(t *testing.T) {
 	createTestNote(t, notesDir, "note3", ".personal\nPersonal stuff")
 
 	t.Run("single tag", func(t *testing.T) {
-		results, err := SearchNotesByTags([]string{"work"}, -1)
+		results, err := SearchNotesByTags([]string{"work"}, -1, false)
 		if err != nil {
 			t.Fatalf("SearchNotesByTags failed: %v", err)
 		}
@@ -123,7 +144,7 @@ func TestSearchNotesByTags(t *testing.T) {
 	})
 
 	t.Run("multiple tags scores higher", func(t *testing.T) {
-		results, err := SearchNotesByTags([]string{"work", "urgent"}, -1)
+		results, err := SearchNotesByTags([]string{"work", "urgent"}, -1, false)
 		if err != nil {
 			t.Fatalf("SearchNotesByTags failed: %v", err)
 		}
@@ -139,7 +160,7 @@ func TestSearchNotesByTags(t *testing.T) {
 	})
 
 	t.Run("nonexistent tag", func(t *testing.T) {
-		results, err := SearchNotesByTags([]string{"nonexistent"}, -1)
+		results, err := SearchNotesByTags([]string{"nonexistent"}, -1, false)
 		if err != nil {
 			t.Fatalf("SearchNotesByTags failed: %v", err)
 		}
@@ -190,6 +211,12 @@ func TestPinOperations(t *testing.T) {
 		}
 	})
 
+	t.Run("PinNote doesn't guess at typos", func(t *testing.T) {
+		if err := PinNote("tset-note"); err == nil {
+			t.Error("Should error instead of pinning the closest title")
+		}
+	})
+
 	t.Run("UnpinNote", func(t *testing.T) {
 		err := UnpinNote("test-note")
 		if err != nil {
@@ -271,6 +298,51 @@ func TestTrashOperations(t *testing.T) {
 			t.Error("Note should be back in index")
 		}
 	})
+
+	t.Run("trashDays purges old trash", func(t *testing.T) {
+		createTestNote(t, notesDir, "old-trash", "Old")
+		if err := DeleteNote("old-trash"); err != nil {
+			t.Fatalf("DeleteNote failed: %v", err)
+		}
+		data.WithTrashLock(func(entries map[string]data.TrashEntry) error {
+			for id, e := range entries {
+				e.DeletedAt = e.DeletedAt.AddDate(0, 0, -10)
+				entries[id] = e
+			}
+			return nil
+		})
+
+		if entries, _ := ListTrash(); len(entries) != 1 {
+			t.Fatalf("retention 0 should keep trash, got %+v", entries)
+		}
+		cfg, _ := data.LoadConfig()
+		cfg.TrashDays = 7
+		data.SaveConfig(cfg)
+		if entries, _ := ListTrash(); len(entries) != 0 {
+			t.Errorf("expected trash purged, got %+v", entries)
+		}
+	})
+
+	t.Run("failed purge still deletes", func(t *testing.T) {
+		// A directory in place of an expired trashed file can't be removed
+		os.MkdirAll(filepath.Join(data.TrashPath(), "stuck.md", "keep"), 0755)
+		data.WithTrashLock(func(entries map[string]data.TrashEntry) error {
+			entries["stuck"] = data.TrashEntry{ID: "stuck", Name: "stuck", File: "stuck.md", DeletedAt: time.Now().AddDate(0, 0, -10)}
+			return nil
+		})
+
+		createTestNote(t, notesDir, "doomed", "Doomed")
+		err := DeleteNote("doomed")
+		if err == nil || !IsPurgeError(err) {
+			t.Fatalf("DeleteNote error = %v, want a purge error", err)
+		}
+		if matches, _ := data.FindTrashed("doomed"); len(matches) != 1 {
+			t.Errorf("note should be in trash, got %+v", matches)
+		}
+		if err := DeleteNote("missing"); IsPurgeError(err) {
+			t.Errorf("a missing note is not a purge error: %v", err)
+		}
+	})
 }
 
 // --- Tags tests ---
@@ -586,7 +658,7 @@ func TestFilterNotesByTags(t *testing.T) {
 	createTestNote(t, notesDir, "note4", ".work.urgent.project\nContent 4")
 
 	t.Run("filters by single tag", func(t *testing.T) {
-		results, err := FilterNotesByTags([]string{"personal"}, -1)
+		results, err := FilterNotesByTags([]string{"personal"}, -1, false)
 		if err != nil {
 			t.Fatalf("FilterNotesByTags failed: %v", err)
 		}
@@ -599,7 +671,7 @@ func TestFilterNotesByTags(t *testing.T) {
 	})
 
 	t.Run("filters by multiple tags AND logic", func(t *testing.T) {
-		results, err := FilterNotesByTags([]string{"work", "urgent"}, -1)
+		results, err := FilterNotesByTags([]string{"work", "urgent"}, -1, false)
 		if err != nil {
 			t.Fatalf("FilterNotesByTags failed: %v", err)
 		}
@@ -610,7 +682,7 @@ func TestFilterNotesByTags(t *testing.T) {
 	})
 
 	t.Run("returns empty for nonexistent tag", func(t *testing.T) {
-		results, err := FilterNotesByTags([]string{"nonexistent"}, -1)
+		results, err := FilterNotesByTags([]string{"nonexistent"}, -1, false)
 		if err != nil {
 			t.Fatalf("FilterNotesByTags failed: %v", err)
 		}
@@ -620,7 +692,7 @@ func TestFilterNotesByTags(t *testing.T) {
 	})
 
 	t.Run("returns empty when no notes have all tags", func(t *testing.T) {
-		results, err := FilterNotesByTags([]string{"personal", "work"}, -1)
+		results, err := FilterNotesByTags([]string{"personal", "work"}, -1, false)
 		if err != nil {
 			t.Fatalf("FilterNotesByTags failed: %v", err)
 		}
@@ -630,7 +702,7 @@ func TestFilterNotesByTags(t *testing.T) {
 	})
 
 	t.Run("respects limit", func(t *testing.T) {
-		results, err := FilterNotesByTags([]string{"work"}, 1)
+		results, err := FilterNotesByTags([]string{"work"}, 1, false)
 		if err != nil {
 			t.Fatalf("FilterNotesByTags failed: %v", err)
 		}
@@ -639,3 +711,815 @@ func TestFilterNotesByTags(t *testing.T) {
 		}
 	})
 }
+
+func TestHierarchicalTags(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	createTestNote(t, notesDir, "acme", ".work/clients/acme\nContent")
+	createTestNote(t, notesDir, "globex", ".work/clients/globex.urgent\nContent")
+	createTestNote(t, notesDir, "clients", ".work/clients\nContent")
+	createTestNote(t, notesDir, "home", ".home\nContent")
+
+	titles := func(results []SearchResult) string {
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Title)
+		}
+		sort.Strings(out)
+		return strings.Join(out, ",")
+	}
+
+	t.Run("filter includes descendants", func(t *testing.T) {
+		results, _ := FilterNotesByTags([]string{"work"}, -1, false)
+		if got := titles(results); got != "acme,clients,globex" {
+			t.Errorf("work = [%s]", got)
+		}
+		results, _ = FilterNotesByTags([]string{"work/clients", "urgent"}, -1, false)
+		if got := titles(results); got != "globex" {
+			t.Errorf("work/clients + urgent = [%s]", got)
+		}
+	})
+
+	t.Run("exact opts out", func(t *testing.T) {
+		results, _ := FilterNotesByTags([]string{"work/clients"}, -1, true)
+		if got := titles(results); got != "clients" {
+			t.Errorf("exact work/clients = [%s]", got)
+		}
+		results, _ = SearchNotesByTags([]string{"work"}, -1, true)
+		if len(results) != 0 {
+			t.Errorf("no note is tagged exactly work, got %v", results)
+		}
+	})
+
+	t.Run("search counts a note once per tag", func(t *testing.T) {
+		results, _ := SearchNotesByTags([]string{"work", "home"}, -1, false)
+		if got := titles(results); got != "acme,clients,globex,home" {
+			t.Errorf("work OR home = [%s]", got)
+		}
+		for _, r := range results {
+			if r.Score != 1 {
+				t.Errorf("%s scored %d, want 1", r.Title, r.Score)
+			}
+		}
+	})
+
+	t.Run("query tag field", func(t *testing.T) {
+		results, _ := SearchQuery("tag:work -tag:=work/clients", -1)
+		if got := titles(results); got != "acme,globex" {
+			t.Errorf("query = [%s]", got)
+		}
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		roots, err := TagTree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(roots) != 3 || roots[0].Name != "home" || roots[2].Name != "work" {
+			t.Fatalf("roots = %+v", roots)
+		}
+		work := roots[2]
+		if work.Total != 3 || work.Count != 0 || len(work.Children) != 1 {
+			t.Errorf("work = %+v", work)
+		}
+		clients := work.Children[0]
+		if clients.Tag != "work/clients" || clients.Total != 3 || clients.Count != 1 || len(clients.Children) != 2 {
+			t.Errorf("clients = %+v", clients)
+		}
+	})
+}
+
+func TestRewriteTagsAcrossNotes(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	createTestNote(t, notesDir, "acme", ".#work/clients/acme.urgent\nContent")
+	createTestNote(t, notesDir, "plan", "---\ntags: [work, home]\n---\nContent")
+	createTestNote(t, notesDir, "chores", ".home\nContent")
+
+	read := func(name string) string {
+		content, _ := os.ReadFile(filepath.Join(notesDir, name+".md"))
+		return string(content)
+	}
+
+	t.Run("dry run writes nothing", func(t *testing.T) {
+		changes, err := RenameTag("work", "job", false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 2 || changes[0].Note != "acme" || strings.Join(changes[0].After, ",") != "job/clients/acme,urgent" {
+			t.Errorf("changes = %+v", changes)
+		}
+		if read("acme") != ".#work/clients/acme.urgent\nContent" {
+			t.Errorf("acme changed: %q", read("acme"))
+		}
+	})
+
+	t.Run("rename moves the subtree", func(t *testing.T) {
+		if _, err := RenameTag(".work", "job", false, false); err != nil {
+			t.Fatal(err)
+		}
+		if got := read("acme"); got != ".#job/clients/acme.urgent\nContent" {
+			t.Errorf("acme = %q", got)
+		}
+		if got := read("plan"); got != "---\ntags: [job, home]\n---\nContent" {
+			t.Errorf("plan = %q", got)
+		}
+		tags, _ := data.LoadTags()
+		if _, ok := tags["work"]; ok {
+			t.Error("tags.json still has work")
+		}
+		if tags["job"].Total != 2 {
+			t.Errorf("job = %+v", tags["job"])
+		}
+		if results, _ := SearchQuery("tag:job/clients", -1); len(results) != 1 {
+			t.Errorf("tag:job/clients found %d notes", len(results))
+		}
+		if versions, _ := data.ListSnapshots("acme"); len(versions) < 2 {
+			t.Errorf("acme has %d versions, want the old and new content", len(versions))
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		changes, err := MergeTags([]string{"home", "urgent"}, "life", false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 3 {
+			t.Errorf("changed %d notes, want 3", len(changes))
+		}
+		if got := read("chores"); got != ".life\nContent" {
+			t.Errorf("chores = %q", got)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if _, err := DeleteTag("job/clients", true, false); err != nil {
+			t.Fatal(err)
+		}
+		if got := read("acme"); got != ".#job/clients/acme.life\nContent" {
+			t.Errorf("exact delete changed acme: %q", got)
+		}
+		if _, err := DeleteTag("job", false, false); err != nil {
+			t.Fatal(err)
+		}
+		if got := read("acme"); got != ".life\nContent" {
+			t.Errorf("acme = %q", got)
+		}
+		index, _ := data.LoadIndex()
+		if tags := index["plan"].Tags; strings.Join(tags, ",") != "life" {
+			t.Errorf("plan tags = %v", tags)
+		}
+	})
+
+	t.Run("dry run matches the real run and reports skipped notes", func(t *testing.T) {
+		createTestNote(t, notesDir, "mixed", "---\ntags: [home, life]\n---\n.life\nContent")
+		createTestNote(t, notesDir, "stale", ".life\nContent")
+		data.IndexNotes(notesDir)
+		// Edited since it was indexed: the index has the tag, the text doesn't
+		os.WriteFile(filepath.Join(notesDir, "stale.md"), []byte("Content"), 0644)
+
+		preview, err := RenameTag("life", "home", false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes, err := RenameTag("life", "home", false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(preview) != fmt.Sprint(changes) {
+			t.Errorf("dry run %+v, real run %+v", preview, changes)
+		}
+		var skipped []string
+		for _, c := range changes {
+			if c.Skipped {
+				skipped = append(skipped, c.Note)
+			}
+		}
+		if strings.Join(skipped, ",") != "stale" {
+			t.Errorf("skipped = %v, want stale", skipped)
+		}
+		if got := read("stale"); got != "Content" {
+			t.Errorf("skipped note changed: %q", got)
+		}
+	})
+
+	t.Run("invalid tags", func(t *testing.T) {
+		if _, err := RenameTag("life", "a.b", false, true); err == nil {
+			t.Error("expected an error for a tag with a period")
+		}
+		if _, err := RenameTag("life", "Life", false, true); err == nil {
+			t.Error("expected an error renaming a tag to itself")
+		}
+	})
+}
+
+func TestTasks(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	createTestNote(t, notesDir, "sprint", ".work\n- [ ] later\n- [ ] soon due:2026-01-02\n- [x] shipped\n")
+	createTestNote(t, notesDir, "home", "- [ ] fix sink #chores\n- [ ] later\n")
+
+	texts := func(items []TaskItem) string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.Note+":"+item.Text)
+		}
+		return strings.Join(out, ",")
+	}
+
+	t.Run("list and filter", func(t *testing.T) {
+		tests := []struct {
+			filter TaskFilter
+			want   string
+		}{
+			{TaskFilter{}, "sprint:soon due:2026-01-02,home:fix sink #chores,home:later,sprint:later"},
+			{TaskFilter{Status: "done"}, "sprint:shipped"},
+			{TaskFilter{Status: "all", Note: "Sprint"}, "sprint:soon due:2026-01-02,sprint:later,sprint:shipped"},
+			{TaskFilter{Tags: []string{"work"}}, "sprint:soon due:2026-01-02,sprint:later"},
+			{TaskFilter{Tags: []string{"chores"}}, "home:fix sink #chores"},
+		}
+		for _, tt := range tests {
+			items, err := ListTasks(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := texts(items); got != tt.want {
+				t.Errorf("%+v = %s, want %s", tt.filter, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("toggle rewrites the line and reindexes", func(t *testing.T) {
+		task, err := ToggleTask("sprint", 3, "soon due:2026-01-02")
+		if err != nil || !task.Done || task.Line != 3 {
+			t.Fatalf("task = %+v, err = %v", task, err)
+		}
+		content, _ := os.ReadFile(filepath.Join(notesDir, "sprint.md"))
+		if want := ".work\n- [ ] later\n- [x] soon due:2026-01-02\n- [x] shipped\n"; string(content) != want {
+			t.Errorf("content = %q", content)
+		}
+		items, _ := ListTasks(TaskFilter{Status: "done"})
+		if len(items) != 2 {
+			t.Errorf("done = %s", texts(items))
+		}
+	})
+
+	t.Run("a moved task is found by its text", func(t *testing.T) {
+		notePath := filepath.Join(notesDir, "sprint.md")
+		os.WriteFile(notePath, []byte(".work\nnew line\n- [ ] later\n- [x] soon due:2026-01-02\n- [x] shipped\n"), 0644)
+		if _, err := ToggleTask("sprint", 2, "later"); err != nil {
+			t.Fatal(err)
+		}
+		content, _ := os.ReadFile(notePath)
+		if !strings.Contains(string(content), "new line\n- [x] later\n") {
+			t.Errorf("content = %q", content)
+		}
+		if _, err := ToggleTask("sprint", 2, "gone"); err == nil {
+			t.Error("expected an error for a missing task")
+		}
+	})
+}
+
+func TestSelectNotes(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	os.MkdirAll(filepath.Join(notesDir, "work"), 0755)
+	createTestNote(t, notesDir, "wiki", "---\npublish: yes\n---\n.docs/howto\n")
+	createTestNote(t, notesDir, "draft", "---\npublish: no\n---\n.docs\n")
+	createTestNote(t, notesDir, "work/plan", ".docs\n")
+	createTestNote(t, notesDir, "diary", "private")
+	data.IndexNotes(notesDir)
+
+	tests := []struct {
+		filter NoteFilter
+		want   string
+	}{
+		{NoteFilter{}, "diary,draft,wiki,work/plan"},
+		{NoteFilter{Tags: []string{"docs"}}, "draft,wiki,work/plan"},
+		{NoteFilter{Tags: []string{"docs"}, Exact: true}, "draft,work/plan"},
+		{NoteFilter{Properties: []string{"publish=yes"}}, "wiki"},
+		{NoteFilter{Properties: []string{"publish"}}, "draft,wiki"},
+		{NoteFilter{Tags: []string{"docs"}, Notebook: "work"}, "work/plan"},
+		{NoteFilter{Query: "private"}, "diary"},
+		{NoteFilter{Query: "private", Tags: []string{"docs"}}, ""},
+		{NoteFilter{Dates: []string{time.Now().Format("060102")}, Tags: []string{"docs/howto"}}, "wiki"},
+		{NoteFilter{Dates: []string{"0101"}, ByModified: true}, ""},
+	}
+	for _, tt := range tests {
+		notes, err := SelectNotes(tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for name := range notes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("%+v = %s, want %s", tt.filter, got, tt.want)
+		}
+	}
+}
+
+// writeTestArchive gzips files into a tar archive, as gote export writes one
+func writeTestArchive(t *testing.T, files map[string]string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg})
+		tw.Write([]byte(files[name]))
+	}
+	tw.Close()
+	gw.Close()
+	return &buf
+}
+
+func TestMergeImport(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	createTestNote(t, notesDir, "same", "unchanged")
+	createTestNote(t, notesDir, "plan", "my plan")
+	createTestNote(t, notesDir, "original", "copied text")
+
+	archive := func() *bytes.Buffer {
+		return writeTestArchive(t, map[string]string{
+			"notes/same.md":     "unchanged",
+			"notes/plan.md":     "their plan",
+			"notes/copy.md":     "copied text",
+			"notes/work/new.md": ".work\nnew note",
+			"gote/config.json":  `{"note_dir": "/elsewhere"}`,
+			ExportMetadataFile:  `{"work/new": {"created": "200102.030405"}}`,
+		})
+	}
+
+	result, err := MergeImport(archive(), ConflictSkip, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(result.Added, result.Unchanged, result.Duplicates, result.Skipped) !=
+		"[work/new] [same] [copy (same as original)] [plan]" {
+		t.Errorf("skip result = %+v", result)
+	}
+	index, _ := data.LoadIndex()
+	if index["work/new"].Created != "200102.030405" || !slices.Equal(index["work/new"].Tags, []string{"work"}) {
+		t.Errorf("imported note indexed as %+v", index["work/new"])
+	}
+	if cfg, _ := data.LoadConfig(); cfg.NoteDir != notesDir {
+		t.Errorf("config note dir = %s, want it untouched", cfg.NoteDir)
+	}
+
+	t.Run("rename", func(t *testing.T) {
+		for _, want := range []string{"plan (imported)", "plan (imported 2)"} {
+			result, err := MergeImport(archive(), ConflictRename, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Renamed) != 1 || result.Renamed[0] != "plan -> "+want {
+				t.Errorf("renamed = %v, want plan -> %s", result.Renamed, want)
+			}
+			if content, _ := os.ReadFile(filepath.Join(notesDir, want+".md")); string(content) != "their plan" {
+				t.Errorf("%s = %q", want, content)
+			}
+		}
+	})
+
+	t.Run("ask and overwrite", func(t *testing.T) {
+		var asked []string
+		result, err := MergeImport(archive(), ConflictAsk, func(name string) (string, error) {
+			asked = append(asked, name)
+			return ConflictOverwrite, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(asked, []string{"plan"}) || !slices.Equal(result.Overwritten, []string{"plan"}) {
+			t.Errorf("asked about %v, overwrote %v", asked, result.Overwritten)
+		}
+		if content, _ := os.ReadFile(filepath.Join(notesDir, "plan.md")); string(content) != "their plan" {
+			t.Errorf("plan = %q", content)
+		}
+		if snapshots, _ := data.ListSnapshots("plan"); len(snapshots) == 0 {
+			t.Error("overwritten note was not snapshotted")
+		}
+	})
+
+	t.Run("unsafe path", func(t *testing.T) {
+		_, err := MergeImport(writeTestArchive(t, map[string]string{"notes/../escape.md": "x"}), ConflictSkip, nil)
+		if err == nil {
+			t.Error("expected an error for a path outside the notes directory")
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(notesDir), "escape.md")); err == nil {
+			t.Error("note written outside the notes directory")
+		}
+	})
+}
+
+func TestImportFrom(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	src := t.TempDir()
+	write := func(name, content string) {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+	}
+	write("vault/.obsidian/app.json", "{}")
+	write("vault/img/chart.png", "PNG")
+	write("vault/Projects/Alpha.md", "---\ntags: [client]\n---\nOn #project/alpha, see [[Beta]]\n![[chart.png|200]]\n![[Beta]]\n")
+	write("vault/Beta.md", "Beta")
+	old := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+	os.Chtimes(filepath.Join(src, "vault", "Beta.md"), old, old)
+
+	t.Run("obsidian", func(t *testing.T) {
+		result, err := ImportFrom(SourceObsidian, filepath.Join(src, "vault"), "obs", ConflictSkip, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(result.Added, []string{"obs/Beta", "obs/Projects/Alpha"}) ||
+			!slices.Equal(result.Attachments, []string{"obs/img/chart.png"}) || len(result.Problems) != 1 {
+			t.Errorf("result = %+v", result)
+		}
+		content, _ := os.ReadFile(filepath.Join(notesDir, "obs", "Projects", "Alpha.md"))
+		want := "---\ntags: [client]\n---\n.project/alpha\nOn #project/alpha, see [[Beta]]\n![chart.png](<../img/chart.png>)\n[[Beta]]\n"
+		if string(content) != want {
+			t.Errorf("Alpha =\n%s\nwant\n%s", content, want)
+		}
+		index, _ := data.LoadIndex()
+		if tags := index["obs/Projects/Alpha"].Tags; !slices.Equal(tags, []string{"project/alpha", "client"}) {
+			t.Errorf("Alpha tags = %v", tags)
+		}
+		if modified := index["obs/Beta"].Modified; modified != "240301.100000" {
+			t.Errorf("Beta modified = %s, want 240301.100000", modified)
+		}
+		if _, err := os.Stat(filepath.Join(notesDir, "obs", ".obsidian")); err == nil {
+			t.Error("hidden .obsidian folder was imported")
+		}
+
+		// Importing again changes nothing
+		again, err := ImportFrom(SourceObsidian, filepath.Join(src, "vault"), "obs", ConflictSkip, nil)
+		if err != nil || again.Imported() != 0 || len(again.Unchanged) != 2 {
+			t.Errorf("second import = %+v, %v", again, err)
+		}
+	})
+
+	t.Run("folder", func(t *testing.T) {
+		write("folder/readme.txt", "plain text")
+		write("folder/sub/page.md", "Beta")
+		result, err := ImportFrom(SourceFolder, filepath.Join(src, "folder"), "", ConflictSkip, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(result.Added, []string{"readme"}) || len(result.Duplicates) != 1 {
+			t.Errorf("result = %+v", result)
+		}
+	})
+
+	t.Run("enex", func(t *testing.T) {
+		write("notes.enex", `<en-export><note><title>Trip/Plan</title><created>20230510T083000Z</created>
+<updated>20230601T120000Z</updated><tag>Travel Plans</tag>
+<content><![CDATA[<en-note><div>Pack</div><en-media hash="0cc175b9c0f1b6a831c399e269772661" type="image/png"/></en-note>]]></content>
+<resource><data>YQ==</data><mime>image/png</mime><resource-attributes><file-name>a.png</file-name></resource-attributes></resource>
+</note></en-export>`)
+		result, err := ImportFrom(SourceEnex, filepath.Join(src, "notes.enex"), "ev", ConflictSkip, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(result.Added, []string{"ev/Trip-Plan"}) || !slices.Equal(result.Attachments, []string{"ev/attachments/a.png"}) {
+			t.Errorf("result = %+v", result)
+		}
+		content, _ := os.ReadFile(filepath.Join(notesDir, "ev", "Trip-Plan.md"))
+		if want := ".travel-plans\nPack\n![a.png](<attachments/a.png>)\n"; string(content) != want {
+			t.Errorf("note = %q, want %q", content, want)
+		}
+		index, _ := data.LoadIndex()
+		wantCreated := time.Date(2023, 5, 10, 8, 30, 0, 0, time.UTC).Local().Format("060102.150405")
+		if meta := index["ev/Trip-Plan"]; meta.Created != wantCreated || !slices.Equal(meta.Tags, []string{"travel-plans"}) {
+			t.Errorf("indexed as %+v", meta)
+		}
+	})
+}
+
+// --- Link tests ---
+
+func TestLinks(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	createTestNote(t, notesDir, "hub", "Links to [[spoke]] and [[nowhere]]")
+	createTestNote(t, notesDir, "spoke", "Back to [[hub]]")
+	createTestNote(t, notesDir, "other", "Also see [the spoke](spoke.md)")
+
+	t.Run("GetLinks skips missing targets", func(t *testing.T) {
+		_, results, err := GetLinks("hub")
+		if err != nil {
+			t.Fatalf("GetLinks failed: %v", err)
+		}
+		if len(results) != 1 || results[0].Title != "spoke" {
+			t.Errorf("GetLinks(hub) = %v, want [spoke]", results)
+		}
+	})
+
+	t.Run("GetBacklinks", func(t *testing.T) {
+		_, results, err := GetBacklinks("spoke")
+		if err != nil {
+			t.Fatalf("GetBacklinks failed: %v", err)
+		}
+		if len(results) != 2 {
+			t.Errorf("Expected 2 backlinks, got %d", len(results))
+		}
+	})
+
+	t.Run("typos resolve to the closest title", func(t *testing.T) {
+		name, results, err := GetBacklinks("spokee")
+		if err != nil {
+			t.Fatalf("GetBacklinks failed: %v", err)
+		}
+		if name != "spoke" || len(results) != 2 {
+			t.Errorf("GetBacklinks(spokee) = %q, %v; want spoke and 2 backlinks", name, results)
+		}
+	})
+
+	t.Run("rename rewrites references", func(t *testing.T) {
+		if err := RenameNote("spoke", "wheel"); err != nil {
+			t.Fatalf("RenameNote failed: %v", err)
+		}
+		hub, _ := os.ReadFile(filepath.Join(notesDir, "hub.md"))
+		if string(hub) != "Links to [[wheel]] and [[nowhere]]" {
+			t.Errorf("hub content = %q", string(hub))
+		}
+		other, _ := os.ReadFile(filepath.Join(notesDir, "other.md"))
+		if string(other) != "Also see [the spoke](wheel.md)" {
+			t.Errorf("other content = %q", string(other))
+		}
+
+		_, results, err := GetBacklinks("wheel")
+		if err != nil {
+			t.Fatalf("GetBacklinks failed: %v", err)
+		}
+		if len(results) != 2 {
+			t.Errorf("Expected 2 backlinks after rename, got %d", len(results))
+		}
+	})
+}
+
+func TestNotebookLinks(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	os.MkdirAll(filepath.Join(notesDir, "work"), 0755)
+	os.MkdirAll(filepath.Join(notesDir, "home"), 0755)
+	createTestNote(t, notesDir, "work/plan", "Work plan")
+	createTestNote(t, notesDir, "home/plan", "Home plan")
+	createTestNote(t, notesDir, "work/notes", "See [the plan](plan.md)")
+	createTestNote(t, notesDir, "index", "[a](work/plan.md) and [b](home/plan.md)")
+
+	t.Run("same base name stays apart", func(t *testing.T) {
+		links, err := data.LoadLinks()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := links["plan"]; ok {
+			t.Error("links.json merged both plans into plan")
+		}
+		if got := links["work/plan"].Backlinks; len(got) != 2 {
+			t.Errorf("work/plan backlinks = %v, want index and work/notes", got)
+		}
+		if got := links["home/plan"].Backlinks; len(got) != 1 || got[0] != "index" {
+			t.Errorf("home/plan backlinks = %v, want [index]", got)
+		}
+	})
+
+	t.Run("move to another notebook rewrites paths", func(t *testing.T) {
+		if err := RenameNote("work/plan", "archive/plan"); err != nil {
+			t.Fatalf("RenameNote failed: %v", err)
+		}
+		index, _ := os.ReadFile(filepath.Join(notesDir, "index.md"))
+		if string(index) != "[a](archive/plan.md) and [b](home/plan.md)" {
+			t.Errorf("index content = %q", index)
+		}
+		notes, _ := os.ReadFile(filepath.Join(notesDir, "work", "notes.md"))
+		if string(notes) != "See [the plan](../archive/plan.md)" {
+			t.Errorf("work/notes content = %q", notes)
+		}
+	})
+
+	t.Run("moved note keeps its links", func(t *testing.T) {
+		if err := RenameNote("work/notes", "home/notes"); err != nil {
+			t.Fatalf("RenameNote failed: %v", err)
+		}
+		notes, _ := os.ReadFile(filepath.Join(notesDir, "home", "notes.md"))
+		if string(notes) != "See [the plan](../archive/plan.md)" {
+			t.Errorf("home/notes content = %q", notes)
+		}
+		links, _ := data.LoadLinks()
+		if got := links["archive/plan"].Backlinks; len(got) != 2 || got[0] != "home/notes" || got[1] != "index" {
+			t.Errorf("archive/plan backlinks = %v", got)
+		}
+	})
+}
+
+// --- Notebook tests ---
+
+func TestNotebooks(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	os.MkdirAll(filepath.Join(notesDir, "work"), 0755)
+	os.MkdirAll(filepath.Join(notesDir, "personal"), 0755)
+	createTestNote(t, notesDir, "work/standup", "Daily standup")
+	createTestNote(t, notesDir, "personal/standup", "Morning routine")
+	createTestNote(t, notesDir, "todo", "See [[work/standup]]")
+
+	t.Run("notebook query filter", func(t *testing.T) {
+		results, err := SearchQuery("notebook:work", -1)
+		if err != nil {
+			t.Fatalf("SearchQuery failed: %v", err)
+		}
+		if len(results) != 1 || results[0].Title != "work/standup" {
+			t.Errorf("notebook:work = %v", results)
+		}
+	})
+
+	t.Run("FilterByNotebook", func(t *testing.T) {
+		all := []SearchResult{{Title: "work/standup"}, {Title: "personal/standup"}, {Title: "todo"}}
+		if got := FilterByNotebook(all, "personal"); len(got) != 1 || got[0].Title != "personal/standup" {
+			t.Errorf("FilterByNotebook(personal) = %v", got)
+		}
+		if got := FilterByNotebook(all, ""); len(got) != 3 {
+			t.Errorf("empty notebook should keep all, got %v", got)
+		}
+	})
+
+	t.Run("CreateNotebook", func(t *testing.T) {
+		if err := CreateNotebook("projects/gote"); err != nil {
+			t.Fatalf("CreateNotebook failed: %v", err)
+		}
+		if info, err := os.Stat(filepath.Join(notesDir, "projects", "gote")); err != nil || !info.IsDir() {
+			t.Error("notebook directory not created")
+		}
+		if err := CreateNotebook("projects/gote"); err == nil {
+			t.Error("expected error for existing notebook")
+		}
+	})
+
+	t.Run("MoveNote updates path and links", func(t *testing.T) {
+		newName, err := MoveNote("work/standup", "projects/gote")
+		if err != nil {
+			t.Fatalf("MoveNote failed: %v", err)
+		}
+		if newName != "projects/gote/standup" {
+			t.Errorf("new name = %q", newName)
+		}
+		if _, err := os.Stat(filepath.Join(notesDir, "projects", "gote", "standup.md")); err != nil {
+			t.Errorf("file not moved: %v", err)
+		}
+		content, _ := os.ReadFile(filepath.Join(notesDir, "todo.md"))
+		if string(content) != "See [[projects/gote/standup]]" {
+			t.Errorf("link not rewritten: %q", content)
+		}
+	})
+
+	t.Run("MoveNote to top level", func(t *testing.T) {
+		if _, err := MoveNote("personal/standup", "/"); err != nil {
+			t.Fatalf("MoveNote failed: %v", err)
+		}
+		index, _ := data.LoadIndex()
+		if meta, ok := index["standup"]; !ok || meta.FilePath != filepath.Join(notesDir, "standup.md") {
+			t.Errorf("expected top-level standup, got %+v", index)
+		}
+	})
+}
+
+func TestAddToNote(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+	// The editor must never run
+	data.SaveConfig(data.Config{NoteDir: notesDir, Editor: "false"})
+
+	createTestNote(t, notesDir, "log", "---\ntitle: Log\n---\n.ops\nfirst")
+
+	t.Run("append and prepend", func(t *testing.T) {
+		before, _ := data.LoadIndex()
+		if _, err := AddToNote("log", "second", AddOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := AddToNote("log", "zeroth\n", AddOptions{Prepend: true}); err != nil {
+			t.Fatal(err)
+		}
+		content, _ := os.ReadFile(filepath.Join(notesDir, "log.md"))
+		if want := "---\ntitle: Log\n---\n.ops\nzeroth\nfirst\nsecond\n"; string(content) != want {
+			t.Errorf("content = %q, want %q", content, want)
+		}
+		index, _ := data.LoadIndex()
+		if index["log"].WordCount != before["log"].WordCount+2 || strings.Join(index["log"].Tags, ",") != "ops" {
+			t.Errorf("not reindexed: %+v", index["log"])
+		}
+		if versions, _ := data.ListSnapshots("log"); len(versions) < 2 {
+			t.Errorf("history has %d versions", len(versions))
+		}
+	})
+
+	t.Run("timestamp heading", func(t *testing.T) {
+		if _, err := AddToNote("log", "third", AddOptions{Timestamp: true}); err != nil {
+			t.Fatal(err)
+		}
+		content, _ := os.ReadFile(filepath.Join(notesDir, "log.md"))
+		heading := "\n\n## " + time.Now().Format("2006-01-02")
+		if !strings.Contains(string(content), heading) || !strings.HasSuffix(string(content), "\n\nthird\n") {
+			t.Errorf("content = %q", content)
+		}
+	})
+
+	t.Run("missing note", func(t *testing.T) {
+		if _, err := AddToNote("work/inbox", "idea", AddOptions{}); err == nil {
+			t.Error("expected an error without Create")
+		}
+		name, err := AddToNote("work/inbox", "idea", AddOptions{Create: true})
+		if err != nil || name != "work/inbox" {
+			t.Fatalf("name = %q, err = %v", name, err)
+		}
+		content, _ := os.ReadFile(filepath.Join(notesDir, "work", "inbox.md"))
+		if string(content) != "idea\n" {
+			t.Errorf("content = %q", content)
+		}
+		if index, _ := data.LoadIndex(); index["work/inbox"].FilePath == "" {
+			t.Error("new note not indexed")
+		}
+	})
+
+	t.Run("empty text", func(t *testing.T) {
+		if _, err := AddToNote("log", " \n", AddOptions{}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("concurrent adds all land", func(t *testing.T) {
+		errs := make(chan error, 8)
+		for i := 0; i < 8; i++ {
+			go func(i int) {
+				_, err := AddToNote("log", fmt.Sprintf("line %d", i), AddOptions{})
+				errs <- err
+			}(i)
+		}
+		for i := 0; i < 8; i++ {
+			if err := <-errs; err != nil {
+				t.Fatal(err)
+			}
+		}
+		content, _ := os.ReadFile(filepath.Join(notesDir, "log.md"))
+		for i := 0; i < 8; i++ {
+			if !strings.Contains(string(content), fmt.Sprintf("line %d\n", i)) {
+				t.Errorf("line %d lost: %q", i, content)
+			}
+		}
+		if index, _ := data.LoadIndex(); index["log"].WordCount != len(strings.Fields(string(content))) {
+			t.Errorf("index word count %d doesn't match the note", index["log"].WordCount)
+		}
+	})
+}
+
+func TestCreateNoteWithText(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+	data.SaveConfig(data.Config{NoteDir: notesDir, Editor: "false", TimestampNotes: "date"})
+	today := time.Now().Format("060102")
+
+	name, err := CreateNoteWithText("build", "", "", "ok\n")
+	if err != nil || name != today+" build" {
+		t.Fatalf("name = %q, err = %v", name, err)
+	}
+	content, _ := os.ReadFile(data.NotePath(notesDir, name))
+	if string(content) != "ok\n" {
+		t.Errorf("content = %q", content)
+	}
+
+	data.SaveTemplate("report", "---\ntags: ci\n---\n# {{title}} ({{prompt \"Branch\" \"main\"}})\n")
+	name, err = CreateNoteWithText("nightly", "report", "none", "all green\n")
+	if err != nil || name != "nightly" {
+		t.Fatalf("name = %q, err = %v", name, err)
+	}
+	content, _ = os.ReadFile(data.NotePath(notesDir, name))
+	if want := ".ci\n# nightly (main)\nall green\n"; string(content) != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+	if index, _ := data.LoadIndex(); index["nightly"].FilePath == "" {
+		t.Error("note not indexed")
+	}
+
+	if _, err := CreateNoteWithText("nightly", "", "none", "again"); !errors.Is(err, ErrNoteExists) {
+		t.Errorf("err = %v, want ErrNoteExists", err)
+	}
+}
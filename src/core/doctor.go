@@ -0,0 +1,443 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+
+	"gote/src/data"
+)
+
+// Problem kinds reported by Doctor
+const (
+	ProblemCorruptFile    = "corrupt-file"    // a JSON data file doesn't parse
+	ProblemMissingFile    = "missing-file"    // index entry whose note file is gone
+	ProblemUnindexed      = "unindexed"       // note file with no index entry
+	ProblemOutdated       = "outdated"        // index entry misnamed or older than its file
+	ProblemFTSMissing     = "fts-missing"     // indexed note absent from (or stale in) the FTS index
+	ProblemFTSOrphan      = "fts-orphan"      // FTS entry for a note not in the index
+	ProblemStaleTags      = "stale-tags"      // tags.json disagrees with the index
+	ProblemStaleLinks     = "stale-links"     // links.json disagrees with the index
+	ProblemDanglingPin    = "dangling-pin"    // pin naming a note not in the index
+	ProblemTrashCollision = "trash-collision" // trashed note whose name a live note has taken
+	ProblemTrashManifest  = "trash-manifest"  // trashed file not recorded in the trash manifest
+)
+
+// Problem is one inconsistency found by Doctor
+type Problem struct {
+	Kind    string
+	Subject string // note, tag or file name
+	Detail  string
+	Fixed   bool
+}
+
+// doctor carries the state of one consistency check
+type doctor struct {
+	fix      bool
+	noteDir  string
+	skip     map[string]bool // data files too damaged to check against, or rebuilt whole
+	problems []Problem
+}
+
+func (d *doctor) report(kind, subject, detail string, fixErr error) {
+	p := Problem{Kind: kind, Subject: subject, Detail: detail}
+	if d.fix {
+		if fixErr == nil {
+			p.Fixed = true
+		} else {
+			p.Detail += " (fix failed: " + fixErr.Error() + ")"
+		}
+	}
+	d.problems = append(d.problems, p)
+}
+
+// Doctor checks the index, FTS index, tags, links, pins and trash against
+// the notes on disk and each other. With fix set, each problem is repaired
+// as it is found: damaged files are moved aside and rebuilt, the index is
+// corrected under its lock, dangling pins are repointed or dropped and
+// trash entries are recorded and renamed.
+func Doctor(fix bool) ([]Problem, error) {
+	d := &doctor{fix: fix, skip: make(map[string]bool)}
+	if err := d.checkDataFiles(); err != nil {
+		return d.problems, err
+	}
+	if d.skip["config.json"] {
+		return d.problems, nil // without the config, the notes folder is unknown
+	}
+	if d.skip["index.json"] {
+		return d.problems, nil // nothing else can be checked without an index
+	}
+	if err := d.checkIndex(); err != nil {
+		return d.problems, err
+	}
+	if !d.skip["pins.json"] {
+		if err := d.checkPins(); err != nil {
+			return d.problems, err
+		}
+	}
+	if !d.skip["trash/manifest.json"] || d.fix {
+		if err := d.checkTrash(); err != nil {
+			return d.problems, err
+		}
+	}
+	return d.problems, nil
+}
+
+// errConfigKept is why a damaged config.json isn't fixed: resetting it
+// would lose the notes folder, and every later check would use the wrong one
+var errConfigKept = errors.New("not reset, as that would lose the notes folder")
+
+// checkDataFiles finds JSON files that don't parse. Fixing moves them aside;
+// the later checks then rebuild them from the notes. A damaged config.json
+// is only reported, and stops the checks before anything is changed.
+func (d *doctor) checkDataFiles() error {
+	for _, f := range data.DataFiles() {
+		err := f.Check()
+		if err == nil {
+			continue
+		}
+		if f.Name == "config.json" {
+			d.skip[f.Name] = true
+			d.report(ProblemCorruptFile, f.Name, err.Error()+"; repair it by hand, then run gote doctor again", errConfigKept)
+			return nil
+		}
+		if !d.fix {
+			d.skip[f.Name] = true
+			d.report(ProblemCorruptFile, f.Name, err.Error(), nil)
+			continue
+		}
+		dest, qerr := data.QuarantineFile(f.Path)
+		detail := err.Error()
+		if qerr == nil {
+			detail += "; moved to " + dest
+		}
+		d.report(ProblemCorruptFile, f.Name, detail, qerr)
+		// The index is rebuilt note by note below; the rest are regenerated whole
+		if qerr != nil || f.Name != "index.json" {
+			d.skip[f.Name] = true
+		}
+	}
+
+	bad, err := data.CountBadFTSLogLines()
+	if err != nil {
+		return err
+	}
+	if bad > 0 {
+		var fixErr error
+		if d.fix {
+			fixErr = data.CompactFTS()
+		}
+		d.report(ProblemCorruptFile, "fts.log", fmt.Sprintf("%d unreadable journal entries", bad), fixErr)
+	}
+
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	d.noteDir = cfg.NoteDir
+	return nil
+}
+
+// checkIndex compares the index with the note files, the FTS index, and
+// tags.json and links.json. When fixing, it holds the index lock throughout,
+// and saving the index regenerates tags.json and links.json.
+func (d *doctor) checkIndex() error {
+	if !d.fix {
+		index, err := data.LoadIndex()
+		if err != nil {
+			return fmt.Errorf("loading index: %w", err)
+		}
+		return d.examineIndex(index)
+	}
+	return data.WithIndexLock(d.examineIndex)
+}
+
+func (d *doctor) examineIndex(index map[string]data.NoteMeta) error {
+	if !d.skip["tags.json"] {
+		d.checkTags(index)
+	}
+	if !d.skip["links.json"] {
+		d.checkLinks(index)
+	}
+
+	files, err := data.ScanNotes(d.noteDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("scanning notes: %w", err)
+	}
+
+	indexed := make(map[string]bool, len(index))
+	for _, key := range sortedKeys(index) {
+		meta := index[key]
+		info, err := os.Stat(meta.FilePath)
+		if os.IsNotExist(err) {
+			var fixErr error
+			if d.fix {
+				delete(index, key)
+				fixErr = data.RemoveDocFTS(key)
+			}
+			d.report(ProblemMissingFile, key, "file not found: "+meta.FilePath, fixErr)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		indexed[meta.FilePath] = true
+
+		want := key
+		if _, inNotes := files[meta.FilePath]; inNotes {
+			want = data.NoteName(d.noteDir, meta.FilePath)
+		}
+		var detail string
+		switch {
+		case want != key:
+			detail = "indexed as " + key + ", should be " + want
+		case meta.Modified != info.ModTime().Format(timeFmt):
+			detail = "file changed since it was indexed"
+		default:
+			continue
+		}
+		var fixErr error
+		if d.fix {
+			if want != key {
+				delete(index, key)
+				if fixErr = data.RemoveDocFTS(key); fixErr != nil {
+					d.report(ProblemOutdated, key, detail, fixErr)
+					continue
+				}
+			}
+			fixErr = indexNoteFile(index, d.noteDir, meta.FilePath, meta, true)
+		}
+		d.report(ProblemOutdated, key, detail, fixErr)
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		if !indexed[path] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		var fixErr error
+		if d.fix {
+			fixErr = indexNoteFile(index, d.noteDir, path, data.NoteMeta{}, false)
+		}
+		d.report(ProblemUnindexed, data.NoteName(d.noteDir, path), path, fixErr)
+	}
+
+	if d.skip["fts.json"] {
+		if d.fix {
+			return data.IndexAllFTS(d.noteDir, index)
+		}
+		return nil
+	}
+	return d.checkFTS(index)
+}
+
+func (d *doctor) checkFTS(index map[string]data.NoteMeta) error {
+	fts, err := data.LoadFTS()
+	if err != nil {
+		return fmt.Errorf("loading FTS index: %w", err)
+	}
+
+	for _, key := range sortedKeys(index) {
+		meta := index[key]
+		doc, ok := fts.Docs[key]
+		if ok && doc.FilePath == meta.FilePath {
+			continue
+		}
+		detail := "not in the search index"
+		if ok {
+			detail = "search index points at " + doc.FilePath
+		}
+		var fixErr error
+		if d.fix {
+			var content []byte
+			if content, fixErr = os.ReadFile(meta.FilePath); fixErr == nil {
+				fixErr = data.IndexDocFTS(key, meta.FilePath, string(content))
+			}
+		}
+		d.report(ProblemFTSMissing, key, detail, fixErr)
+	}
+
+	for _, title := range sortedKeys(fts.Docs) {
+		if _, ok := index[title]; ok {
+			continue
+		}
+		var fixErr error
+		if d.fix {
+			fixErr = data.RemoveDocFTS(title)
+		}
+		d.report(ProblemFTSOrphan, title, "in the search index but not the note index", fixErr)
+	}
+	return nil
+}
+
+// checkTags compares tags.json with the tags in the index. The note order
+// within a tag is not significant.
+func (d *doctor) checkTags(index map[string]data.NoteMeta) {
+	saved, err := data.LoadTags()
+	if err != nil {
+		return // reported by checkDataFiles
+	}
+	want := data.BuildTagsIndex(index)
+	names := make(map[string]bool)
+	for tag := range saved {
+		names[tag] = true
+	}
+	for tag := range want {
+		names[tag] = true
+	}
+	for _, tag := range sortedKeys(names) {
+		got, inSaved := saved[tag]
+		exp, inWant := want[tag]
+		var detail string
+		switch {
+		case !inWant:
+			detail = "tag no longer used by any note"
+		case !inSaved:
+			detail = "tag missing from tags.json"
+		case !sameSet(got.Notes, exp.Notes) || got.Count != exp.Count:
+			detail = fmt.Sprintf("lists %d notes, index has %d", got.Count, exp.Count)
+		case got.Total != exp.Total:
+			detail = fmt.Sprintf("rolls up %d notes, index has %d", got.Total, exp.Total)
+		default:
+			continue
+		}
+		// Saving the index under the lock rewrites tags.json
+		d.report(ProblemStaleTags, tag, detail, nil)
+	}
+}
+
+// checkLinks compares links.json with the links in the index
+func (d *doctor) checkLinks(index map[string]data.NoteMeta) {
+	saved, err := data.LoadLinks()
+	if err != nil {
+		return
+	}
+	want := data.BuildLinksIndex(index)
+	names := make(map[string]bool)
+	for note := range saved {
+		names[note] = true
+	}
+	for note := range want {
+		names[note] = true
+	}
+	for _, note := range sortedKeys(names) {
+		got, exp := saved[note], want[note]
+		if sameSet(got.Links, exp.Links) && sameSet(got.Backlinks, exp.Backlinks) {
+			continue
+		}
+		d.report(ProblemStaleLinks, note, "links.json out of date", nil)
+	}
+}
+
+// checkPins finds pins that no longer name an indexed note. A pin whose note
+// was renamed or moved into a notebook is repointed; others are dropped.
+func (d *doctor) checkPins() error {
+	index, err := data.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("loading index: %w", err)
+	}
+	examine := func(pins map[string]data.EmptyStruct) error {
+		for _, pin := range sortedKeys(pins) {
+			if _, ok := index[pin]; ok {
+				continue
+			}
+			actual, _, found := data.LookupNote(index, pin)
+			detail := "pinned note not found"
+			if found {
+				detail = "pinned note is now " + actual
+			}
+			if d.fix {
+				delete(pins, pin)
+				if found {
+					pins[actual] = data.EmptyStruct{}
+				}
+			}
+			d.report(ProblemDanglingPin, pin, detail, nil)
+		}
+		return nil
+	}
+	if !d.fix {
+		pins, err := data.LoadPins()
+		if err != nil {
+			return fmt.Errorf("loading pins: %w", err)
+		}
+		return examine(pins)
+	}
+	return data.WithPinsLock(examine)
+}
+
+// checkTrash finds trashed files missing from the trash manifest, and
+// trashed notes whose name is taken by a live note, which can't be recovered
+// until one is renamed. Fixing records the files and renames the entries.
+func (d *doctor) checkTrash() error {
+	examine := func(entries map[string]data.TrashEntry) error {
+		taken := make(map[string]bool, len(entries))
+		for _, e := range entries {
+			taken[e.Name] = true
+		}
+		for _, id := range sortedKeys(entries) {
+			e := entries[id]
+			if e.Adopted {
+				d.report(ProblemTrashManifest, e.Name, "trashed file "+e.File+" missing from the trash manifest", nil)
+			}
+			if !noteFileExists(d.noteDir, e.Name) {
+				continue
+			}
+			name := e.Name
+			detail := "a note with this name exists; recovering it would fail"
+			if d.fix {
+				newName := ""
+				for n := 2; newName == ""; n++ {
+					candidate := fmt.Sprintf("%s-%d", e.Name, n)
+					if !taken[candidate] && !noteFileExists(d.noteDir, candidate) {
+						newName = candidate
+					}
+				}
+				e.Name = newName
+				entries[id] = e
+				taken[newName] = true
+				detail += "; renamed in trash to " + newName
+			}
+			d.report(ProblemTrashCollision, name, detail, nil)
+		}
+		return nil
+	}
+	if d.fix {
+		return data.WithTrashLock(examine)
+	}
+	entries, err := data.ReadTrash()
+	if err != nil {
+		return fmt.Errorf("reading trash: %w", err)
+	}
+	return examine(entries)
+}
+
+func noteFileExists(noteDir, name string) bool {
+	_, err := os.Stat(data.NotePath(noteDir, name))
+	return err == nil
+}
+
+// sameSet reports whether a and b hold the same strings, ignoring order
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return reflect.DeepEqual(a, b)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gote/src/data"
+)
+
+func problemKinds(problems []Problem) map[string][]string {
+	kinds := make(map[string][]string)
+	for _, p := range problems {
+		kinds[p.Kind] = append(kinds[p.Kind], p.Subject)
+	}
+	return kinds
+}
+
+func TestDoctor(t *testing.T) {
+	goteDir, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	os.MkdirAll(filepath.Join(notesDir, "work"), 0755)
+	os.WriteFile(filepath.Join(notesDir, "alpha.md"), []byte(".project\nAlpha"), 0644)
+	os.WriteFile(filepath.Join(notesDir, "work", "beta.md"), []byte("Beta"), 0644)
+	os.WriteFile(filepath.Join(notesDir, "gamma.md"), []byte("Gamma"), 0644)
+	if err := data.IndexNotes(notesDir); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("clean vault", func(t *testing.T) {
+		problems, err := Doctor(false)
+		if err != nil {
+			t.Fatalf("Doctor failed: %v", err)
+		}
+		if len(problems) != 0 {
+			t.Errorf("expected no problems, got %+v", problems)
+		}
+	})
+
+	// Drift: gamma deleted behind gote's back, a new file, a ghost FTS entry,
+	// stale tags, pins for a moved and a deleted note, and a trash collision
+	os.Remove(filepath.Join(notesDir, "gamma.md"))
+	os.WriteFile(filepath.Join(notesDir, "delta.md"), []byte("Delta"), 0644)
+	data.IndexDocFTS("ghost", filepath.Join(notesDir, "ghost.md"), "boo")
+	data.AtomicWriteJSON(data.TagsPath(), map[string]data.TagMeta{
+		"old": {Tag: "old", Notes: []string{"x"}, Count: 1},
+	})
+	data.SavePins(map[string]data.EmptyStruct{"beta": {}, "gone": {}, "alpha": {}})
+	os.MkdirAll(data.TrashPath(), 0755)
+	os.WriteFile(filepath.Join(data.TrashPath(), "alpha.md"), []byte("old alpha"), 0644)
+
+	t.Run("reports without changing anything", func(t *testing.T) {
+		before, _ := os.ReadFile(data.IndexPath())
+		problems, err := Doctor(false)
+		if err != nil {
+			t.Fatalf("Doctor failed: %v", err)
+		}
+		kinds := problemKinds(problems)
+		checks := map[string][]string{
+			ProblemMissingFile:    {"gamma"},
+			ProblemUnindexed:      {"delta"},
+			ProblemFTSOrphan:      {"ghost"},
+			ProblemStaleTags:      {"old", "project"},
+			ProblemDanglingPin:    {"beta", "gone"},
+			ProblemTrashCollision: {"alpha"},
+			ProblemTrashManifest:  {"alpha"},
+		}
+		for kind, want := range checks {
+			if !sameSet(kinds[kind], want) {
+				t.Errorf("%s = %v, want %v", kind, kinds[kind], want)
+			}
+		}
+		for _, p := range problems {
+			if p.Fixed {
+				t.Errorf("report-only run marked %+v fixed", p)
+			}
+		}
+		after, _ := os.ReadFile(data.IndexPath())
+		if string(before) != string(after) {
+			t.Error("report-only run modified the index")
+		}
+	})
+
+	t.Run("fix repairs everything", func(t *testing.T) {
+		problems, err := Doctor(true)
+		if err != nil {
+			t.Fatalf("Doctor --fix failed: %v", err)
+		}
+		for _, p := range problems {
+			if !p.Fixed {
+				t.Errorf("not fixed: %+v", p)
+			}
+		}
+
+		index, _ := data.LoadIndex()
+		if _, ok := index["gamma"]; ok {
+			t.Error("gamma still indexed")
+		}
+		if _, ok := index["delta"]; !ok {
+			t.Error("delta not indexed")
+		}
+		pins, _ := data.LoadPins()
+		if _, ok := pins["work/beta"]; !ok || len(pins) != 2 {
+			t.Errorf("pins = %v, want alpha and work/beta", pins)
+		}
+		if trashed, _ := data.ListTrashedNotes(); len(trashed) != 1 || trashed[0] != "alpha-2" {
+			t.Errorf("trashed alpha not renamed: %v", trashed)
+		}
+
+		problems, err = Doctor(false)
+		if err != nil {
+			t.Fatalf("Doctor failed: %v", err)
+		}
+		if len(problems) != 0 {
+			t.Errorf("problems remain after fix: %+v", problems)
+		}
+	})
+
+	t.Run("corrupt config is reported, never reset", func(t *testing.T) {
+		raw, _ := os.ReadFile(filepath.Join(goteDir, "config.json"))
+		os.WriteFile(filepath.Join(goteDir, "config.json"), []byte("{broken"), 0644)
+		os.WriteFile(data.FTSPath(), []byte("[1, 2"), 0644)
+
+		problems, err := Doctor(true)
+		if err != nil {
+			t.Fatalf("Doctor --fix failed: %v", err)
+		}
+		if len(problems) != 1 || problems[0].Subject != "config.json" || problems[0].Fixed {
+			t.Errorf("problems = %+v, want only an unfixed config.json", problems)
+		}
+		if now, _ := os.ReadFile(filepath.Join(goteDir, "config.json")); string(now) != "{broken" {
+			t.Errorf("config.json changed to %q", now)
+		}
+		if matches, _ := filepath.Glob(filepath.Join(goteDir, "*.corrupt-*")); len(matches) != 0 {
+			t.Errorf("nothing should be fixed with the config damaged, moved %v", matches)
+		}
+		os.WriteFile(filepath.Join(goteDir, "config.json"), raw, 0644)
+	})
+
+	t.Run("corrupt files are moved aside and rebuilt", func(t *testing.T) {
+		os.WriteFile(data.IndexPath(), []byte("{not json"), 0644)
+		os.WriteFile(data.FTSPath(), []byte("[1, 2"), 0644)
+
+		problems, _ := Doctor(false)
+		if kinds := problemKinds(problems); !sameSet(kinds[ProblemCorruptFile], []string{"index.json", "fts.json"}) {
+			t.Errorf("corrupt files = %v", kinds[ProblemCorruptFile])
+		}
+
+		if _, err := Doctor(true); err != nil {
+			t.Fatalf("Doctor --fix failed: %v", err)
+		}
+		matches, _ := filepath.Glob(filepath.Join(goteDir, "index.json.corrupt-*"))
+		if len(matches) != 1 {
+			t.Errorf("expected quarantined index, got %v", matches)
+		}
+		index, _ := data.LoadIndex()
+		if len(index) != 3 {
+			t.Errorf("rebuilt index has %d notes, want 3", len(index))
+		}
+		results, _ := SearchNotesFullText("delta", -1)
+		if len(results) != 1 {
+			t.Errorf("search index not rebuilt: %v", results)
+		}
+		if problems, _ := Doctor(false); len(problems) != 0 {
+			t.Errorf("problems remain after fix: %+v", problems)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gote/src/data"
+)
+
+// NoteFilter chooses the notes an export includes. Every set field must
+// match; an empty filter matches every note.
+type NoteFilter struct {
+	Tags       []string // notes with all of these tags, or tags below them unless Exact
+	Exact      bool
+	Properties []string // "key=value", or "key" for any value
+	Notebook   string
+	Query      string   // search query, as for gote search
+	Dates      []string // created (or modified, with ByModified) in this range
+	ByModified bool
+}
+
+// Empty reports whether the filter matches every note
+func (f NoteFilter) Empty() bool {
+	return len(f.Tags) == 0 && len(f.Properties) == 0 && strings.Trim(f.Notebook, "/") == "" &&
+		strings.TrimSpace(f.Query) == "" && len(f.Dates) == 0
+}
+
+// Matches reports whether the note name with meta passes the tag, property
+// and notebook filters. The query and dates need SelectNotes.
+func (f NoteFilter) Matches(name string, meta data.NoteMeta) bool {
+	if !data.InNotebook(name, f.Notebook) {
+		return false
+	}
+	for _, filter := range f.Tags {
+		if !slices.ContainsFunc(meta.Tags, func(tag string) bool { return data.TagMatches(tag, filter, f.Exact) }) {
+			return false
+		}
+	}
+	for _, prop := range f.Properties {
+		key, want, _ := strings.Cut(prop, "=")
+		value, ok := meta.Properties[strings.ToLower(strings.TrimSpace(key))]
+		if !ok || !propertyMatches(value, strings.TrimSpace(want)) {
+			return false
+		}
+	}
+	return true
+}
+
+// SelectNotes returns the indexed notes that pass filter
+func SelectNotes(filter NoteFilter) (map[string]data.NoteMeta, error) {
+	index, err := data.LoadIndex()
+	if err != nil {
+		return nil, fmt.Errorf("error loading index: %w", err)
+	}
+
+	// The query and date range narrow the notes further
+	var within []map[string]bool
+	if strings.TrimSpace(filter.Query) != "" {
+		results, err := SearchQuery(filter.Query, -1)
+		if err != nil {
+			return nil, err
+		}
+		within = append(within, resultTitles(results))
+	}
+	if len(filter.Dates) > 0 {
+		results, err := SearchNotesByDate(filter.Dates, !filter.ByModified, -1)
+		if err != nil {
+			return nil, err
+		}
+		within = append(within, resultTitles(results))
+	}
+
+	selected := make(map[string]data.NoteMeta)
+	for name, meta := range index {
+		if !filter.Matches(name, meta) {
+			continue
+		}
+		if !slices.ContainsFunc(within, func(titles map[string]bool) bool { return !titles[name] }) {
+			selected[name] = meta
+		}
+	}
+	return selected, nil
+}
+
+func resultTitles(results []SearchResult) map[string]bool {
+	titles := make(map[string]bool, len(results))
+	for _, r := range results {
+		titles[r.Title] = true
+	}
+	return titles
+}
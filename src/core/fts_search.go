@@ -23,63 +23,58 @@ func SearchNotesFullText(query string, limit int) ([]SearchResult, error) {
 	if err != nil {
 		return nil, err
 	}
-	if len(idx) == 0 {
+	if len(idx.Docs) == 0 {
 		return nil, nil
 	}
 
-	// Compute average document length
-	var totalLen int
-	for _, doc := range idx {
-		totalLen += doc.Length
-	}
-	avgdl := float64(totalLen) / float64(len(idx))
-	N := float64(len(idx))
-
-	// Count document frequency for each query term
-	df := make(map[string]int)
-	for _, term := range queryTerms {
-		for _, doc := range idx {
-			if doc.Terms[term] > 0 {
-				df[term]++
+	avgdl := idx.AvgLength()
+	N := float64(len(idx.Docs))
+
+	// Accumulate scores from the postings of each query term. Terms not in
+	// the index are expanded to similar indexed terms at reduced weight.
+	scores := make(map[string]float64)
+	for _, queryTerm := range queryTerms {
+		expansions, weight := []string{queryTerm}, 1.0
+		if idx.DocFreq(queryTerm) == 0 {
+			expansions, weight = idx.SimilarTerms(queryTerm), fuzzyTermWeight
+		}
+		for _, term := range expansions {
+			postings := idx.Postings[term]
+			// IDF: log((N - df + 0.5) / (df + 0.5) + 1)
+			dfVal := float64(len(postings))
+			idf := math.Log((N-dfVal+0.5)/(dfVal+0.5) + 1)
+			for title, p := range postings {
+				// BM25 term score
+				tf := float64(p.TF)
+				dl := float64(idx.Docs[title].Length)
+				num := tf * (bm25K1 + 1)
+				denom := tf + bm25K1*(1-bm25B+bm25B*dl/avgdl)
+				scores[title] += idf * num / denom * weight
 			}
 		}
 	}
 
-	// Score each document
 	type scored struct {
 		title    string
 		filePath string
 		score    float64
 	}
 	var results []scored
-
-	for _, doc := range idx {
-		var score float64
-		for _, term := range queryTerms {
-			tf := float64(doc.Terms[term])
-			if tf == 0 {
-				continue
-			}
-			// IDF: log((N - df + 0.5) / (df + 0.5) + 1)
-			dfVal := float64(df[term])
-			idf := math.Log((N-dfVal+0.5)/(dfVal+0.5) + 1)
-			// BM25 term score
-			dl := float64(doc.Length)
-			num := tf * (bm25K1 + 1)
-			denom := tf + bm25K1*(1-bm25B+bm25B*dl/avgdl)
-			score += idf * num / denom
-		}
+	for title, score := range scores {
 		if score > 0 {
 			results = append(results, scored{
-				title:    doc.Title,
-				filePath: doc.FilePath,
+				title:    title,
+				filePath: idx.Docs[title].FilePath,
 				score:    score,
 			})
 		}
 	}
 
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].score > results[j].score
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].title < results[j].title
 	})
 
 	if limit > 0 && limit < len(results) {
@@ -168,7 +168,7 @@ func TestFTSIndexSync(t *testing.T) {
 
 	// Verify it's indexed
 	idx, _ := data.LoadFTS()
-	if _, ok := idx["sync-test"]; !ok {
+	if _, ok := idx.Docs["sync-test"]; !ok {
 		t.Fatal("expected sync-test in FTS index")
 	}
 
@@ -180,7 +180,7 @@ func TestFTSIndexSync(t *testing.T) {
 
 	// Verify it's gone
 	idx, _ = data.LoadFTS()
-	if _, ok := idx["sync-test"]; ok {
+	if _, ok := idx.Docs["sync-test"]; ok {
 		t.Error("sync-test should be removed from FTS index")
 	}
 }
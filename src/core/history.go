@@ -0,0 +1,159 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gote/src/data"
+)
+
+// HistoryEntry describes one saved version of a note
+type HistoryEntry struct {
+	Version   int // stays the same as older versions are pruned
+	Timestamp string
+	Words     int
+	Delta     int // word count change from the previous version
+}
+
+// snapshotNote records the note's current content in its history, then
+// applies the configured retention policy
+func snapshotNote(noteName, notePath string) error {
+	content, err := os.ReadFile(notePath)
+	if err != nil {
+		return fmt.Errorf("error reading note for history: %w", err)
+	}
+	saved, err := data.SaveSnapshot(noteName, content)
+	if err != nil || !saved {
+		return err
+	}
+
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	maxAge := time.Duration(cfg.HistoryDays) * 24 * time.Hour
+	return data.PruneHistory(noteName, cfg.HistoryLimit(), maxAge)
+}
+
+// ListHistory returns a note's saved versions, oldest first
+func ListHistory(noteName string) ([]HistoryEntry, error) {
+	actualName, _, err := lookupNoteForHistory(noteName)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := data.ListSnapshots(actualName)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []HistoryEntry
+	prevWords := 0
+	for _, s := range snapshots {
+		content, err := os.ReadFile(s.Path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading version %d: %w", s.Seq, err)
+		}
+		words := len(strings.Fields(string(content)))
+		entries = append(entries, HistoryEntry{
+			Version:   s.Seq,
+			Timestamp: s.Timestamp.Format(timeFmt),
+			Words:     words,
+			Delta:     words - prevWords,
+		})
+		prevWords = words
+	}
+	return entries, nil
+}
+
+// DiffHistory returns a unified diff from version v1 to version v2.
+// A v2 of 0 compares against the note's current content.
+func DiffHistory(noteName string, v1, v2 int) (string, error) {
+	actualName, meta, err := lookupNoteForHistory(noteName)
+	if err != nil {
+		return "", err
+	}
+
+	from, err := readVersion(actualName, v1)
+	if err != nil {
+		return "", err
+	}
+	toName := "current"
+	var to []byte
+	if v2 == 0 {
+		to, err = os.ReadFile(meta.FilePath)
+		if err != nil {
+			return "", fmt.Errorf("error reading note: %w", err)
+		}
+	} else {
+		toName = fmt.Sprintf("v%d", v2)
+		to, err = readVersion(actualName, v2)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return UnifiedDiff(
+		fmt.Sprintf("%s (v%d)", actualName, v1),
+		fmt.Sprintf("%s (%s)", actualName, toName),
+		string(from), string(to),
+	), nil
+}
+
+// RestoreVersion replaces a note's content with version v. The current
+// content is snapshotted first, so a restore can itself be undone.
+func RestoreVersion(noteName string, v int) error {
+	actualName, meta, err := lookupNoteForHistory(noteName)
+	if err != nil {
+		return err
+	}
+	content, err := readVersion(actualName, v)
+	if err != nil {
+		return err
+	}
+
+	if err := snapshotNote(actualName, meta.FilePath); err != nil {
+		return err
+	}
+	if err := os.WriteFile(meta.FilePath, content, 0644); err != nil {
+		return fmt.Errorf("error restoring note: %w", err)
+	}
+	if err := snapshotNote(actualName, meta.FilePath); err != nil {
+		return err
+	}
+	return data.IndexNote(meta.FilePath)
+}
+
+func lookupNoteForHistory(noteName string) (string, data.NoteMeta, error) {
+	index, err := data.LoadIndex()
+	if err != nil {
+		return "", data.NoteMeta{}, fmt.Errorf("loading index: %w", err)
+	}
+	actualName, meta, exists := data.LookupNote(index, noteName)
+	if !exists {
+		return "", data.NoteMeta{}, fmt.Errorf("note not found: %s", noteName)
+	}
+	return actualName, meta, nil
+}
+
+func readVersion(noteName string, v int) ([]byte, error) {
+	snapshots, err := data.ListSnapshots(noteName)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range snapshots {
+		if s.Seq != v {
+			continue
+		}
+		content, err := os.ReadFile(s.Path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading version %d: %w", v, err)
+		}
+		return content, nil
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("version %d not found for %s (no history)", v, noteName)
+	}
+	return nil, fmt.Errorf("version %d not found for %s (have v%d to v%d)", v, noteName, snapshots[0].Seq, snapshots[len(snapshots)-1].Seq)
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gote/src/data"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Run("equal input has no diff", func(t *testing.T) {
+		if d := UnifiedDiff("a", "b", "x\ny\n", "x\ny\n"); d != "" {
+			t.Errorf("expected empty diff, got %q", d)
+		}
+	})
+
+	t.Run("single change", func(t *testing.T) {
+		got := UnifiedDiff("a", "b", "one\ntwo\nthree\n", "one\n2\nthree\n")
+		want := "--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n"
+		if got != want {
+			t.Errorf("UnifiedDiff =\n%s\nwant\n%s", got, want)
+		}
+	})
+
+	t.Run("distant changes split into hunks", func(t *testing.T) {
+		var a, b []string
+		for i := 0; i < 20; i++ {
+			a = append(a, "line")
+			b = append(b, "line")
+		}
+		a[0], b[0] = "start", "START"
+		a[19], b[19] = "end", "END"
+		got := UnifiedDiff("a", "b", strings.Join(a, "\n"), strings.Join(b, "\n"))
+		if strings.Count(got, "@@ -") != 2 {
+			t.Errorf("expected 2 hunks, got:\n%s", got)
+		}
+		if !strings.Contains(got, "@@ -17,4 +17,4 @@") {
+			t.Errorf("unexpected second hunk header:\n%s", got)
+		}
+	})
+
+	t.Run("insertion into empty", func(t *testing.T) {
+		got := UnifiedDiff("a", "b", "", "new\n")
+		if !strings.Contains(got, "@@ -0,0 +1,1 @@\n+new\n") {
+			t.Errorf("unexpected diff:\n%s", got)
+		}
+	})
+}
+
+func TestHistory(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	createTestNote(t, notesDir, "journal", "first draft")
+	notePath := filepath.Join(notesDir, "journal.md")
+
+	// Simulate two editing sessions
+	snapshotNote("journal", notePath)
+	os.WriteFile(notePath, []byte("first draft with more words"), 0644)
+	snapshotNote("journal", notePath)
+
+	t.Run("ListHistory reports word deltas", func(t *testing.T) {
+		entries, err := ListHistory("journal")
+		if err != nil {
+			t.Fatalf("ListHistory failed: %v", err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("expected 2 versions, got %d", len(entries))
+		}
+		if entries[1].Delta != 3 {
+			t.Errorf("v2 delta = %d, want 3", entries[1].Delta)
+		}
+	})
+
+	t.Run("DiffHistory between versions", func(t *testing.T) {
+		diff, err := DiffHistory("journal", 1, 2)
+		if err != nil {
+			t.Fatalf("DiffHistory failed: %v", err)
+		}
+		if !strings.Contains(diff, "-first draft\n+first draft with more words") {
+			t.Errorf("unexpected diff:\n%s", diff)
+		}
+	})
+
+	t.Run("DiffHistory rejects unknown version", func(t *testing.T) {
+		if _, err := DiffHistory("journal", 9, 0); err == nil {
+			t.Error("expected error for missing version")
+		}
+	})
+
+	t.Run("RestoreVersion", func(t *testing.T) {
+		if err := RestoreVersion("journal", 1); err != nil {
+			t.Fatalf("RestoreVersion failed: %v", err)
+		}
+		content, _ := os.ReadFile(notePath)
+		if string(content) != "first draft" {
+			t.Errorf("content = %q, want \"first draft\"", content)
+		}
+		entries, _ := ListHistory("journal")
+		if len(entries) != 3 {
+			t.Errorf("restore should add a version, got %d", len(entries))
+		}
+	})
+
+	t.Run("history follows rename", func(t *testing.T) {
+		if err := RenameNote("journal", "diary"); err != nil {
+			t.Fatalf("RenameNote failed: %v", err)
+		}
+		entries, err := ListHistory("diary")
+		if err != nil {
+			t.Fatalf("ListHistory failed: %v", err)
+		}
+		if len(entries) != 3 {
+			t.Errorf("expected 3 versions after rename, got %d", len(entries))
+		}
+		if snaps, _ := data.ListSnapshots("journal"); len(snaps) != 0 {
+			t.Error("old history should be moved")
+		}
+	})
+
+	t.Run("version numbers survive pruning", func(t *testing.T) {
+		if err := data.PruneHistory("diary", 2, 0); err != nil {
+			t.Fatal(err)
+		}
+		entries, _ := ListHistory("diary")
+		if len(entries) != 2 || entries[0].Version != 2 || entries[1].Version != 3 {
+			t.Fatalf("entries after pruning = %+v, want v2 and v3", entries)
+		}
+		if _, err := DiffHistory("diary", 1, 0); err == nil {
+			t.Error("pruned v1 should be gone, not renamed")
+		}
+		diff, err := DiffHistory("diary", 2, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(diff, "-first draft with more words\n+first draft") {
+			t.Errorf("v2 should still be the longer draft:\n%s", diff)
+		}
+	})
+
+	t.Run("rewriting links on rename keeps versions", func(t *testing.T) {
+		createTestNote(t, notesDir, "ref", "see [[diary]]")
+		data.IndexNotes(notesDir)
+		if err := RenameNote("diary", "log"); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := ListHistory("ref")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("expected versions before and after the rewrite, got %d", len(entries))
+		}
+		diff, _ := DiffHistory("ref", entries[0].Version, entries[1].Version)
+		if !strings.Contains(diff, "-see [[diary]]\n+see [[log]]") {
+			t.Errorf("unexpected diff:\n%s", diff)
+		}
+	})
+}
@@ -0,0 +1,287 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gote/src/data"
+)
+
+// ExportMetadataFile is the archive entry a selective export stores the
+// exported notes' index entries in
+const ExportMetadataFile = "metadata.json"
+
+// What MergeImport does with an archived note whose name is taken by a
+// different note
+const (
+	ConflictAsk       = "ask"
+	ConflictSkip      = "skip"
+	ConflictRename    = "rename"    // import it as "name (imported)"
+	ConflictOverwrite = "overwrite" // replace it, keeping the old text in history
+)
+
+// ConflictFunc chooses ConflictSkip, ConflictRename or ConflictOverwrite for
+// the note name, when MergeImport's policy is ConflictAsk
+type ConflictFunc func(name string) (string, error)
+
+// ImportResult lists what MergeImport did with each note in the archive
+type ImportResult struct {
+	Added       []string
+	Renamed     []string // "name -> new name"
+	Overwritten []string
+	Skipped     []string // name conflicts left alone
+	Unchanged   []string // already in the vault with the same content
+	Duplicates  []string // same content as a note with another name
+	Attachments []string // other files copied in
+	Problems    []string // what could not be converted, as "note: problem"
+}
+
+// Imported is the number of notes written to the vault
+func (r ImportResult) Imported() int {
+	return len(r.Added) + len(r.Renamed) + len(r.Overwritten)
+}
+
+// MergeImport adds the notes in a gote export archive to the vault, leaving
+// the vault's own data files alone. Notes already present byte for byte, by
+// name or under another name, are left out; a different note with the same
+// name is handled per policy. The vault is then reindexed from the notes on
+// disk, keeping the created dates the archive recorded for new notes.
+func MergeImport(archive io.Reader, policy string, ask ConflictFunc) (ImportResult, error) {
+	m, err := newNoteMerge(policy, ask)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	gr, err := gzip.NewReader(archive)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("not a valid gzip archive: %w", err)
+	}
+	defer gr.Close()
+
+	failed := m.readArchive(tar.NewReader(gr))
+	return m.finish(failed)
+}
+
+// noteMerge adds notes from another vault or app to this one
+type noteMerge struct {
+	noteDir    string
+	policy     string
+	ask        ConflictFunc
+	hashes     map[[sha256.Size]byte]string // content hash -> note name
+	sourceMeta map[string]data.NoteMeta     // dates from the source, by its note names
+	importedAs map[string]string            // note written -> its name in the source
+	result     ImportResult
+}
+
+func newNoteMerge(policy string, ask ConflictFunc) (*noteMerge, error) {
+	switch policy {
+	case ConflictAsk, ConflictSkip, ConflictRename, ConflictOverwrite:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q (use skip, rename or overwrite)", policy)
+	}
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+	if err := os.MkdirAll(cfg.NoteDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating notes directory: %w", err)
+	}
+	hashes, err := noteHashes(cfg.NoteDir)
+	if err != nil {
+		return nil, err
+	}
+	return &noteMerge{
+		noteDir:    cfg.NoteDir,
+		policy:     policy,
+		ask:        ask,
+		hashes:     hashes,
+		sourceMeta: make(map[string]data.NoteMeta),
+		importedAs: make(map[string]string),
+	}, nil
+}
+
+// readArchive adds the notes in a gote export
+func (m *noteMerge) readArchive(tr *tar.Reader) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// The archive's index only supplies created dates; the vault is reindexed
+		if hdr.Name == ExportMetadataFile || hdr.Name == "gote/index.json" {
+			var entries map[string]data.NoteMeta
+			if err := json.NewDecoder(tr).Decode(&entries); err == nil {
+				for name, meta := range entries {
+					m.sourceMeta[name] = meta
+				}
+			}
+			continue
+		}
+		rel, ok := strings.CutPrefix(hdr.Name, "notes/")
+		if !ok || !strings.HasSuffix(rel, ".md") {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", hdr.Name, err)
+		}
+		if _, err := m.add(strings.TrimSuffix(rel, ".md"), content, hdr.ModTime); err != nil {
+			return err
+		}
+	}
+}
+
+// add writes the note the source calls name, unless the vault already has
+// it or the conflict policy says to skip it. It returns the name the note
+// was written under, or "" if it wasn't.
+func (m *noteMerge) add(name string, content []byte, modTime time.Time) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("unsafe note name: %s", name)
+	}
+	sum := sha256.Sum256(content)
+	dest := name
+	existing, err := os.ReadFile(data.NotePath(m.noteDir, name))
+	switch {
+	case err == nil && bytes.Equal(existing, content):
+		m.result.Unchanged = append(m.result.Unchanged, name)
+		return "", nil
+	case err == nil:
+		// Two source notes with the same name are both kept
+		choice := ConflictRename
+		if _, ours := m.importedAs[name]; !ours {
+			choice = m.policy
+		}
+		if choice == ConflictAsk {
+			if choice, err = m.ask(name); err != nil {
+				return "", err
+			}
+		}
+		switch choice {
+		case ConflictRename:
+			dest = importedName(m.noteDir, name)
+			m.result.Renamed = append(m.result.Renamed, name+" -> "+dest)
+		case ConflictOverwrite:
+			if err := snapshotNote(name, data.NotePath(m.noteDir, name)); err != nil {
+				return "", err
+			}
+			m.result.Overwritten = append(m.result.Overwritten, name)
+		default:
+			m.result.Skipped = append(m.result.Skipped, name)
+			return "", nil
+		}
+	case !os.IsNotExist(err):
+		return "", fmt.Errorf("error reading %s: %w", name, err)
+	case len(content) > 0 && m.hashes[sum] != "":
+		m.result.Duplicates = append(m.result.Duplicates, name+" (same as "+m.hashes[sum]+")")
+		return "", nil
+	default:
+		m.result.Added = append(m.result.Added, name)
+	}
+
+	notePath := data.NotePath(m.noteDir, dest)
+	if err := os.MkdirAll(filepath.Dir(notePath), 0755); err != nil {
+		return "", fmt.Errorf("error creating notebook: %w", err)
+	}
+	if err := os.WriteFile(notePath, content, 0644); err != nil {
+		return "", fmt.Errorf("error writing %s: %w", dest, err)
+	}
+	if !modTime.IsZero() {
+		os.Chtimes(notePath, modTime, modTime)
+	}
+	if len(content) > 0 {
+		m.hashes[sum] = dest
+	}
+	m.importedAs[dest] = name
+	return dest, nil
+}
+
+// finish reindexes the vault once the notes are written, even if failed
+// stopped the import part way, and dates the new notes as the source did
+func (m *noteMerge) finish(failed error) (ImportResult, error) {
+	if failed != nil && m.result.Imported() == 0 {
+		return m.result, failed
+	}
+	if err := data.IndexNotes(m.noteDir); err != nil {
+		return m.result, fmt.Errorf("error reindexing: %w", err)
+	}
+	if len(m.sourceMeta) == 0 || len(m.importedAs) == 0 {
+		return m.result, failed
+	}
+	err := data.WithIndexLock(func(index map[string]data.NoteMeta) error {
+		for name, sourceName := range m.importedAs {
+			meta, ok := index[name]
+			source, found := m.sourceMeta[sourceName]
+			// An overwritten note keeps the vault's dates
+			if !ok || !found || slices.Contains(m.result.Overwritten, name) {
+				continue
+			}
+			if source.Created != "" {
+				meta.Created = source.Created
+			}
+			if source.LastVisited != "" {
+				meta.LastVisited = source.LastVisited
+			}
+			index[name] = meta
+		}
+		return nil
+	})
+	if err != nil {
+		return m.result, err
+	}
+	return m.result, failed
+}
+
+// noteHashes maps the SHA-256 of every non-empty note in noteDir to its name
+func noteHashes(noteDir string) (map[[sha256.Size]byte]string, error) {
+	hashes := make(map[[sha256.Size]byte]string)
+	err := filepath.Walk(noteDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(p) != ".md" || info.Size() == 0 {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		hashes[sha256.Sum256(content)] = data.NoteName(noteDir, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading notes: %w", err)
+	}
+	return hashes, nil
+}
+
+// importedName finds a free name for an imported note that clashes with
+// name: "plan (imported)", then "plan (imported 2)" and so on
+func importedName(noteDir, name string) string {
+	for i := 1; ; i++ {
+		suffix := " (imported)"
+		if i > 1 {
+			suffix = fmt.Sprintf(" (imported %d)", i)
+		}
+		candidate := path.Join(data.NotebookOf(name), data.BaseName(name)+suffix)
+		if _, err := os.Stat(data.NotePath(noteDir, candidate)); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
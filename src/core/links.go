@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gote/src/data"
+)
+
+// GetLinks returns the name of the note noteName resolves to, which may be
+// the closest title, and the notes it links to. Links to notes that don't
+// exist are skipped.
+func GetLinks(noteName string) (string, []SearchResult, error) {
+	index, err := data.LoadIndex()
+	if err != nil {
+		return "", nil, fmt.Errorf("loading index: %w", err)
+	}
+	actualName, meta, exists := data.LookupNoteFuzzy(index, noteName)
+	if !exists {
+		return "", nil, fmt.Errorf("note not found: %s", noteName)
+	}
+
+	var results []SearchResult
+	for _, target := range meta.Links {
+		key, targetMeta, ok := data.LookupNote(index, target)
+		if !ok {
+			continue
+		}
+		results = append(results, SearchResult{
+			Title:    key,
+			FilePath: targetMeta.FilePath,
+			Score:    1,
+			Created:  targetMeta.Created,
+		})
+	}
+	return actualName, results, nil
+}
+
+// GetBacklinks returns the name of the note noteName resolves to, as for
+// GetLinks, and the notes that link to it, newest first
+func GetBacklinks(noteName string) (string, []SearchResult, error) {
+	index, err := data.LoadIndex()
+	if err != nil {
+		return "", nil, fmt.Errorf("loading index: %w", err)
+	}
+	actualName, _, exists := data.LookupNoteFuzzy(index, noteName)
+	if !exists {
+		return "", nil, fmt.Errorf("note not found: %s", noteName)
+	}
+
+	links, err := data.LoadLinks()
+	if err != nil {
+		return "", nil, fmt.Errorf("loading links: %w", err)
+	}
+
+	var results []SearchResult
+	for _, source := range links[actualName].Backlinks {
+		meta, ok := index[source]
+		if !ok {
+			continue
+		}
+		results = append(results, SearchResult{
+			Title:    source,
+			FilePath: meta.FilePath,
+			Score:    1,
+			Created:  meta.Created,
+		})
+	}
+
+	sortResultsByCreated(results)
+	return actualName, results, nil
+}
+
+// rewriteLinksTo updates every note in index that links to oldName so it
+// links to newName instead. Must be called with the index lock held.
+func rewriteLinksTo(index map[string]data.NoteMeta, oldName, newName string) error {
+	for key, meta := range index {
+		if !linksTo(meta, oldName) {
+			continue
+		}
+		content, err := os.ReadFile(meta.FilePath)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", key, err)
+		}
+		updated := data.RewriteLinks(string(content), key, oldName, newName)
+		if updated == string(content) {
+			continue
+		}
+		// Keep a version from before and after, as an edit would
+		if err := snapshotNote(key, meta.FilePath); err != nil {
+			return err
+		}
+		if err := os.WriteFile(meta.FilePath, []byte(updated), 0644); err != nil {
+			return fmt.Errorf("error updating links in %s: %w", key, err)
+		}
+		if err := snapshotNote(key, meta.FilePath); err != nil {
+			return err
+		}
+
+		info, err := os.Stat(meta.FilePath)
+		if err != nil {
+			return fmt.Errorf("error stating %s: %w", key, err)
+		}
+		newMeta, err := data.BuildNoteMeta(meta.FilePath, info)
+		if err != nil {
+			return fmt.Errorf("error building metadata for %s: %w", key, err)
+		}
+		newMeta.Created = meta.Created
+		newMeta.LastVisited = meta.LastVisited
+		index[key] = newMeta
+
+		if err := data.IndexDocFTS(key, meta.FilePath, updated); err != nil {
+			return fmt.Errorf("warning: FTS index failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// linksTo reports whether meta may have an outgoing link to name, by its
+// full name or by its base name alone
+func linksTo(meta data.NoteMeta, name string) bool {
+	for _, link := range meta.Links {
+		if strings.EqualFold(link, name) || (!strings.Contains(link, "/") && strings.EqualFold(link, data.BaseName(name))) {
+			return true
+		}
+	}
+	return false
+}
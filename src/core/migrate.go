@@ -0,0 +1,320 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gote/src/data"
+)
+
+// The apps ImportFrom migrates notes from
+const (
+	SourceObsidian = "obsidian" // an Obsidian vault
+	SourceFolder   = "folder"   // a folder of .md and .txt files
+	SourceEnex     = "enex"     // an Evernote .enex export
+)
+
+// ImportFrom migrates the notes at src, a vault, folder or .enex file, into
+// the notebook of this vault ("" for the top level). Tags, dates, attachments
+// and links are carried over; notes already here or clashing with a note are
+// handled as MergeImport handles them. The result's Problems lists anything
+// that could not be converted.
+func ImportFrom(source, src, notebook, policy string, ask ConflictFunc) (ImportResult, error) {
+	notebook = strings.Trim(notebook, "/")
+	if notebook != "" {
+		if err := data.ValidateNotebookName(notebook); err != nil {
+			return ImportResult{}, err
+		}
+	}
+	m, err := newNoteMerge(policy, ask)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	var failed error
+	switch source {
+	case SourceObsidian, SourceFolder:
+		failed = m.readFolder(src, notebook, source == SourceObsidian)
+	case SourceEnex:
+		failed = m.readEnex(src, notebook)
+	default:
+		return ImportResult{}, fmt.Errorf("unknown import source %q (use obsidian, folder or enex)", source)
+	}
+	return m.finish(failed)
+}
+
+// readFolder adds the .md notes under root, and for a plain folder the .txt
+// ones too. Other files are copied as attachments, keeping their paths so
+// links to them still work. Hidden files and folders such as .obsidian are
+// left out.
+func (m *noteMerge) readFolder(root, notebook string, obsidian bool) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a folder", root)
+	}
+	absRoot, _ := filepath.Abs(root)
+	absNotes, _ := filepath.Abs(m.noteDir)
+
+	var notes []string
+	attachments := make(map[string]string) // lowercased file name -> path, for ![[embeds]]
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != root && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			// Importing a folder that holds the vault mustn't import the vault
+			if abs, _ := filepath.Abs(p); abs == absNotes && abs != absRoot {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		switch ext := strings.ToLower(path.Ext(rel)); {
+		case ext == ".md", ext == ".txt" && !obsidian:
+			notes = append(notes, rel)
+		default:
+			attachments[strings.ToLower(path.Base(rel))] = rel
+			if err := m.copyAttachment(p, path.Join(notebook, rel)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range notes {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		name := path.Join(notebook, strings.TrimSuffix(rel, path.Ext(rel)))
+		if err := data.ValidateNotePath(name); err != nil {
+			m.result.Problems = append(m.result.Problems, rel+": not imported: "+err.Error())
+			continue
+		}
+		if obsidian {
+			var problems []string
+			content, problems = convertObsidian(content, rel, attachments)
+			for _, problem := range problems {
+				m.result.Problems = append(m.result.Problems, name+": "+problem)
+			}
+		}
+		m.sourceMeta[name] = data.NoteMeta{Created: data.GetBirthtime(info).Format("060102.150405")}
+		if _, err := m.add(name, content, info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertObsidian turns an Obsidian note at rel into a gote one: its #tags
+// go on a .tag line and ![[embeds]] become Markdown images and links.
+// [[Wiki links]] work as they are.
+func convertObsidian(content []byte, rel string, attachments map[string]string) ([]byte, []string) {
+	var problems []string
+	text := data.ReplaceEmbeds(string(content), func(target string) string {
+		ext := strings.ToLower(path.Ext(target))
+		if ext == "" || ext == ".md" {
+			problems = append(problems, "embedded note "+target+" is now a link")
+			return "[[" + strings.TrimSuffix(target, path.Ext(target)) + "]]"
+		}
+		file, ok := attachments[strings.ToLower(path.Base(target))]
+		if !ok {
+			problems = append(problems, "missing attachment "+target)
+			return "![[" + target + "]]"
+		}
+		return attachmentLink(path.Base(target), path.Dir(rel), file)
+	})
+	return []byte(addTagLine(text, data.InlineTags(text))), problems
+}
+
+// attachmentLink is a Markdown link to file from a note in dir. Images are
+// shown inline.
+func attachmentLink(label, dir, file string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(dir), filepath.FromSlash(file))
+	if err != nil {
+		rel = file
+	}
+	link := "[" + label + "](<" + filepath.ToSlash(rel) + ">)"
+	switch strings.ToLower(path.Ext(file)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".bmp":
+		return "!" + link
+	}
+	return link
+}
+
+// addTagLine gives text a .tag line with tags, after any front matter,
+// or adds tags it lacks to the one it has
+func addTagLine(text string, tags []string) string {
+	if len(tags) == 0 {
+		return text
+	}
+	_, body := data.ParseFrontMatter(text)
+	head := text[:len(text)-len(body)]
+	first, rest, hasRest := strings.Cut(body, "\n")
+	existing := data.ParseTags(strings.TrimRight(first, "\r"))
+	line := strings.TrimRight(first, "\r")
+	if existing == nil {
+		line, rest, hasRest = "", body, true
+	}
+	for _, tag := range tags {
+		if !slices.Contains(existing, tag) {
+			line += "." + tag
+		}
+	}
+	if hasRest {
+		line += "\n"
+	}
+	return head + line + rest
+}
+
+// copyAttachment copies the file at src to name in the notes directory. A
+// different file already there is kept, and reported.
+func (m *noteMerge) copyAttachment(src, name string) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return m.writeAttachment(name, content)
+}
+
+func (m *noteMerge) writeAttachment(name string, content []byte) error {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return fmt.Errorf("unsafe attachment name: %s", name)
+	}
+	dest := filepath.Join(m.noteDir, filepath.FromSlash(name))
+	if existing, err := os.ReadFile(dest); err == nil {
+		if !bytes.Equal(existing, content) {
+			m.result.Problems = append(m.result.Problems, name+": a different file is already there; kept it")
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dest, content, 0644); err != nil {
+		return err
+	}
+	m.result.Attachments = append(m.result.Attachments, name)
+	return nil
+}
+
+// readEnex adds the notes in an Evernote export, converting their HTML to
+// Markdown. Attached files go in the notebook's attachments folder.
+func (m *noteMerge) readEnex(src, notebook string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return data.ReadEnex(f, func(note data.EnexNote) error {
+		name := path.Join(notebook, enexNoteName(note.Title))
+		var problems []string
+		links := make(map[string]string) // resource hash -> Markdown link
+		for _, res := range note.Resources {
+			file := path.Base(filepath.ToSlash(res.FileName))
+			if res.FileName == "" || strings.HasPrefix(file, ".") || file == "/" {
+				file = res.Hash + mimeExtension(res.Mime)
+			}
+			attachment := path.Join(notebook, "attachments", file)
+			// Different files of the same name are told apart by their hash
+			if existing, err := os.ReadFile(filepath.Join(m.noteDir, filepath.FromSlash(attachment))); err == nil && !bytes.Equal(existing, res.Data) {
+				attachment = path.Join(notebook, "attachments", res.Hash[:8]+"-"+file)
+			}
+			if err := m.writeAttachment(attachment, res.Data); err != nil {
+				problems = append(problems, "attachment "+file+" not saved: "+err.Error())
+				continue
+			}
+			links[res.Hash] = attachmentLink(file, notebook, attachment)
+		}
+
+		body, convertProblems := data.ENMLToMarkdown(note.Content, func(hash, mime string) string {
+			return links[hash]
+		})
+		problems = append(problems, convertProblems...)
+		for _, problem := range problems {
+			m.result.Problems = append(m.result.Problems, name+": "+problem)
+		}
+
+		var tags []string
+		for _, tag := range note.Tags {
+			// Evernote tags may hold spaces and periods, which .tag lines can't
+			tag = data.NormalizeTag(strings.Join(strings.FieldsFunc(tag, func(r rune) bool {
+				return r == ' ' || r == '.' || r == '#'
+			}), "-"))
+			if tag != "" && !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		if !note.Created.IsZero() {
+			m.sourceMeta[name] = data.NoteMeta{Created: note.Created.Local().Format("060102.150405")}
+		}
+		_, err := m.add(name, []byte(addTagLine(body, tags)), note.Updated)
+		return err
+	})
+}
+
+// enexNoteName makes an Evernote note title a valid note name
+func enexNoteName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', 0:
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	name = strings.ReplaceAll(name, "..", ".")
+	name = strings.TrimLeft(name, ".- ")
+	if name == "" {
+		return "Untitled"
+	}
+	return name
+}
+
+// mimeExtension guesses the extension of an attachment Evernote didn't name
+func mimeExtension(mime string) string {
+	switch mime {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "application/pdf":
+		return ".pdf"
+	case "audio/wav":
+		return ".wav"
+	case "audio/mpeg":
+		return ".mp3"
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gote/src/data"
+)
+
+// ListNotebooks returns all notebooks under the notes directory
+func ListNotebooks() ([]data.Notebook, error) {
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+	index, err := data.LoadIndex()
+	if err != nil {
+		return nil, fmt.Errorf("loading index: %w", err)
+	}
+	return data.ListNotebooks(cfg.NoteDir, index)
+}
+
+// CreateNotebook creates an empty notebook directory
+func CreateNotebook(name string) error {
+	if err := data.ValidateNotebookName(name); err != nil {
+		return err
+	}
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	dir := strings.TrimSuffix(data.NotePath(cfg.NoteDir, strings.Trim(name, "/")), ".md")
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return fmt.Errorf("notebook already exists: %s", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating notebook: %w", err)
+	}
+	return nil
+}
+
+// MoveNote moves a note into notebook, keeping its base name. An empty
+// notebook (or "/") moves it to the top level. Returns the new note name.
+func MoveNote(noteName, notebook string) (string, error) {
+	notebook = strings.Trim(notebook, "/")
+	if notebook != "" {
+		if err := data.ValidateNotebookName(notebook); err != nil {
+			return "", err
+		}
+	}
+	index, err := data.LoadIndex()
+	if err != nil {
+		return "", fmt.Errorf("loading index: %w", err)
+	}
+	actualName, _, exists := data.LookupNote(index, noteName)
+	if !exists {
+		return "", fmt.Errorf("note not found: %s", noteName)
+	}
+
+	newName := path.Join(notebook, data.BaseName(actualName))
+	if newName == actualName {
+		return "", fmt.Errorf("%s is already in that notebook", actualName)
+	}
+	if err := RenameNote(actualName, newName); err != nil {
+		return "", err
+	}
+	return newName, nil
+}
+
+// FilterByNotebook keeps the results inside notebook (including sub-notebooks).
+// An empty notebook keeps everything.
+func FilterByNotebook(results []SearchResult, notebook string) []SearchResult {
+	if strings.Trim(notebook, "/") == "" {
+		return results
+	}
+	var filtered []SearchResult
+	for _, r := range results {
+		if data.InNotebook(r.Title, notebook) {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered
+}
@@ -13,7 +13,7 @@ import (
 const timeFmt = "060102.150405"
 
 func CreateOrOpenNote(noteName string) error {
-	if err := data.ValidateNoteName(noteName); err != nil {
+	if err := data.ValidateNotePath(noteName); err != nil {
 		return err
 	}
 
@@ -35,7 +35,10 @@ func CreateOrOpenNote(noteName string) error {
 		}
 
 		if notePath == "" {
-			notePath = filepath.Join(noteDir, noteName+".md")
+			notePath = data.NotePath(noteDir, noteName)
+			if err := os.MkdirAll(filepath.Dir(notePath), 0755); err != nil {
+				return fmt.Errorf("error creating notebook: %w", err)
+			}
 			if _, err := os.Stat(notePath); os.IsNotExist(err) {
 				f, err := os.Create(notePath)
 				if err != nil {
@@ -45,10 +48,18 @@ func CreateOrOpenNote(noteName string) error {
 			}
 		}
 
+		if err := snapshotNote(actualName, notePath); err != nil {
+			return err
+		}
+
 		if err := data.OpenFileInEditor(notePath, cfg.Editor); err != nil {
 			return fmt.Errorf("error opening note in editor: %w", err)
 		}
 
+		if err := snapshotNote(actualName, notePath); err != nil {
+			return err
+		}
+
 		info, err := os.Stat(notePath)
 		if err != nil {
 			return fmt.Errorf("error stating note after edit: %w", err)
@@ -79,15 +90,29 @@ func UpdateLastVisited(title string) error {
 // OpenAndReindexNote opens a note in the editor and reindexes it afterward
 // This should be used when opening existing notes to ensure tags/metadata stay in sync
 func OpenAndReindexNote(filePath, title string) error {
+	return OpenAndReindexNoteAtLine(filePath, title, 0)
+}
+
+// OpenAndReindexNoteAtLine is OpenAndReindexNote with the cursor placed on a
+// 1-based line, e.g. the first search match. Line 0 opens at the editor's default.
+func OpenAndReindexNoteAtLine(filePath, title string, line int) error {
 	cfg, err := data.LoadConfig()
 	if err != nil {
 		return fmt.Errorf("error loading config: %w", err)
 	}
 
-	if err := data.OpenFileInEditor(filePath, cfg.Editor); err != nil {
+	if err := snapshotNote(title, filePath); err != nil {
+		return err
+	}
+
+	if err := data.OpenFileInEditorAtLine(filePath, cfg.Editor, line); err != nil {
 		return fmt.Errorf("error opening note: %w", err)
 	}
 
+	if err := snapshotNote(title, filePath); err != nil {
+		return err
+	}
+
 	// Reindex the note to pick up any changes (tags, content, etc.)
 	if err := data.IndexNote(filePath); err != nil {
 		return fmt.Errorf("error reindexing note: %w", err)
@@ -102,7 +127,7 @@ func GetNoteInfo(noteName string) (data.NoteMeta, error) {
 	if err != nil {
 		return data.NoteMeta{}, fmt.Errorf("loading index: %w", err)
 	}
-	_, meta, exists := data.LookupNote(index, noteName)
+	_, meta, exists := data.LookupNoteFuzzy(index, noteName)
 	if !exists {
 		return data.NoteMeta{}, fmt.Errorf("note not found: %s", noteName)
 	}
@@ -110,7 +135,7 @@ func GetNoteInfo(noteName string) (data.NoteMeta, error) {
 }
 
 func RenameNote(oldName, newName string) error {
-	if err := data.ValidateNoteName(newName); err != nil {
+	if err := data.ValidateNotePath(newName); err != nil {
 		return err
 	}
 
@@ -126,7 +151,7 @@ func RenameNote(oldName, newName string) error {
 		}
 
 		oldPath := meta.FilePath
-		newPath := filepath.Join(cfg.NoteDir, newName+".md")
+		newPath := data.NotePath(cfg.NoteDir, newName)
 		// Allow case-only renames (e.g., "rde" -> "RDE") on case-insensitive filesystems
 		if !strings.EqualFold(oldName, newName) {
 			if _, err := os.Stat(newPath); err == nil {
@@ -134,9 +159,20 @@ func RenameNote(oldName, newName string) error {
 			}
 		}
 
+		if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+			return fmt.Errorf("error creating notebook: %w", err)
+		}
 		if err := os.Rename(oldPath, newPath); err != nil {
 			return fmt.Errorf("error renaming note: %w", err)
 		}
+		// Relative [text](note.md) links start from the new notebook
+		if content, err := os.ReadFile(newPath); err == nil {
+			if rebased := data.RebaseLinks(string(content), actualOldName, newName); rebased != string(content) {
+				if err := os.WriteFile(newPath, []byte(rebased), 0644); err != nil {
+					return fmt.Errorf("error updating links in %s: %w", newName, err)
+				}
+			}
+		}
 
 		delete(index, actualOldName)
 		meta.Title = newName
@@ -154,6 +190,15 @@ func RenameNote(oldName, newName string) error {
 			}
 		}
 
+		if err := data.RenameHistory(actualOldName, newName); err != nil {
+			return fmt.Errorf("error moving note history: %w", err)
+		}
+
+		// Point [[links]] in other notes at the new name
+		if err := rewriteLinksTo(index, actualOldName, newName); err != nil {
+			return err
+		}
+
 		// Update pins inside the index lock to prevent inconsistency
 		return data.WithPinsLock(func(pins map[string]data.EmptyStruct) error {
 			if _, pinned := pins[actualOldName]; pinned {
@@ -167,7 +212,7 @@ func RenameNote(oldName, newName string) error {
 
 // DuplicateNote copies a note's content to a new note with the given name
 func DuplicateNote(oldName, newName string) error {
-	if err := data.ValidateNoteName(newName); err != nil {
+	if err := data.ValidateNotePath(newName); err != nil {
 		return err
 	}
 
@@ -182,10 +227,13 @@ func DuplicateNote(oldName, newName string) error {
 			return fmt.Errorf("note not found: %s", oldName)
 		}
 
-		newPath := filepath.Join(cfg.NoteDir, newName+".md")
+		newPath := data.NotePath(cfg.NoteDir, newName)
 		if _, err := os.Stat(newPath); err == nil {
 			return fmt.Errorf("a note with that name already exists: %s", newName)
 		}
+		if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+			return fmt.Errorf("error creating notebook: %w", err)
+		}
 
 		content, err := os.ReadFile(meta.FilePath)
 		if err != nil {
@@ -217,7 +265,7 @@ func DuplicateNote(oldName, newName string) error {
 
 // PromoteQuickNote moves content from quick.md to a new named note
 func PromoteQuickNote(newName string) error {
-	if err := data.ValidateNoteName(newName); err != nil {
+	if err := data.ValidateNotePath(newName); err != nil {
 		return err
 	}
 
@@ -227,12 +275,15 @@ func PromoteQuickNote(newName string) error {
 	}
 
 	quickPath := filepath.Join(cfg.NoteDir, "quick.md")
-	newPath := filepath.Join(cfg.NoteDir, newName+".md")
+	newPath := data.NotePath(cfg.NoteDir, newName)
 
 	// Check if target note already exists
 	if _, err := os.Stat(newPath); err == nil {
 		return fmt.Errorf("note already exists: %s", newName)
 	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return fmt.Errorf("error creating notebook: %w", err)
+	}
 
 	// Check quick.md exists
 	if _, err := os.Stat(quickPath); os.IsNotExist(err) {
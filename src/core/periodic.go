@@ -0,0 +1,176 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gote/src/data"
+)
+
+// Periods of the periodic (journal) notes
+const (
+	PeriodDay   = "daily"
+	PeriodWeek  = "weekly"
+	PeriodMonth = "monthly"
+)
+
+// periodOrder sorts longer periods first when they start on the same day
+var periodOrder = map[string]int{PeriodMonth: 0, PeriodWeek: 1, PeriodDay: 2}
+
+// builtinPeriodTemplates are used for a period with no template of its own
+var builtinPeriodTemplates = map[string]string{
+	PeriodDay:   "# {{date \"Monday, January 2, 2006\"}}\n\n",
+	PeriodWeek:  "# Week {{week}}\n\n",
+	PeriodMonth: "# {{date \"January 2006\"}}\n\n",
+}
+
+var (
+	dayNameRegex   = regexp.MustCompile(`^\d{6}$`)
+	weekNameRegex  = regexp.MustCompile(`^(\d{2})[Ww](\d{2})$`)
+	monthNameRegex = regexp.MustCompile(`^\d{4}$`)
+)
+
+// PeriodicNote is an existing daily, weekly or monthly note
+type PeriodicNote struct {
+	Name     string
+	FilePath string
+	Period   string
+	Start    time.Time // first day of the period
+}
+
+// PeriodTime moves now by offset days, weeks or months. Moving by months
+// keeps the day where the target month has it, and otherwise uses its last day.
+func PeriodTime(period string, now time.Time, offset int) time.Time {
+	switch period {
+	case PeriodWeek:
+		return now.AddDate(0, 0, 7*offset)
+	case PeriodMonth:
+		first := time.Date(now.Year(), now.Month()+time.Month(offset), 1, now.Hour(), now.Minute(), now.Second(), 0, now.Location())
+		lastDay := first.AddDate(0, 1, -1).Day()
+		return first.AddDate(0, 0, min(now.Day(), lastDay)-1)
+	}
+	return now.AddDate(0, 0, offset)
+}
+
+// PeriodicNoteBase names the note for the period containing t, in the
+// 060102 style: "261017" for a day, "26W42" for its ISO week and "2610" for
+// its month
+func PeriodicNoteBase(period string, t time.Time) string {
+	switch period {
+	case PeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%02dW%02d", year%100, week)
+	case PeriodMonth:
+		return t.Format("0601")
+	}
+	return t.Format("060102")
+}
+
+// ParsePeriodicName reads a name made by PeriodicNoteBase, giving its period
+// and the day the period starts
+func ParsePeriodicName(base string) (period string, start time.Time, ok bool) {
+	switch {
+	case dayNameRegex.MatchString(base):
+		t, err := time.ParseInLocation("060102", base, time.Local)
+		return PeriodDay, t, err == nil
+	case monthNameRegex.MatchString(base):
+		t, err := time.ParseInLocation("0601", base, time.Local)
+		return PeriodMonth, t, err == nil
+	case weekNameRegex.MatchString(base):
+		m := weekNameRegex.FindStringSubmatch(base)
+		year, _ := strconv.Atoi(m[1])
+		week, _ := strconv.Atoi(m[2])
+		// January 4th is always in week 1; step back to its Monday
+		jan4 := time.Date(2000+year, time.January, 4, 0, 0, 0, 0, time.Local)
+		monday := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+7*(week-1))
+		if y, w := monday.ISOWeek(); week < 1 || y != 2000+year || w != week {
+			return "", time.Time{}, false
+		}
+		return PeriodWeek, monday, true
+	}
+	return "", time.Time{}, false
+}
+
+// PeriodicNoteName returns the full name of the note for the period
+// containing t, in the journal notebook
+func PeriodicNoteName(cfg data.Config, period string, t time.Time) string {
+	return path.Join(cfg.JournalDir(), PeriodicNoteBase(period, t))
+}
+
+// OpenPeriodicNote opens the note for the period offset periods from now
+// (-1 is yesterday, last week or last month), creating it from the period's
+// template the way CreateNoteFromTemplate does if it doesn't exist yet.
+// Returns the note's name.
+func OpenPeriodicNote(period string, offset int, prompt PromptFunc) (string, error) {
+	if _, ok := builtinPeriodTemplates[period]; !ok {
+		return "", fmt.Errorf("unknown period: %s", period)
+	}
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("error loading config: %w", err)
+	}
+	when := PeriodTime(period, time.Now(), offset)
+	tmpl, err := periodTemplate(cfg, period)
+	if err != nil {
+		return "", err
+	}
+	return createNoteFromTemplate(PeriodicNoteName(cfg, period, when), tmpl, "none", when, prompt)
+}
+
+// periodTemplate returns the template configured for period, else a
+// template named after it ("daily"), else the built-in one
+func periodTemplate(cfg data.Config, period string) (noteTemplate, error) {
+	name := map[string]string{
+		PeriodDay:   cfg.DailyTemplate,
+		PeriodWeek:  cfg.WeeklyTemplate,
+		PeriodMonth: cfg.MonthlyTemplate,
+	}[period]
+	if name == "" && data.TemplateExists(period) {
+		name = period
+	}
+	if name == "" {
+		return noteTemplate{name: period, body: builtinPeriodTemplates[period], bodyLine: 1}, nil
+	}
+	settings, body, bodyLine, err := loadTemplate(name)
+	if err != nil {
+		return noteTemplate{}, err
+	}
+	return noteTemplate{name: name, settings: settings, body: body, bodyLine: bodyLine}, nil
+}
+
+// ListPeriodicNotes returns the periodic notes in the journal notebook,
+// newest first. period limits them to one kind when set.
+func ListPeriodicNotes(period string) ([]PeriodicNote, error) {
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+	index, err := data.LoadIndex()
+	if err != nil {
+		return nil, fmt.Errorf("error loading index: %w", err)
+	}
+
+	var notes []PeriodicNote
+	for key, meta := range index {
+		if !strings.EqualFold(data.NotebookOf(key), cfg.JournalDir()) {
+			continue
+		}
+		p, start, ok := ParsePeriodicName(data.BaseName(key))
+		if !ok || (period != "" && p != period) {
+			continue
+		}
+		notes = append(notes, PeriodicNote{Name: key, FilePath: meta.FilePath, Period: p, Start: start})
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if !notes[i].Start.Equal(notes[j].Start) {
+			return notes[i].Start.After(notes[j].Start)
+		}
+		return periodOrder[notes[i].Period] < periodOrder[notes[j].Period]
+	})
+	return notes, nil
+}
@@ -7,6 +7,8 @@ import (
 	"gote/src/data"
 )
 
+// PinNote pins a note. The name must match a note exactly, case-insensitively
+// or by base name; pinning doesn't guess at typos.
 func PinNote(noteName string) error {
 	index, err := data.LoadIndex()
 	if err != nil {
@@ -0,0 +1,712 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gote/src/data"
+)
+
+// Query syntax:
+//
+//	word            notes whose title or content contains word (stemmed)
+//	"exact phrase"  words in sequence, in the title or content
+//	-term           exclude notes matching term
+//	a OR b          either side; adjacent terms are ANDed
+//	( ... )         grouping
+//	title:x         title contains x (title:"two words" for phrases)
+//	notebook:x      note is in notebook x or one of its sub-notebooks
+//	tag:x           note has tag x or one below it (tag:=x for x only)
+//	prop:key=value  note's front matter sets key to value (prop:key for any value)
+//	created:2410..2412, modified:241015
+//	                date ranges in the same formats as search -w
+//
+// Any other word:with a colon is searched as text, unless the part before the
+// colon is a misspelt field name.
+
+// titleMatchBoost ranks title hits above content hits, as in SearchNotesCombined
+const titleMatchBoost = 1000
+
+// QuerySyntaxError reports a malformed query and where it went wrong
+type QuerySyntaxError struct {
+	Pos int // 1-based character offset
+	Msg string
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("query syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+// Query is a parsed search query
+type Query struct {
+	root     queryNode
+	expanded []string // vocabulary words misspelled terms matched in the last Search
+}
+
+// Terms returns the words and phrases the query searches for, excluding
+// negated terms and tag/date filters. After Search it also includes the
+// indexed words that misspelled terms were expanded to.
+func (q *Query) Terms() []string {
+	terms := append([]string(nil), q.expanded...)
+	var walk func(n queryNode, negated bool)
+	walk = func(n queryNode, negated bool) {
+		switch n := n.(type) {
+		case *andNode:
+			for _, c := range n.children {
+				walk(c, negated)
+			}
+		case *orNode:
+			for _, c := range n.children {
+				walk(c, negated)
+			}
+		case *notNode:
+			walk(n.child, !negated)
+		case *textNode:
+			if !negated {
+				terms = append(terms, n.text)
+			}
+		case *titleNode:
+			if !negated {
+				terms = append(terms, n.text)
+			}
+		}
+	}
+	walk(q.root, false)
+	return terms
+}
+
+// --- Lexer ---
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokPhrase
+	tokField
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind  tokenKind
+	text  string // word, phrase, or field value
+	field string // field name for tokField
+	pos   int    // 1-based offset
+}
+
+var queryFields = map[string]bool{"title": true, "tag": true, "notebook": true, "prop": true, "created": true, "modified": true}
+
+func lexQuery(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+	i := 0
+
+	readQuoted := func(start int) (string, error) {
+		// runes[i] is the opening quote
+		j := i + 1
+		for j < len(runes) && runes[j] != '"' {
+			j++
+		}
+		if j >= len(runes) {
+			return "", &QuerySyntaxError{Pos: start + 1, Msg: "unterminated quote"}
+		}
+		text := string(runes[i+1 : j])
+		i = j + 1
+		return text, nil
+	}
+	isBreak := func(r rune) bool {
+		return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+	}
+
+	for i < len(runes) {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, pos: start + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, pos: start + 1})
+			i++
+		case r == '"':
+			text, err := readQuoted(start)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokPhrase, text: text, pos: start + 1})
+		case (r == '-' || r == '+') && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			if r == '-' {
+				tokens = append(tokens, token{kind: tokNot, pos: start + 1})
+			}
+			i++
+		default:
+			j := i
+			for j < len(runes) && !isBreak(runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			i = j
+
+			if word == "OR" {
+				tokens = append(tokens, token{kind: tokOr, pos: start + 1})
+				continue
+			}
+			if word == "AND" {
+				continue // adjacent terms are already ANDed
+			}
+
+			if colon := strings.Index(word, ":"); colon > 0 && isFieldName(word[:colon]) {
+				field := strings.ToLower(word[:colon])
+				if !queryFields[field] {
+					// "TODO:" and "re:deploy" are text; only a misspelt field is an error
+					if near := nearestField(field); near != "" {
+						return nil, &QuerySyntaxError{Pos: start + 1, Msg: fmt.Sprintf("unknown field %q (did you mean %s:?)", field, near)}
+					}
+					tokens = append(tokens, token{kind: tokWord, text: word, pos: start + 1})
+					continue
+				}
+				value := word[colon+1:]
+				if value == "" && i < len(runes) && runes[i] == '"' {
+					quoted, err := readQuoted(i)
+					if err != nil {
+						return nil, err
+					}
+					value = quoted
+				}
+				if value == "" {
+					return nil, &QuerySyntaxError{Pos: start + 1, Msg: fmt.Sprintf("missing value for %s:", field)}
+				}
+				tokens = append(tokens, token{kind: tokField, field: field, text: value, pos: start + 1})
+				continue
+			}
+			tokens = append(tokens, token{kind: tokWord, text: word, pos: start + 1})
+		}
+	}
+	tokens = append(tokens, token{kind: tokEOF, pos: len(runes) + 1})
+	return tokens, nil
+}
+
+// IsQueryField reports whether name is a field usable as name:value in a query
+func IsQueryField(name string) bool {
+	return queryFields[strings.ToLower(name)]
+}
+
+// nearestField is the query field name is a typo of, or ""
+func nearestField(name string) string {
+	best, bestDist := "", max(1, data.MaxTypos(len(name)))+1
+	for field := range queryFields {
+		if d := data.EditDistance([]rune(name), []rune(field)); d < bestDist || (d == bestDist && field < best) {
+			best, bestDist = field, d
+		}
+	}
+	return best
+}
+
+func isFieldName(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// --- Parser ---
+
+type queryNode interface{}
+
+type andNode struct{ children []queryNode }
+type orNode struct{ children []queryNode }
+type notNode struct{ child queryNode }
+
+// textNode matches a word or phrase against titles and content
+type textNode struct {
+	text   string
+	phrase bool
+}
+
+// titleNode matches a substring of the title only
+type titleNode struct{ text string }
+
+type tagNode struct {
+	tag   string
+	exact bool // don't match tags below tag
+}
+
+type notebookNode struct{ notebook string }
+
+// propNode matches a front matter property; an empty value matches any
+type propNode struct{ key, value string }
+
+type dateNode struct {
+	rng        DateRange
+	useCreated bool
+}
+
+type queryParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *queryParser) peek() token { return p.tokens[p.pos] }
+func (p *queryParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// ParseQuery parses a search query. Errors are *QuerySyntaxError.
+func ParseQuery(input string) (*Query, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, &QuerySyntaxError{Pos: 1, Msg: "empty query"}
+	}
+	tokens, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &QuerySyntaxError{Pos: t.pos, Msg: "unexpected )"}
+	}
+	return &Query{root: root}, nil
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []queryNode{left}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	if len(children) == 1 {
+		return left, nil
+	}
+	return &orNode{children: children}, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	var children []queryNode
+	for {
+		t := p.peek()
+		if t.kind == tokEOF || t.kind == tokRParen || t.kind == tokOr {
+			break
+		}
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+	if len(children) == 0 {
+		t := p.peek()
+		switch t.kind {
+		case tokOr:
+			return nil, &QuerySyntaxError{Pos: t.pos, Msg: "OR needs a term on both sides"}
+		case tokRParen:
+			return nil, &QuerySyntaxError{Pos: t.pos, Msg: "expected a search term before )"}
+		default:
+			return nil, &QuerySyntaxError{Pos: t.pos, Msg: "expected a search term"}
+		}
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &andNode{children: children}, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{child: child}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, &QuerySyntaxError{Pos: t.pos, Msg: "missing closing )"}
+		}
+		p.next()
+		return n, nil
+	case tokWord:
+		return &textNode{text: t.text}, nil
+	case tokPhrase:
+		if strings.TrimSpace(t.text) == "" {
+			return nil, &QuerySyntaxError{Pos: t.pos, Msg: "empty phrase"}
+		}
+		return &textNode{text: t.text, phrase: true}, nil
+	case tokField:
+		return parseField(t)
+	case tokRParen:
+		return nil, &QuerySyntaxError{Pos: t.pos, Msg: "unexpected )"}
+	default:
+		return nil, &QuerySyntaxError{Pos: t.pos, Msg: "expected a search term"}
+	}
+}
+
+func parseField(t token) (queryNode, error) {
+	switch t.field {
+	case "title":
+		return &titleNode{text: t.text}, nil
+	case "tag":
+		exact := strings.HasPrefix(t.text, "=")
+		tag := data.NormalizeTag(strings.TrimPrefix(strings.TrimPrefix(t.text, "="), "."))
+		if tag == "" {
+			return nil, &QuerySyntaxError{Pos: t.pos, Msg: "missing value for tag:"}
+		}
+		return &tagNode{tag: tag, exact: exact}, nil
+	case "notebook":
+		notebook := strings.Trim(t.text, "/")
+		if notebook == "" {
+			return nil, &QuerySyntaxError{Pos: t.pos, Msg: "missing value for notebook:"}
+		}
+		return &notebookNode{notebook: notebook}, nil
+	case "prop":
+		key, value, _ := strings.Cut(t.text, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			return nil, &QuerySyntaxError{Pos: t.pos, Msg: "missing property name for prop: (use prop:key=value)"}
+		}
+		return &propNode{key: key, value: strings.TrimSpace(value)}, nil
+	default: // created, modified
+		inputs := strings.SplitN(t.text, "..", 2)
+		if len(inputs) == 2 && inputs[1] == "" {
+			inputs = inputs[:1]
+		}
+		rng, err := ParseDateRange(inputs)
+		if err != nil {
+			return nil, &QuerySyntaxError{Pos: t.pos, Msg: fmt.Sprintf("invalid %s: date: %v", t.field, err)}
+		}
+		return &dateNode{rng: rng, useCreated: t.field == "created"}, nil
+	}
+}
+
+// --- Evaluation ---
+
+type queryEval struct {
+	index    map[string]data.NoteMeta
+	fts      *data.FTSIndex
+	avgdl    float64
+	n        float64
+	expanded []string
+	exact    bool // evaluating a negation, where near misses mustn't match
+}
+
+// fuzzyTermWeight discounts hits on a misspelled term's nearby stems
+const fuzzyTermWeight = 0.5
+
+// matches maps note titles to their relevance score
+type matches map[string]float64
+
+// Search runs the query against the index and FTS index
+func (q *Query) Search(limit int) ([]SearchResult, error) {
+	index, err := data.LoadIndex()
+	if err != nil {
+		return nil, err
+	}
+	fts, err := data.LoadFTS()
+	if err != nil {
+		return nil, err
+	}
+
+	ev := &queryEval{index: index, fts: fts, avgdl: fts.AvgLength(), n: float64(len(fts.Docs))}
+	found := ev.eval(q.root)
+	q.expanded = ev.expanded
+
+	var results []SearchResult
+	for title, score := range found {
+		meta := index[title]
+		results = append(results, SearchResult{
+			Title:    title,
+			FilePath: meta.FilePath,
+			Score:    int(math.Round(score)),
+			Created:  meta.Created,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Created != results[j].Created {
+			return results[i].Created > results[j].Created
+		}
+		return results[i].Title < results[j].Title
+	})
+
+	if limit > 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	attachSnippets(results, q.Terms())
+	return results, nil
+}
+
+// SearchQuery parses and runs a query in one step
+func SearchQuery(query string, limit int) ([]SearchResult, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return q.Search(limit)
+}
+
+func (ev *queryEval) eval(n queryNode) matches {
+	switch n := n.(type) {
+	case *andNode:
+		result := ev.eval(n.children[0])
+		for _, c := range n.children[1:] {
+			other := ev.eval(c)
+			for title, score := range result {
+				if s, ok := other[title]; ok {
+					result[title] = score + s
+				} else {
+					delete(result, title)
+				}
+			}
+		}
+		return result
+	case *orNode:
+		result := make(matches)
+		for _, c := range n.children {
+			for title, score := range ev.eval(c) {
+				result[title] += score
+			}
+		}
+		return result
+	case *notNode:
+		// Excluding a near miss would drop notes the user wants
+		exact := ev.exact
+		ev.exact = true
+		excluded := ev.eval(n.child)
+		ev.exact = exact
+		result := make(matches)
+		for title := range ev.index {
+			if _, ok := excluded[title]; !ok {
+				result[title] = 0
+			}
+		}
+		return result
+	case *textNode:
+		return ev.evalText(n)
+	case *titleNode:
+		result := make(matches)
+		ev.matchTitles(n.text, !ev.exact, result)
+		return result
+	case *tagNode:
+		result := make(matches)
+		for title, meta := range ev.index {
+			for _, tag := range meta.Tags {
+				if data.TagMatches(tag, n.tag, n.exact) {
+					result[title] = 1
+					break
+				}
+			}
+		}
+		return result
+	case *notebookNode:
+		result := make(matches)
+		for title := range ev.index {
+			if data.InNotebook(title, n.notebook) {
+				result[title] = 0
+			}
+		}
+		return result
+	case *propNode:
+		result := make(matches)
+		for title, meta := range ev.index {
+			if value, ok := meta.Properties[n.key]; ok && propertyMatches(value, n.value) {
+				result[title] = 1
+			}
+		}
+		return result
+	case *dateNode:
+		result := make(matches)
+		for title, meta := range ev.index {
+			value := meta.Modified
+			if n.useCreated {
+				value = meta.Created
+			}
+			if value != "" && value >= n.rng.Start && value <= n.rng.End {
+				result[title] = 0
+			}
+		}
+		return result
+	}
+	return make(matches)
+}
+
+// propertyMatches compares a property value with a wanted one, ignoring
+// case. A list value ("alice, bob") matches any of its items.
+func propertyMatches(value, want string) bool {
+	if want == "" || strings.EqualFold(value, want) {
+		return true
+	}
+	for _, item := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), want) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTitles scores titles containing text and, if fuzzy, fuzzy title
+// matches at up to half the boost, so exact hits stay on top
+func (ev *queryEval) matchTitles(text string, fuzzy bool, result matches) {
+	needle := strings.ToLower(text)
+	for title := range ev.index {
+		if strings.Contains(strings.ToLower(title), needle) {
+			result[title] = titleMatchBoost
+		} else if !fuzzy {
+			continue
+		} else if score, ok := data.FuzzyScore(text, title); ok {
+			result[title] = titleMatchBoost / 2 * math.Min(1, float64(score)/float64(perfectFuzzyScore(text)))
+		}
+	}
+}
+
+// perfectFuzzyScore is the score of text matched contiguously from a word start
+func perfectFuzzyScore(text string) int {
+	score, _ := data.FuzzyScore(text, text)
+	return max(score, 1)
+}
+
+// evalText matches a word or phrase by title or FTS content. A bare word
+// outside a negation also matches titles fuzzily, and if it's missing from
+// the index, similar indexed terms; phrases and negated terms match exactly.
+func (ev *queryEval) evalText(n *textNode) matches {
+	fuzzy := !n.phrase && !ev.exact
+	result := make(matches)
+	ev.matchTitles(n.text, fuzzy, result)
+
+	terms := data.Tokenize(n.text)
+	if len(terms) == 0 {
+		return result
+	}
+
+	weight := 1.0
+	for i, term := range terms {
+		if ev.fts.DocFreq(term) > 0 {
+			continue
+		}
+		if !fuzzy {
+			return result
+		}
+		similar := ev.fts.SimilarTerms(term)
+		if len(similar) == 0 {
+			return result
+		}
+		// A single word may match any nearby stem; within a phrase use the closest
+		if len(terms) == 1 {
+			for _, alt := range similar {
+				ev.addTermHits(alt, fuzzyTermWeight, result)
+			}
+			ev.expanded = append(ev.expanded, similar...)
+			return result
+		}
+		terms[i] = similar[0]
+		ev.expanded = append(ev.expanded, similar[0])
+		weight = fuzzyTermWeight
+	}
+
+	if len(terms) == 1 {
+		ev.addTermHits(terms[0], weight, result)
+		return result
+	}
+	for title := range ev.fts.Postings[terms[0]] {
+		if _, ok := ev.index[title]; !ok || !ev.hasPhrase(title, terms) {
+			continue
+		}
+		var score float64
+		for _, term := range terms {
+			score += ev.bm25(term, title)
+		}
+		result[title] += score * 100 * weight // scale like SearchNotesFullText
+	}
+	return result
+}
+
+// addTermHits adds the weighted BM25 score of every doc containing term
+func (ev *queryEval) addTermHits(term string, weight float64, result matches) {
+	for title := range ev.fts.Postings[term] {
+		if _, ok := ev.index[title]; ok {
+			result[title] += ev.bm25(term, title) * 100 * weight
+		}
+	}
+}
+
+// hasPhrase reports whether terms occur consecutively in the doc. Docs indexed
+// before positions were stored fall back to requiring all terms.
+func (ev *queryEval) hasPhrase(title string, terms []string) bool {
+	postings := make([]data.Posting, len(terms))
+	for i, term := range terms {
+		p, ok := ev.fts.Postings[term][title]
+		if !ok {
+			return false
+		}
+		postings[i] = p
+	}
+	if len(postings[0].Positions) == 0 {
+		return true
+	}
+
+	for _, start := range postings[0].Positions {
+		matched := true
+		for i := 1; i < len(terms) && matched; i++ {
+			matched = containsInt(postings[i].Positions, start+i)
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (ev *queryEval) bm25(term, title string) float64 {
+	postings := ev.fts.Postings[term]
+	p, ok := postings[title]
+	if !ok {
+		return 0
+	}
+	df := float64(len(postings))
+	idf := math.Log((ev.n-df+0.5)/(df+0.5) + 1)
+	tf := float64(p.TF)
+	dl := float64(ev.fts.Docs[title].Length)
+	return idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*dl/ev.avgdl))
+}
+
+func containsInt(sorted []int, v int) bool {
+	i := sort.SearchInts(sorted, v)
+	return i < len(sorted) && sorted[i] == v
+}
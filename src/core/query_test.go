@@ -0,0 +1,224 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"gote/src/data"
+)
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "   ", "empty query"},
+		{"unterminated quote", `"open phrase`, "unterminated quote"},
+		{"missing paren", "(a OR b", "missing closing )"},
+		{"stray paren", "a)", "unexpected )"},
+		{"dangling OR", "a OR", "expected a search term"},
+		{"leading OR", "OR a", "OR needs a term on both sides"},
+		{"unknown field", "tittle:x", `unknown field "tittle" (did you mean title:?)`},
+		{"misspelt field", "tags:work", "did you mean tag:?"},
+		{"bad date", "created:24x", "invalid created: date"},
+		{"missing field value", "tag:", "missing value for tag:"},
+		{"missing property name", "prop:=open", "missing property name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQuery(tt.input)
+			var syntaxErr *QuerySyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("ParseQuery(%q) error = %v, want QuerySyntaxError", tt.input, err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseQuery(%q) error = %q, want it to contain %q", tt.input, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	q, err := ParseQuery(`alpha "beta gamma" -delta (tag:x OR title:eps)`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	got := strings.Join(q.Terms(), "|")
+	if got != "alpha|beta gamma|eps" {
+		t.Errorf("Terms() = %q, want alpha|beta gamma|eps", got)
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	notes := map[string]string{
+		"standup":   ".work\nDaily standup about the release plan",
+		"retro":     ".work.team\nRetro: the plan for release was late",
+		"groceries": ".home\nBuy milk and plan dinner",
+		"reading":   ".home\nRelease notes for the new book",
+		"launch":    "---\nstatus: Open\nowner: [alice, bob]\ntags: [work]\n---\nLaunch checklist",
+	}
+	index := make(map[string]data.NoteMeta)
+	created := map[string]string{"standup": "241001.090000", "retro": "241105.090000", "groceries": "241210.090000", "reading": "250115.090000", "launch": "230201.090000"}
+	for name, content := range notes {
+		path := filepath.Join(notesDir, name+".md")
+		os.WriteFile(path, []byte(content), 0644)
+		info, _ := os.Stat(path)
+		meta, _ := data.BuildNoteMeta(path, info)
+		meta.Created = created[name]
+		index[name] = meta
+	}
+	data.SaveIndexWithTags(index)
+	data.IndexAllFTS(notesDir, index)
+
+	titles := func(t *testing.T, query string) string {
+		t.Helper()
+		results, err := SearchQuery(query, -1)
+		if err != nil {
+			t.Fatalf("SearchQuery(%q) failed: %v", query, err)
+		}
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Title)
+		}
+		sort.Strings(out)
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"plan", "groceries,retro,standup"},
+		{"plan release", "retro,standup"},
+		{`"release plan"`, "standup"},
+		{"plan -release", "groceries"},
+		{"milk OR book", "groceries,reading"},
+		{"(milk OR book) release", "reading"},
+		{"tag:work", "launch,retro,standup"},
+		{"tag:.home plan", "groceries"},
+		{"title:retro", "retro"},
+		{"created:2410..2411", "retro,standup"},
+		{"created:2412 OR created:25", "groceries,reading"},
+		{"release -tag:work", "reading"},
+		{"nothing-matches-this", ""},
+		{"prop:status=open", "launch"},
+		{"prop:owner=bob prop:status", "launch"},
+		{"prop:owner=carol", ""},
+		{"tag:work -prop:status", "retro,standup"},
+		{"Retro: late", "retro"},
+		{"release:plan", "standup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := titles(t, tt.query); got != tt.want {
+				t.Errorf("SearchQuery(%q) = [%s], want [%s]", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("results carry snippets", func(t *testing.T) {
+		results, _ := SearchQuery("milk", -1)
+		if len(results) != 1 || len(results[0].Snippets) != 1 {
+			t.Fatalf("expected one snippet, got %+v", results)
+		}
+		snip := results[0].Snippets[0]
+		if snip.Line != 2 || results[0].FirstMatchLine() != 2 {
+			t.Errorf("snippet line = %d, want 2", snip.Line)
+		}
+		if len(snip.Matches) != 1 || snip.Text[snip.Matches[0][0]:snip.Matches[0][1]] != "milk" {
+			t.Errorf("matches = %v in %q", snip.Matches, snip.Text)
+		}
+	})
+
+	t.Run("misspelled terms expand to indexed words", func(t *testing.T) {
+		q, err := ParseQuery("relase")
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := q.Search(-1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 3 {
+			t.Errorf("expected 3 results, got %v", results)
+		}
+		for _, r := range results {
+			if len(r.Snippets) == 0 {
+				t.Errorf("%s: expected snippet for expanded term", r.Title)
+			}
+		}
+	})
+
+	t.Run("negated and quoted terms match exactly", func(t *testing.T) {
+		for query, want := range map[string]string{
+			"plan -relase":     "groceries,retro,standup", // not release
+			"plan -retor":      "groceries,retro,standup", // not the title retro
+			"plan -title:rtro": "groceries,retro,standup",
+			`"relase plan"`:    "",
+			"plan -release":    "groceries",
+		} {
+			if got := titles(t, query); got != want {
+				t.Errorf("SearchQuery(%q) = [%s], want [%s]", query, got, want)
+			}
+		}
+	})
+
+	t.Run("fuzzy title match", func(t *testing.T) {
+		if got := titles(t, "title:grocreis"); got != "groceries" {
+			t.Errorf("title:grocreis = [%s]", got)
+		}
+	})
+
+	t.Run("title matches rank first", func(t *testing.T) {
+		results, _ := SearchQuery("retro OR plan", -1)
+		if len(results) == 0 || results[0].Title != "retro" {
+			t.Errorf("expected retro first, got %v", results)
+		}
+	})
+}
+
+func TestFindSnippets(t *testing.T) {
+	content := "Intro line\nWe are planning the release\nnothing here\nPlans changed again\nfinal plan"
+
+	snippets := FindSnippets(content, []string{"plan"}, 2)
+	if len(snippets) != 2 {
+		t.Fatalf("expected 2 snippets, got %d", len(snippets))
+	}
+	if snippets[0].Line != 2 || snippets[1].Line != 4 {
+		t.Errorf("lines = %d,%d, want 2,4", snippets[0].Line, snippets[1].Line)
+	}
+	m := snippets[0].Matches[0]
+	if got := snippets[0].Text[m[0]:m[1]]; got != "planning" {
+		t.Errorf("match = %q, want planning", got)
+	}
+
+	t.Run("long lines are trimmed around the match", func(t *testing.T) {
+		long := strings.Repeat("filler ", 40) + "needle" + strings.Repeat(" filler", 40)
+		snips := FindSnippets(long, []string{"needle"}, 0)
+		if len(snips) != 1 {
+			t.Fatalf("expected 1 snippet, got %d", len(snips))
+		}
+		s := snips[0]
+		if !strings.HasPrefix(s.Text, "…") || !strings.HasSuffix(s.Text, "…") {
+			t.Errorf("expected ellipses on both ends: %q", s.Text)
+		}
+		if len(s.Matches) != 1 || s.Text[s.Matches[0][0]:s.Matches[0][1]] != "needle" {
+			t.Errorf("match offsets not adjusted: %v in %q", s.Matches, s.Text)
+		}
+	})
+
+	t.Run("no terms", func(t *testing.T) {
+		if snips := FindSnippets(content, nil, 0); snips != nil {
+			t.Errorf("expected nil, got %v", snips)
+		}
+	})
+}
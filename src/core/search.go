@@ -20,29 +20,39 @@ type SearchResult struct {
 	FilePath string
 	Score    int
 	Created  string
+	Snippets []Snippet // matching lines, filled in by query searches
 }
 
+// SearchNotesByTitle ranks titles by fuzzy match against query: substrings
+// and fzf-style subsequences ("mtg" for "meeting") score highest, and words
+// within a couple of typos ("meting") still match. Ties go to newer notes.
 func SearchNotesByTitle(query string, limit int) ([]SearchResult, error) {
-	query = strings.ToLower(query)
 	index, err := data.LoadIndex()
 	if err != nil {
 		return nil, err
 	}
-	var results []SearchResult
 
+	titles := make([]string, 0, len(index))
 	for title := range index {
-		if strings.Contains(strings.ToLower(title), query) {
-			meta := index[title]
-			results = append(results, SearchResult{
-				Title:    title,
-				FilePath: meta.FilePath,
-				Score:    1,
-				Created:  meta.Created,
-			})
-		}
+		titles = append(titles, title)
+	}
+	var results []SearchResult
+	for _, m := range data.FuzzyRank(query, titles) {
+		meta := index[m.Text]
+		results = append(results, SearchResult{
+			Title:    m.Text,
+			FilePath: meta.FilePath,
+			Score:    m.Score,
+			Created:  meta.Created,
+		})
 	}
 
-	sortResultsByCreated(results)
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Created > results[j].Created
+	})
 
 	if limit > 0 && limit < len(results) {
 		results = results[:limit]
@@ -51,8 +61,10 @@ func SearchNotesByTitle(query string, limit int) ([]SearchResult, error) {
 	return results, nil
 }
 
-// SearchNotesByTags returns notes matching ANY of the specified tags (OR logic)
-func SearchNotesByTags(tags []string, limit int) ([]SearchResult, error) {
+// SearchNotesByTags returns notes matching ANY of the specified tags (OR logic).
+// A tag also matches the tags below it ("work" finds "work/clients/acme")
+// unless exact is set.
+func SearchNotesByTags(tags []string, limit int, exact bool) ([]SearchResult, error) {
 	tagsMap, err := data.LoadTags()
 	if err != nil {
 		return nil, err
@@ -65,22 +77,19 @@ func SearchNotesByTags(tags []string, limit int) ([]SearchResult, error) {
 
 	noteCount := make(map[string]int)
 	for _, tag := range tags {
-		tm, exists := tagsMap[tag]
-		if !exists {
-			continue
-		}
-		for _, note := range tm.Notes {
+		for note := range notesTagged(tagsMap, tag, exact) {
 			noteCount[note]++
 		}
 	}
 
+	titles := titlesByPath(index)
 	var results []SearchResult
 	for notePath, count := range noteCount {
-		title := strings.TrimSuffix(filepath.Base(notePath), ".md")
-		created := ""
-		if meta, exists := index[title]; exists {
-			created = meta.Created
+		title, ok := titles[notePath]
+		if !ok {
+			title = strings.TrimSuffix(filepath.Base(notePath), ".md")
 		}
+		created := index[title].Created
 		results = append(results, SearchResult{
 			Title:    title,
 			FilePath: notePath,
@@ -103,8 +112,9 @@ func SearchNotesByTags(tags []string, limit int) ([]SearchResult, error) {
 	return results, nil
 }
 
-// FilterNotesByTags returns notes that have ALL specified tags (AND logic)
-func FilterNotesByTags(tags []string, limit int) ([]SearchResult, error) {
+// FilterNotesByTags returns notes that have ALL specified tags (AND logic).
+// A tag is satisfied by the tags below it unless exact is set.
+func FilterNotesByTags(tags []string, limit int, exact bool) ([]SearchResult, error) {
 	tagsMap, err := data.LoadTags()
 	if err != nil {
 		return nil, err
@@ -122,26 +132,27 @@ func FilterNotesByTags(tags []string, limit int) ([]SearchResult, error) {
 	// Count how many of the specified tags each note has
 	noteCount := make(map[string]int)
 	for _, tag := range tags {
-		tm, exists := tagsMap[tag]
-		if !exists {
+		notes := notesTagged(tagsMap, tag, exact)
+		if len(notes) == 0 {
 			// If any tag doesn't exist, no notes can match all tags
 			return nil, nil
 		}
-		for _, note := range tm.Notes {
+		for note := range notes {
 			noteCount[note]++
 		}
 	}
 
 	// Only include notes that have ALL the specified tags
+	titles := titlesByPath(index)
 	var results []SearchResult
 	requiredCount := len(tags)
 	for notePath, count := range noteCount {
 		if count == requiredCount {
-			title := strings.TrimSuffix(filepath.Base(notePath), ".md")
-			created := ""
-			if meta, exists := index[title]; exists {
-				created = meta.Created
+			title, ok := titles[notePath]
+			if !ok {
+				title = strings.TrimSuffix(filepath.Base(notePath), ".md")
 			}
+			created := index[title].Created
 			results = append(results, SearchResult{
 				Title:    title,
 				FilePath: notePath,
@@ -159,3 +170,28 @@ func FilterNotesByTags(tags []string, limit int) ([]SearchResult, error) {
 
 	return results, nil
 }
+
+// notesTagged returns the paths of notes with tag, or with a tag below it
+// unless exact is set. Each note appears once however many tags match.
+func notesTagged(tagsMap map[string]data.TagMeta, tag string, exact bool) map[string]bool {
+	notes := make(map[string]bool)
+	for name, tm := range tagsMap {
+		if !data.TagMatches(name, tag, exact) {
+			continue
+		}
+		for _, note := range tm.Notes {
+			notes[note] = true
+		}
+	}
+	return notes
+}
+
+// titlesByPath maps note file paths to index keys. Tags store file paths, and
+// notes in different notebooks can share a file name.
+func titlesByPath(index map[string]data.NoteMeta) map[string]string {
+	titles := make(map[string]string, len(index))
+	for title, meta := range index {
+		titles[meta.FilePath] = title
+	}
+	return titles
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"gote/src/data"
+)
+
+const (
+	maxSnippets  = 3   // snippets kept per search result
+	snippetWidth = 100 // max bytes of a line shown in a snippet
+)
+
+// Snippet is a line of a note that matched a search
+type Snippet struct {
+	Line    int      // 1-based line number in the note
+	Text    string   // the line, trimmed to a window around the matches
+	Matches [][2]int // byte ranges in Text to highlight
+}
+
+// FirstMatchLine returns the line of the result's first snippet, or 0
+func (r SearchResult) FirstMatchLine() int {
+	if len(r.Snippets) == 0 {
+		return 0
+	}
+	return r.Snippets[0].Line
+}
+
+// termMatcher finds query terms in text by stem, falling back to
+// case-insensitive substrings for terms that produce no tokens
+type termMatcher struct {
+	stems      map[string]bool
+	substrings []string
+}
+
+func newTermMatcher(terms []string) termMatcher {
+	m := termMatcher{stems: make(map[string]bool)}
+	for _, term := range terms {
+		tokens := data.Tokenize(term)
+		if len(tokens) == 0 && strings.TrimSpace(term) != "" {
+			m.substrings = append(m.substrings, strings.ToLower(term))
+		}
+		for _, t := range tokens {
+			m.stems[t] = true
+		}
+		// Terms may already be stems (e.g. FTS expansions), which don't
+		// always stem to themselves
+		if len(tokens) == 1 {
+			m.stems[strings.ToLower(term)] = true
+		}
+	}
+	return m
+}
+
+// find returns the byte ranges in text matching the terms, in order
+func (m termMatcher) find(text string) [][2]int {
+	var ranges [][2]int
+	for _, span := range data.TokenizeSpans(text) {
+		if m.stems[span.Stem] {
+			ranges = append(ranges, [2]int{span.Start, span.End})
+		}
+	}
+	if len(m.substrings) > 0 {
+		lower := strings.ToLower(text)
+		for _, sub := range m.substrings {
+			for off := 0; ; {
+				i := strings.Index(lower[off:], sub)
+				if i < 0 {
+					break
+				}
+				ranges = append(ranges, [2]int{off + i, off + i + len(sub)})
+				off += i + len(sub)
+			}
+		}
+		sortRanges(ranges)
+	}
+	return ranges
+}
+
+func sortRanges(ranges [][2]int) {
+	for i := 1; i < len(ranges); i++ {
+		for j := i; j > 0 && ranges[j][0] < ranges[j-1][0]; j-- {
+			ranges[j], ranges[j-1] = ranges[j-1], ranges[j]
+		}
+	}
+}
+
+// MatchRanges returns the byte ranges in text that match any of terms, using
+// the same stemming as the full-text index
+func MatchRanges(text string, terms []string) [][2]int {
+	return newTermMatcher(terms).find(text)
+}
+
+// FindSnippets returns up to limit lines of content that contain any of terms
+func FindSnippets(content string, terms []string, limit int) []Snippet {
+	m := newTermMatcher(terms)
+	if len(m.stems) == 0 && len(m.substrings) == 0 {
+		return nil
+	}
+
+	var snippets []Snippet
+	for i, line := range strings.Split(content, "\n") {
+		ranges := m.find(line)
+		if len(ranges) == 0 {
+			continue
+		}
+		text, ranges := trimSnippet(strings.TrimRight(line, "\r"), ranges)
+		snippets = append(snippets, Snippet{Line: i + 1, Text: text, Matches: ranges})
+		if limit > 0 && len(snippets) >= limit {
+			break
+		}
+	}
+	return snippets
+}
+
+// trimSnippet cuts long lines to a window around the first match, marking
+// cut ends with "…" and shifting the match ranges to suit
+func trimSnippet(line string, ranges [][2]int) (string, [][2]int) {
+	if len(line) <= snippetWidth {
+		return line, ranges
+	}
+	start := max(0, ranges[0][0]-snippetWidth/3)
+	end := min(len(line), start+snippetWidth)
+	start = max(0, end-snippetWidth)
+	for start > 0 && !utf8.RuneStart(line[start]) {
+		start--
+	}
+	for end < len(line) && !utf8.RuneStart(line[end]) {
+		end++
+	}
+
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = "…"
+	}
+	if end < len(line) {
+		suffix = "…"
+	}
+	var kept [][2]int
+	for _, r := range ranges {
+		if r[0] >= start && r[1] <= end {
+			kept = append(kept, [2]int{r[0] - start + len(prefix), r[1] - start + len(prefix)})
+		}
+	}
+	return prefix + line[start:end] + suffix, kept
+}
+
+// attachSnippets reads each result's note and records its matching lines
+func attachSnippets(results []SearchResult, terms []string) {
+	if len(terms) == 0 {
+		return
+	}
+	for i := range results {
+		content, err := os.ReadFile(results[i].FilePath)
+		if err != nil {
+			continue
+		}
+		results[i].Snippets = FindSnippets(string(content), terms, maxSnippets)
+	}
+}
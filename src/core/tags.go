@@ -2,11 +2,16 @@ package core
 
 import (
 	"fmt"
+	"os"
+	"slices"
 	"sort"
+	"strings"
 
 	"gote/src/data"
 )
 
+// GetPopularTags returns the tags notes use most, by direct use. Parent
+// levels no note uses directly are left out.
 func GetPopularTags(limit int) ([]data.TagMeta, error) {
 	tags, err := data.LoadTags()
 	if err != nil {
@@ -15,7 +20,9 @@ func GetPopularTags(limit int) ([]data.TagMeta, error) {
 
 	var tagSlice []data.TagMeta
 	for _, tag := range tags {
-		tagSlice = append(tagSlice, tag)
+		if tag.Count > 0 {
+			tagSlice = append(tagSlice, tag)
+		}
 	}
 
 	sort.Slice(tagSlice, func(i, j int) bool {
@@ -28,3 +35,215 @@ func GetPopularTags(limit int) ([]data.TagMeta, error) {
 
 	return tagSlice, nil
 }
+
+// TagNode is one level of the tag hierarchy
+type TagNode struct {
+	Tag      string // full tag, e.g. "work/clients"
+	Name     string // its last level, e.g. "clients"
+	Count    int    // notes tagged with exactly this tag
+	Total    int    // notes tagged with it or any tag below it
+	Children []*TagNode
+}
+
+// TagTree returns the top-level tags with the tags below them, sorted by name
+func TagTree() ([]*TagNode, error) {
+	tags, err := data.LoadTags()
+	if err != nil {
+		return nil, fmt.Errorf("error loading tags: %w", err)
+	}
+
+	nodes := make(map[string]*TagNode)
+	var node func(tag string) *TagNode
+	node = func(tag string) *TagNode {
+		if n, ok := nodes[tag]; ok {
+			return n
+		}
+		n := &TagNode{Tag: tag, Name: tag[strings.LastIndex(tag, "/")+1:]}
+		nodes[tag] = n
+		if parents := data.TagAncestors(tag); len(parents) > 0 {
+			parent := node(parents[0])
+			parent.Children = append(parent.Children, n)
+		}
+		return n
+	}
+	for name, tm := range tags {
+		n := node(name)
+		n.Count, n.Total = tm.Count, max(tm.Total, tm.Count)
+	}
+
+	var roots []*TagNode
+	for tag, n := range nodes {
+		if !strings.Contains(tag, "/") {
+			roots = append(roots, n)
+		}
+		sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Name < n.Children[j].Name })
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Name < roots[j].Name })
+	return roots, nil
+}
+
+// TagChange is a note whose tags a rename, merge or delete changed. Skipped
+// notes have the tag in the index but not in a form that could be rewritten,
+// e.g. because the note changed since it was indexed, and are left alone.
+type TagChange struct {
+	Note    string
+	Before  []string
+	After   []string
+	Skipped bool
+}
+
+// RenameTag renames a tag in every note using it, along with the tags below
+// it unless exact ("work" to "job" turns "work/clients" into "job/clients").
+// With dryRun nothing is written and the changes that would be made are returned.
+func RenameTag(oldTag, newTag string, exact, dryRun bool) ([]TagChange, error) {
+	return MergeTags([]string{oldTag}, newTag, exact, dryRun)
+}
+
+// MergeTags renames each of sources to into, as RenameTag does
+func MergeTags(sources []string, into string, exact, dryRun bool) ([]TagChange, error) {
+	into, err := cleanTagArg(into)
+	if err != nil {
+		return nil, err
+	}
+	var from []string
+	for _, source := range sources {
+		tag, err := cleanTagArg(source)
+		if err != nil {
+			return nil, err
+		}
+		if tag == into {
+			return nil, fmt.Errorf("cannot rename tag %s to itself", tag)
+		}
+		from = append(from, tag)
+	}
+	return rewriteNoteTags(func(tag string) (string, bool) {
+		for _, source := range from {
+			if data.TagMatches(tag, source, exact) {
+				return into + tag[len(source):], true
+			}
+		}
+		return tag, true
+	}, dryRun)
+}
+
+// DeleteTag removes a tag, and the tags below it unless exact, from every
+// note using it. The notes themselves are kept.
+func DeleteTag(tag string, exact, dryRun bool) ([]TagChange, error) {
+	tag, err := cleanTagArg(tag)
+	if err != nil {
+		return nil, err
+	}
+	return rewriteNoteTags(func(t string) (string, bool) {
+		return t, !data.TagMatches(t, tag, exact)
+	}, dryRun)
+}
+
+// cleanTagArg normalizes a tag given on the command line, with or without
+// its leading period
+func cleanTagArg(tag string) (string, error) {
+	clean := data.NormalizeTag(strings.TrimLeft(strings.TrimSpace(tag), ".#"))
+	switch {
+	case clean == "":
+		return "", fmt.Errorf("invalid tag: %q", tag)
+	case strings.ContainsAny(clean, ".#[]|"):
+		return "", fmt.Errorf("invalid tag %q: tags cannot contain . # [ ] or |", tag)
+	}
+	return clean, nil
+}
+
+// rewriteNoteTags rewrites the tags of every note that has one mapTag changes,
+// then saves the index, tags and search index under a single index lock.
+// Each note is snapshotted before and after so the change can be undone.
+func rewriteNoteTags(mapTag data.TagMapper, dryRun bool) ([]TagChange, error) {
+	var changes []TagChange
+	var failed error
+	apply := func(index map[string]data.NoteMeta) error {
+		keys := make([]string, 0, len(index))
+		for key := range index {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			meta := index[key]
+			if slices.Equal(mapTags(meta.Tags, mapTag), meta.Tags) {
+				continue
+			}
+			content, err := os.ReadFile(meta.FilePath)
+			if err != nil {
+				failed = fmt.Errorf("error reading %s: %w", key, err)
+				break
+			}
+			updated := data.RewriteTags(string(content), mapTag)
+			if updated == string(content) {
+				changes = append(changes, TagChange{Note: key, Before: meta.Tags, After: meta.Tags, Skipped: true})
+				continue
+			}
+			change := TagChange{Note: key, Before: meta.Tags, After: data.NoteTags(updated)}
+			if dryRun {
+				changes = append(changes, change)
+				continue
+			}
+
+			// Stop at the first failure, but still save the notes already
+			// rewritten so the index matches them
+			if failed = snapshotNote(key, meta.FilePath); failed != nil {
+				break
+			}
+			if err := os.WriteFile(meta.FilePath, []byte(updated), 0644); err != nil {
+				failed = fmt.Errorf("error updating tags in %s: %w", key, err)
+				break
+			}
+			if failed = snapshotNote(key, meta.FilePath); failed != nil {
+				break
+			}
+			info, err := os.Stat(meta.FilePath)
+			if err != nil {
+				failed = fmt.Errorf("error stating %s: %w", key, err)
+				break
+			}
+			newMeta, err := data.BuildNoteMeta(meta.FilePath, info)
+			if err != nil {
+				failed = fmt.Errorf("error building metadata for %s: %w", key, err)
+				break
+			}
+			newMeta.Created = meta.Created
+			newMeta.LastVisited = meta.LastVisited
+			index[key] = newMeta
+			change.After = newMeta.Tags
+			changes = append(changes, change)
+
+			if err := data.IndexDocFTS(key, meta.FilePath, updated); err != nil {
+				failed = fmt.Errorf("warning: FTS index failed: %w", err)
+				break
+			}
+		}
+		return nil
+	}
+
+	if dryRun {
+		index, err := data.LoadIndex()
+		if err != nil {
+			return nil, fmt.Errorf("error loading index: %w", err)
+		}
+		if err := apply(index); err != nil {
+			return changes, err
+		}
+		return changes, failed
+	}
+	if err := data.WithIndexLock(apply); err != nil {
+		return changes, err
+	}
+	return changes, failed
+}
+
+// mapTags applies mapTag to tags, dropping removed tags and duplicates
+func mapTags(tags []string, mapTag data.TagMapper) []string {
+	var mapped []string
+	for _, tag := range tags {
+		if newTag, keep := mapTag(tag); keep && !slices.Contains(mapped, newTag) {
+			mapped = append(mapped, newTag)
+		}
+	}
+	return mapped
+}
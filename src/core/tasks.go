@@ -0,0 +1,153 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"gote/src/data"
+)
+
+// TaskItem is a task and the note it's in
+type TaskItem struct {
+	Note string `json:"note"`
+	data.Task
+}
+
+// TaskFilter narrows ListTasks. Tags match a task's own #tags or its note's
+// tags, including tags below them unless Exact.
+type TaskFilter struct {
+	Status   string // "open" (default), "done" or "all"
+	Tags     []string
+	Exact    bool
+	Note     string
+	Notebook string
+}
+
+// ListTasks returns the indexed tasks matching filter: open ones first, then
+// by due date (undated last), note and line
+func ListTasks(filter TaskFilter) ([]TaskItem, error) {
+	index, err := data.LoadIndex()
+	if err != nil {
+		return nil, fmt.Errorf("error loading index: %w", err)
+	}
+
+	var items []TaskItem
+	for key, meta := range index {
+		if filter.Note != "" && !strings.EqualFold(key, filter.Note) {
+			continue
+		}
+		if !data.InNotebook(key, filter.Notebook) {
+			continue
+		}
+		for _, task := range meta.Tasks {
+			switch {
+			case filter.Status == "done" && !task.Done:
+			case (filter.Status == "" || filter.Status == "open") && task.Done:
+			case !taskHasTags(task, meta.Tags, filter.Tags, filter.Exact):
+			default:
+				items = append(items, TaskItem{Note: key, Task: task})
+			}
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Done != b.Done {
+			return !a.Done
+		}
+		if a.Due != b.Due {
+			return b.Due == "" || (a.Due != "" && a.Due < b.Due)
+		}
+		if a.Note != b.Note {
+			return a.Note < b.Note
+		}
+		return a.Line < b.Line
+	})
+	return items, nil
+}
+
+// taskHasTags reports whether every filter tag matches the task or its note
+func taskHasTags(task data.Task, noteTags, filters []string, exact bool) bool {
+	tags := slices.Concat(task.Tags, noteTags)
+	for _, filter := range filters {
+		if !slices.ContainsFunc(tags, func(tag string) bool { return data.TagMatches(tag, filter, exact) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// ToggleTask checks or unchecks the task at line of noteName and reindexes
+// the note. text is the task as listed: if the note has changed since, the
+// task is looked for by its text instead, so the wrong line is never touched.
+func ToggleTask(noteName string, line int, text string) (data.Task, error) {
+	var toggled data.Task
+	err := data.WithIndexLock(func(index map[string]data.NoteMeta) error {
+		meta, ok := index[noteName]
+		if !ok {
+			return fmt.Errorf("note not found: %s", noteName)
+		}
+		content, err := os.ReadFile(meta.FilePath)
+		if err != nil {
+			return fmt.Errorf("error reading note: %w", err)
+		}
+
+		lines := strings.Split(string(content), "\n")
+		target := -1
+		if line >= 1 && line <= len(lines) {
+			if task, ok := data.ParseTaskLine(lines[line-1]); ok && task.Text == text {
+				target = line - 1
+			}
+		}
+		if target < 0 {
+			for _, task := range data.ParseTasks(string(content)) {
+				if task.Text != text {
+					continue
+				}
+				if target >= 0 {
+					return fmt.Errorf("task %q appears more than once in %s; run gote index and try again", text, noteName)
+				}
+				target = task.Line - 1
+			}
+		}
+		if target < 0 {
+			return fmt.Errorf("task %q not found in %s", text, noteName)
+		}
+
+		if err := snapshotNote(noteName, meta.FilePath); err != nil {
+			return err
+		}
+		lines[target], _ = data.ToggleTaskLine(lines[target])
+		updated := strings.Join(lines, "\n")
+		if err := os.WriteFile(meta.FilePath, []byte(updated), 0644); err != nil {
+			return fmt.Errorf("error writing note: %w", err)
+		}
+		if err := snapshotNote(noteName, meta.FilePath); err != nil {
+			return err
+		}
+
+		info, err := os.Stat(meta.FilePath)
+		if err != nil {
+			return fmt.Errorf("error stating note: %w", err)
+		}
+		newMeta, err := data.BuildNoteMeta(meta.FilePath, info)
+		if err != nil {
+			return fmt.Errorf("error building note metadata: %w", err)
+		}
+		newMeta.Created = meta.Created
+		newMeta.LastVisited = meta.LastVisited
+		index[noteName] = newMeta
+
+		toggled, _ = data.ParseTaskLine(lines[target])
+		toggled.Line = target + 1
+
+		if err := data.IndexDocFTS(noteName, meta.FilePath, updated); err != nil {
+			return fmt.Errorf("warning: FTS index failed: %w", err)
+		}
+		return nil
+	})
+	return toggled, err
+}
@@ -0,0 +1,251 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gote/src/data"
+)
+
+// maxIncludeDepth bounds {{include}} nesting so a template can't include itself forever
+const maxIncludeDepth = 8
+
+// ErrTemplateCancelled is returned when the user cancels a template prompt
+var ErrTemplateCancelled = errors.New("cancelled")
+
+// PromptFunc asks the user for a template value. def is the default offered;
+// an error (e.g. ErrTemplateCancelled) aborts rendering.
+type PromptFunc func(label, def string) (string, error)
+
+// TemplateContext is what a template is rendered against
+type TemplateContext struct {
+	NoteName string     // full note name, e.g. "work/260117 standup"
+	Now      time.Time  // time the note is created
+	Prompt   PromptFunc // nil answers every prompt with its default
+}
+
+// templateRenderer renders one template and the templates it includes,
+// sharing prompt answers between them
+type templateRenderer struct {
+	ctx     TemplateContext
+	answers map[string]string
+	stack   []string // templates being rendered, for cycle detection
+}
+
+// RenderTemplate expands a template's placeholders:
+//
+//	{{title}}                    note name without its notebook
+//	{{name}} / {{notebook}}      full note name / its notebook
+//	{{date "2006-01-02"}}        current date in a Go layout
+//	{{date "Mon Jan 2" "+1w"}}   with offsets: +/-N d, w, m (months), y, h
+//	{{week}} / {{week "-1w"}}    ISO week, e.g. 2026-W03
+//	{{now}}                      the time itself, e.g. {{(now.AddDate 0 0 1).Weekday}}
+//	{{prompt "Attendees"}}       asked when the note is created ({{prompt "Q" "default"}})
+//	{{include "footer"}}         another template, rendered the same way
+//
+// Front matter settings are stripped. Errors name the template and line.
+func RenderTemplate(templateName string, ctx TemplateContext) (string, error) {
+	return newTemplateRenderer(ctx).render(templateName)
+}
+
+func newTemplateRenderer(ctx TemplateContext) *templateRenderer {
+	if ctx.Now.IsZero() {
+		ctx.Now = time.Now()
+	}
+	return &templateRenderer{ctx: ctx, answers: make(map[string]string)}
+}
+
+// loadTemplate reads a template and splits off its settings. bodyLine is the file
+// line the body starts on.
+func loadTemplate(name string) (settings data.TemplateSettings, body string, bodyLine int, err error) {
+	content, err := data.LoadTemplate(name)
+	if err != nil {
+		return settings, "", 0, err
+	}
+	settings, body, err = data.ParseTemplateSettings(content)
+	if err != nil {
+		return settings, "", 0, fmt.Errorf("template %s, %w", name, err)
+	}
+	return settings, body, settings.BodyLine, nil
+}
+
+func (r *templateRenderer) render(name string) (string, error) {
+	for _, open := range r.stack {
+		if open == name {
+			return "", fmt.Errorf("template %s includes itself (%s)", name, strings.Join(append(r.stack, name), " -> "))
+		}
+	}
+	if len(r.stack) >= maxIncludeDepth {
+		return "", fmt.Errorf("templates nested more than %d deep at %s", maxIncludeDepth, name)
+	}
+	_, body, bodyLine, err := loadTemplate(name)
+	if err != nil {
+		return "", err
+	}
+	r.stack = append(r.stack, name)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+	return r.execute(name, body, bodyLine)
+}
+
+// execute renders text, numbering error lines from firstLine. Text that
+// doesn't parse and uses none of the template syntax is a template from
+// before placeholders, with literal braces, and is copied as it is.
+func (r *templateRenderer) execute(name, text string, firstLine int) (string, error) {
+	funcs := r.funcs()
+	tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		if !usesTemplateSyntax(text, funcs) {
+			return text, nil
+		}
+		return "", templateError(err, firstLine)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return "", templateError(err, firstLine)
+	}
+	return out.String(), nil
+}
+
+// templateActionRegex matches the start of each {{action}} and what it
+// begins with: a comment, field, variable, string, group or name
+var templateActionRegex = regexp.MustCompile(`\{\{-?\s*(/\*|[.$"(]|[A-Za-z_]\w*)`)
+
+// templateBuiltins are the keywords and functions text/template knows itself
+var templateBuiltins = map[string]bool{
+	"if": true, "else": true, "end": true, "range": true, "with": true, "define": true,
+	"template": true, "block": true, "break": true, "continue": true, "nil": true,
+	"true": true, "false": true, "and": true, "or": true, "not": true, "len": true,
+	"index": true, "slice": true, "print": true, "printf": true, "println": true,
+	"html": true, "js": true, "urlquery": true, "call": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+}
+
+// usesTemplateSyntax reports whether any {{action}} in text starts the way
+// a template action would, rather than being braces meant literally
+func usesTemplateSyntax(text string, funcs template.FuncMap) bool {
+	for _, m := range templateActionRegex.FindAllStringSubmatch(text, -1) {
+		word := m[1]
+		if _, ok := funcs[word]; ok || templateBuiltins[word] || !isIdentStart(word[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func (r *templateRenderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		"title":    func() string { return data.BaseName(r.ctx.NoteName) },
+		"name":     func() string { return r.ctx.NoteName },
+		"notebook": func() string { return data.NotebookOf(r.ctx.NoteName) },
+		"now":      func() time.Time { return r.ctx.Now },
+		"date": func(layout string, offsets ...string) (string, error) {
+			t, err := shiftTime(r.ctx.Now, offsets)
+			if err != nil {
+				return "", err
+			}
+			return t.Format(layout), nil
+		},
+		"week": func(offsets ...string) (string, error) {
+			t, err := shiftTime(r.ctx.Now, offsets)
+			if err != nil {
+				return "", err
+			}
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week), nil
+		},
+		"prompt": func(label string, def ...string) (string, error) {
+			if answer, ok := r.answers[label]; ok {
+				return answer, nil // ask once, even if used twice
+			}
+			answer := strings.Join(def, " ")
+			if r.ctx.Prompt != nil {
+				var err error
+				if answer, err = r.ctx.Prompt(label, answer); err != nil {
+					return "", err
+				}
+			}
+			r.answers[label] = answer
+			return answer, nil
+		},
+		"include": func(name string) (string, error) {
+			if err := data.ValidateNoteName(name); err != nil {
+				return "", fmt.Errorf("invalid template name: %w", err)
+			}
+			return r.render(name)
+		},
+	}
+}
+
+// offsetRegex matches a date offset like "+1d", "-2w" or "3m"
+var offsetRegex = regexp.MustCompile(`^([+-]?)(\d+)\s*([dwmyh])$`)
+
+// shiftTime applies offsets such as "+1d", "-2w", "+1m" (month), "+1y" or
+// "-3h" to t. Several can be given, separated by spaces or as separate args.
+func shiftTime(t time.Time, offsets []string) (time.Time, error) {
+	for _, arg := range offsets {
+		for _, off := range strings.Fields(arg) {
+			m := offsetRegex.FindStringSubmatch(strings.ToLower(off))
+			if m == nil {
+				return t, fmt.Errorf("invalid date offset %q (use e.g. +1d, -2w, +1m, +1y, -3h)", off)
+			}
+			n, _ := strconv.Atoi(m[2])
+			if m[1] == "-" {
+				n = -n
+			}
+			switch m[3] {
+			case "d":
+				t = t.AddDate(0, 0, n)
+			case "w":
+				t = t.AddDate(0, 0, 7*n)
+			case "m":
+				t = t.AddDate(0, n, 0)
+			case "y":
+				t = t.AddDate(n, 0, 0)
+			case "h":
+				t = t.Add(time.Duration(n) * time.Hour)
+			}
+		}
+	}
+	return t, nil
+}
+
+// templateErrorRegex picks the template name and line out of text/template errors
+var templateErrorRegex = regexp.MustCompile(`^template: ([^:]+):(\d+)(?::\d+)?: (.*)$`)
+
+// execPrefixRegex matches the `executing "x" at <...>: error calling f: ` noise
+var execPrefixRegex = regexp.MustCompile(`^executing "[^"]*" at <(.*)>: (?:error calling \w+: )?`)
+
+// templateError rewrites a text/template error as "template NAME, line N: ...",
+// counting lines from firstLine
+func templateError(err error, firstLine int) error {
+	if errors.Is(err, ErrTemplateCancelled) {
+		return ErrTemplateCancelled
+	}
+	// Errors from an included template are already rewritten
+	var inner *includedError
+	if errors.As(err, &inner) {
+		return inner
+	}
+	m := templateErrorRegex.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+	line, _ := strconv.Atoi(m[2])
+	msg := execPrefixRegex.ReplaceAllString(m[3], "{{$1}}: ")
+	return &includedError{fmt.Sprintf("template %s, line %d: %s", m[1], line+firstLine-1, msg)}
+}
+
+// includedError is a template error already carrying its template and line
+type includedError struct{ msg string }
+
+func (e *includedError) Error() string { return e.msg }
@@ -0,0 +1,365 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gote/src/data"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	_, _, cleanup := testEnv(t)
+	defer cleanup()
+
+	data.EnsureTemplatesDir()
+	now := time.Date(2026, 1, 17, 9, 30, 0, 0, time.Local)
+	render := func(content string, prompt PromptFunc) (string, error) {
+		t.Helper()
+		if err := data.SaveTemplate("t", content); err != nil {
+			t.Fatal(err)
+		}
+		return RenderTemplate("t", TemplateContext{NoteName: "work/standup", Now: now, Prompt: prompt})
+	}
+
+	t.Run("names, dates and weeks", func(t *testing.T) {
+		tests := []struct {
+			template string
+			want     string
+		}{
+			{"# {{title}}", "# standup"},
+			{"{{name}} in {{notebook}}", "work/standup in work"},
+			{`{{date "2006-01-02 15:04"}}`, "2026-01-17 09:30"},
+			{`{{date "2006-01-02" "+1d"}}`, "2026-01-18"},
+			{`{{date "2006-01-02" "-2w"}}`, "2026-01-03"},
+			{`{{date "2006-01-02" "+1m" "-1d"}}`, "2026-02-16"},
+			{`{{date "Jan 2" "+1y -3d"}}`, "Jan 14"},
+			{`{{date "15:04" "+2h"}}`, "11:30"},
+			{"{{week}}", "2026-W03"},
+			{`{{week "-3w"}}`, "2025-W52"},
+			{"{{now.Weekday}}", "Saturday"},
+			{`{{"{{"}}title}}`, "{{title}}"},
+		}
+		for _, tt := range tests {
+			got, err := render(tt.template, nil)
+			if err != nil {
+				t.Errorf("%s: %v", tt.template, err)
+				continue
+			}
+			if got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.template, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("prompts are asked once and default when empty", func(t *testing.T) {
+		var asked []string
+		prompt := func(label, def string) (string, error) {
+			asked = append(asked, label+"="+def)
+			if label == "Attendees" {
+				return "Ann, Bo", nil
+			}
+			return def, nil
+		}
+		got, err := render(`{{prompt "Attendees"}} / {{prompt "Room" "4.2"}} / {{prompt "Attendees"}}`, prompt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "Ann, Bo / 4.2 / Ann, Bo" {
+			t.Errorf("got %q", got)
+		}
+		if strings.Join(asked, ",") != "Attendees=,Room=4.2" {
+			t.Errorf("asked %v", asked)
+		}
+
+		got, _ = render(`{{prompt "Room" "4.2"}}`, nil)
+		if got != "4.2" {
+			t.Errorf("without a prompt func got %q, want the default", got)
+		}
+	})
+
+	t.Run("cancelled prompt", func(t *testing.T) {
+		_, err := render(`a {{prompt "Attendees"}}`, func(string, string) (string, error) {
+			return "", ErrTemplateCancelled
+		})
+		if !errors.Is(err, ErrTemplateCancelled) {
+			t.Errorf("err = %v, want ErrTemplateCancelled", err)
+		}
+	})
+
+	t.Run("includes", func(t *testing.T) {
+		data.SaveTemplate("footer", `-- {{title}} ({{prompt "Owner"}})`)
+		prompt := func(label, def string) (string, error) { return "me", nil }
+		got, err := render(`{{prompt "Owner"}}`+"\n"+`{{include "footer"}}`, prompt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "me\n-- standup (me)" {
+			t.Errorf("got %q", got)
+		}
+
+		data.SaveTemplate("loop", `{{include "t"}}`)
+		_, err = render(`{{include "loop"}}`, nil)
+		if err == nil || !strings.Contains(err.Error(), "t -> loop -> t") {
+			t.Errorf("include cycle err = %v", err)
+		}
+
+		_, err = render(`{{include "../x"}}`, nil)
+		if err == nil {
+			t.Error("include with path traversal should fail")
+		}
+	})
+
+	t.Run("legacy templates with literal braces", func(t *testing.T) {
+		tests := []struct {
+			template string
+			want     string
+		}{
+			// Don't parse and use no template syntax: copied as they are
+			{"Hello {{ user }}!\n", "Hello {{ user }}!\n"},
+			{"{{#each items}}{{/each}}", "{{#each items}}{{/each}}"},
+			{"a {{ b", "a {{ b"},
+			// The escape works in any template
+			{`{{"{{"}} {{title}} }}`, "{{ standup }}"},
+		}
+		for _, tt := range tests {
+			got, err := render(tt.template, nil)
+			if err != nil || got != tt.want {
+				t.Errorf("%q: got %q, %v; want %q", tt.template, got, err, tt.want)
+			}
+		}
+
+		// Template syntax that doesn't parse is still an error
+		if _, err := render("{{title}} and {{ b", nil); err == nil {
+			t.Error("broken template using placeholders should fail")
+		}
+	})
+
+	t.Run("errors name the template line", func(t *testing.T) {
+		tests := []struct {
+			template string
+			want     string
+		}{
+			{"ok\n\n{{date \"2006\" \"+1x\"}}", "template t, line 3:"},
+			{"{{title}}\n{{nope}}", "template t, line 2:"},
+			{"{{title", "template t, line 1:"},
+		}
+		for _, tt := range tests {
+			_, err := render(tt.template, nil)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("%q: err = %v, want prefix %q", tt.template, err, tt.want)
+			}
+		}
+
+		data.SaveTemplate("bad", "line one\n{{date \"2006\" \"soon\"}}")
+		_, err := render("a\n{{include \"bad\"}}", nil)
+		if err == nil || !strings.HasPrefix(err.Error(), "template bad, line 2:") {
+			t.Errorf("included template err = %v", err)
+		}
+	})
+}
+
+func TestCreateNoteFromTemplate(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+	data.SaveConfig(data.Config{NoteDir: notesDir, Editor: "true", TimestampNotes: "date"})
+
+	data.SaveTemplate("standup", "---\nname: {{prompt \"Team\"}}/standup {{date \"060102\"}}\ntags: standup, daily\ntimestamp: none\npin: true\n---\n.work\n# {{title}}\n")
+	data.SaveTemplate("plain", "body")
+	prompt := func(label, def string) (string, error) { return "web", nil }
+	today := time.Now().Format("060102")
+
+	t.Run("name pattern, tags and pin", func(t *testing.T) {
+		name, err := CreateNoteFromTemplate("", "standup", "", prompt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "web/standup " + today; name != want {
+			t.Fatalf("name = %q, want %q", name, want)
+		}
+		content, _ := os.ReadFile(data.NotePath(notesDir, name))
+		if want := ".work.standup.daily\n# standup " + today + "\n"; string(content) != want {
+			t.Errorf("content = %q, want %q", content, want)
+		}
+		index, _ := data.LoadIndex()
+		if tags := index[name].Tags; strings.Join(tags, ",") != "work,standup,daily" {
+			t.Errorf("tags = %v", tags)
+		}
+		pins, _ := data.LoadPins()
+		if _, ok := pins[name]; !ok {
+			t.Error("note not pinned")
+		}
+	})
+
+	t.Run("note is searchable after the editor closes", func(t *testing.T) {
+		name, err := CreateNoteFromTemplate("kickoff", "plain", "none", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fts, _ := data.LoadFTS()
+		if _, ok := fts.Docs[name]; !ok {
+			t.Errorf("%s missing from the FTS index", name)
+		}
+		if index, _ := data.LoadIndex(); index[name].LastVisited == "" {
+			t.Error("last visited not set")
+		}
+	})
+
+	t.Run("existing note is opened, not overwritten", func(t *testing.T) {
+		name, err := CreateNoteFromTemplate("", "standup", "", prompt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := os.ReadFile(data.NotePath(notesDir, name))
+		if strings.Count(string(content), "# standup") != 1 {
+			t.Errorf("content = %q", content)
+		}
+	})
+
+	t.Run("timestamp modes", func(t *testing.T) {
+		name, err := CreateNoteFromTemplate("retro", "plain", "", nil)
+		if err != nil || name != today+" retro" {
+			t.Errorf("config timestamp: name = %q, err = %v", name, err)
+		}
+		name, err = CreateNoteFromTemplate("retro", "standup", "", nil)
+		if err != nil || name != "retro" {
+			t.Errorf("template timestamp: name = %q, err = %v", name, err)
+		}
+		name, err = CreateNoteFromTemplate("planning", "standup", "date", nil)
+		if err != nil || name != today+" planning" {
+			t.Errorf("flag timestamp: name = %q, err = %v", name, err)
+		}
+	})
+
+	t.Run("no name and no pattern", func(t *testing.T) {
+		if _, err := CreateNoteFromTemplate("", "plain", "", nil); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("errors count front matter lines", func(t *testing.T) {
+		data.SaveTemplate("broken", "---\npin: true\n---\n{{title}}\n{{nope}}\n")
+		_, err := CreateNoteFromTemplate("x", "broken", "", nil)
+		if err == nil || !strings.HasPrefix(err.Error(), "template broken, line 5:") {
+			t.Errorf("err = %v", err)
+		}
+		if _, statErr := os.Stat(data.NotePath(notesDir, "x")); statErr == nil {
+			t.Error("note created despite template error")
+		}
+	})
+}
+
+func TestMergeTagLine(t *testing.T) {
+	tests := []struct {
+		content string
+		tags    []string
+		want    string
+	}{
+		{"# Title\n", []string{"a", "b"}, ".a.b\n# Title\n"},
+		{".a.c\nbody", []string{"a", "b"}, ".a.c.b\nbody"},
+		{".a", []string{"b"}, ".a.b"},
+		{"body", nil, "body"},
+		{"---\nstatus: open\n---\n# Title", []string{"a"}, "---\nstatus: open\n---\n.a\n# Title"},
+	}
+	for _, tt := range tests {
+		if got := mergeTagLine(tt.content, tt.tags); got != tt.want {
+			t.Errorf("mergeTagLine(%q, %v) = %q, want %q", tt.content, tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestPeriodicNotes(t *testing.T) {
+	_, notesDir, cleanup := testEnv(t)
+	defer cleanup()
+
+	t.Run("names", func(t *testing.T) {
+		day := time.Date(2026, 1, 1, 9, 0, 0, 0, time.Local)
+		tests := []struct {
+			period, want string
+			start        time.Time
+		}{
+			{PeriodDay, "260101", day},
+			{PeriodWeek, "26W01", time.Date(2025, 12, 29, 0, 0, 0, 0, time.Local)},
+			{PeriodMonth, "2601", time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)},
+		}
+		for _, tt := range tests {
+			base := PeriodicNoteBase(tt.period, day)
+			if base != tt.want {
+				t.Errorf("%s name = %q, want %q", tt.period, base, tt.want)
+			}
+			period, start, ok := ParsePeriodicName(base)
+			if !ok || period != tt.period || start.Format("060102") != tt.start.Format("060102") {
+				t.Errorf("ParsePeriodicName(%q) = %s, %v, %v", base, period, start, ok)
+			}
+		}
+		for _, bad := range []string{"26W54", "261301", "standup", "26W00"} {
+			if _, _, ok := ParsePeriodicName(bad); ok {
+				t.Errorf("ParsePeriodicName(%q) should fail", bad)
+			}
+		}
+	})
+
+	t.Run("offsets", func(t *testing.T) {
+		jan31 := time.Date(2026, 1, 31, 12, 0, 0, 0, time.Local)
+		if got := PeriodTime(PeriodMonth, jan31, 1).Format("060102"); got != "260228" {
+			t.Errorf("month after Jan 31 = %s", got)
+		}
+		if got := PeriodTime(PeriodWeek, jan31, -1).Format("060102"); got != "260124" {
+			t.Errorf("week before = %s", got)
+		}
+		if got := PeriodTime(PeriodDay, jan31, 1).Format("060102"); got != "260201" {
+			t.Errorf("next day = %s", got)
+		}
+	})
+
+	t.Run("create from the configured template", func(t *testing.T) {
+		data.SaveConfig(data.Config{NoteDir: notesDir, Editor: "true", TimestampNotes: "date", DailyTemplate: "day"})
+		data.SaveTemplate("day", "---\ntags: journal\n---\n# {{date \"2006-01-02\"}}\n")
+		name, err := OpenPeriodicNote(PeriodDay, -1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		yesterday := time.Now().AddDate(0, 0, -1)
+		if want := "journal/" + yesterday.Format("060102"); name != want {
+			t.Fatalf("name = %q, want %q", name, want)
+		}
+		content, _ := os.ReadFile(data.NotePath(notesDir, name))
+		if want := ".journal\n# " + yesterday.Format("2006-01-02") + "\n"; string(content) != want {
+			t.Errorf("content = %q, want %q", content, want)
+		}
+
+		// Opening it again leaves it alone
+		os.WriteFile(data.NotePath(notesDir, name), []byte("edited"), 0644)
+		if _, err := OpenPeriodicNote(PeriodDay, -1, nil); err != nil {
+			t.Fatal(err)
+		}
+		if content, _ := os.ReadFile(data.NotePath(notesDir, name)); string(content) != "edited" {
+			t.Errorf("existing note rewritten: %q", content)
+		}
+	})
+
+	t.Run("built-in template and listing", func(t *testing.T) {
+		name, err := OpenPeriodicNote(PeriodMonth, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := os.ReadFile(data.NotePath(notesDir, name))
+		if want := "# " + time.Now().Format("January 2006") + "\n\n"; string(content) != want {
+			t.Errorf("content = %q", content)
+		}
+		createTestNote(t, notesDir, "standup", "not a journal note")
+
+		notes, err := ListPeriodicNotes("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(notes) != 2 || !notes[0].Start.After(notes[1].Start) {
+			t.Errorf("notes = %+v, want both, newest first", notes)
+		}
+		if notes, _ := ListPeriodicNotes(PeriodMonth); len(notes) != 1 || notes[0].Name != name {
+			t.Errorf("monthly notes = %+v", notes)
+		}
+	})
+}
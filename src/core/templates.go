@@ -3,7 +3,9 @@ package core
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"gote/src/data"
@@ -39,58 +41,210 @@ func CreateOrEditTemplate(name string) error {
 	return data.OpenFileInEditor(templatePath, cfg.Editor)
 }
 
-// CreateNoteFromTemplate creates a new note with content from a template
-func CreateNoteFromTemplate(noteName, templateName string) error {
-	if err := data.ValidateNoteName(noteName); err != nil {
-		return err
+// TemplateInfo is a template and the settings its front matter declares
+type TemplateInfo struct {
+	Name     string
+	Settings data.TemplateSettings
+	Err      error // front matter that doesn't parse
+}
+
+// ListTemplateInfo returns every template with its settings
+func ListTemplateInfo() ([]TemplateInfo, error) {
+	names, err := data.ListTemplateFiles()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]TemplateInfo, 0, len(names))
+	for _, name := range names {
+		settings, _, _, err := loadTemplate(name)
+		infos = append(infos, TemplateInfo{Name: name, Settings: settings, Err: err})
+	}
+	return infos, nil
+}
+
+// TimestampNoteName prefixes the note (not its notebook) with the date or
+// date and time, per mode ("date", "datetime"; anything else leaves it)
+func TimestampNoteName(noteName, mode string, now time.Time) string {
+	notebook, base := data.NotebookOf(noteName), data.BaseName(noteName)
+	switch mode {
+	case "date":
+		return path.Join(notebook, now.Format("060102")+" "+base)
+	case "datetime":
+		return path.Join(notebook, now.Format("060102-150405")+" "+base)
+	}
+	return noteName
+}
+
+// CreateNoteFromTemplate creates a new note from a template, rendering its
+// placeholders (see RenderTemplate) and applying its settings. With no
+// noteName the template's name pattern is used. timestamp overrides the
+// template's and the config's timestamp mode when set. prompt asks for
+// {{prompt}} values. If the resulting note already exists it is opened
+// instead. Returns the note's name.
+func CreateNoteFromTemplate(noteName, templateName, timestamp string, prompt PromptFunc) (string, error) {
+	settings, body, bodyLine, err := loadTemplate(templateName)
+	if err != nil {
+		return "", err
 	}
+	tmpl := noteTemplate{name: templateName, settings: settings, body: body, bodyLine: bodyLine}
+	return createNoteFromTemplate(noteName, tmpl, timestamp, time.Now(), prompt)
+}
 
+// noteTemplate is a loaded template, from a file or built in
+type noteTemplate struct {
+	name     string
+	settings data.TemplateSettings
+	body     string
+	bodyLine int
+}
+
+// createNoteFromTemplate is CreateNoteFromTemplate for a loaded template,
+// rendered as of now
+func createNoteFromTemplate(noteName string, tmpl noteTemplate, timestamp string, now time.Time, prompt PromptFunc) (string, error) {
+	return newNote(noteName, tmpl, timestamp, now, prompt, nil)
+}
+
+// newNote creates a note from tmpl and opens it in the editor, or opens the
+// note if it exists. With text it instead adds text after the template and
+// writes the note without opening it, failing if it exists.
+func newNote(noteName string, tmpl noteTemplate, timestamp string, now time.Time, prompt PromptFunc, text *string) (string, error) {
 	cfg, err := data.LoadConfig()
 	if err != nil {
-		return fmt.Errorf("error loading config: %w", err)
+		return "", fmt.Errorf("error loading config: %w", err)
+	}
+	templateName, settings := tmpl.name, tmpl.settings
+
+	r := newTemplateRenderer(TemplateContext{Now: now, Prompt: prompt})
+	named := noteName != ""
+	if !named {
+		if settings.Name == "" {
+			return "", fmt.Errorf("template %s has no name pattern; give a note name", templateName)
+		}
+		if noteName, err = r.execute(templateName, settings.Name, settings.NameLine); err != nil {
+			return "", err
+		}
+		noteName = strings.TrimSpace(noteName)
+	}
+
+	// A generated name already says what it wants, so the config default
+	// only applies to names the user typed
+	mode := timestamp
+	if mode == "" {
+		mode = settings.Timestamp
+	}
+	if mode == "" && named {
+		mode = cfg.TimestampNotes
+	}
+	noteName = TimestampNoteName(noteName, mode, now)
+	if err := data.ValidateNotePath(noteName); err != nil {
+		return "", err
 	}
 
-	// Check if note already exists
 	index, err := data.LoadIndex()
 	if err != nil {
-		return fmt.Errorf("loading index: %w", err)
+		return "", fmt.Errorf("loading index: %w", err)
 	}
-	if _, exists := index[noteName]; exists {
-		return fmt.Errorf("note already exists: %s", noteName)
+	// A note not indexed yet is still never overwritten
+	notePath := data.NotePath(cfg.NoteDir, noteName)
+	_, indexed := index[noteName]
+	if _, err := os.Stat(notePath); indexed || err == nil {
+		if text != nil {
+			return noteName, fmt.Errorf("%w: %s", ErrNoteExists, noteName)
+		}
+		return noteName, CreateOrOpenNote(noteName)
 	}
 
-	// Load template content
-	content, err := data.LoadTemplate(templateName)
+	// Render before creating anything, so a bad template or a cancelled
+	// prompt leaves no note behind
+	r.ctx.NoteName = noteName
+	content, err := r.execute(templateName, tmpl.body, tmpl.bodyLine)
 	if err != nil {
-		return err
+		return "", err
+	}
+	content = mergeTagLine(content, settings.Tags)
+	if text != nil {
+		content = appendBlock(content, *text, false)
 	}
 
 	// Create note with template content
 	noteDir := cfg.NoteDir
 	if err := os.MkdirAll(noteDir, 0755); err != nil {
-		return fmt.Errorf("error creating notes directory: %w", err)
+		return "", fmt.Errorf("error creating notes directory: %w", err)
 	}
 
-	notePath := filepath.Join(noteDir, noteName+".md")
+	if err := os.MkdirAll(filepath.Dir(notePath), 0755); err != nil {
+		return "", fmt.Errorf("error creating notebook: %w", err)
+	}
 	if err := os.WriteFile(notePath, []byte(content), 0644); err != nil {
-		return fmt.Errorf("error creating note: %w", err)
+		return "", fmt.Errorf("error creating note: %w", err)
+	}
+	if text != nil {
+		if err := snapshotNote(noteName, notePath); err != nil {
+			return "", err
+		}
+		if err := data.IndexNote(notePath); err != nil {
+			return "", fmt.Errorf("error indexing note: %w", err)
+		}
+		return noteName, pinNew(noteName, settings)
 	}
 
 	// Open in editor
 	if err := data.OpenFileInEditor(notePath, cfg.Editor); err != nil {
-		return fmt.Errorf("error opening note in editor: %w", err)
+		return "", fmt.Errorf("error opening note in editor: %w", err)
+	}
+
+	if err := snapshotNote(noteName, notePath); err != nil {
+		return "", err
 	}
 
 	// Index the note after editing
-	info, err := os.Stat(notePath)
-	if err != nil {
-		return fmt.Errorf("error stating note after edit: %w", err)
+	if err := data.IndexNote(notePath); err != nil {
+		return "", fmt.Errorf("error indexing note: %w", err)
 	}
-	meta, err := data.BuildNoteMeta(notePath, info)
-	if err != nil {
-		return fmt.Errorf("error building note metadata: %w", err)
+	if err := UpdateLastVisited(noteName); err != nil {
+		return "", err
+	}
+	return noteName, pinNew(noteName, settings)
+}
+
+// pinNew pins a note just created from a template that asks for it
+func pinNew(noteName string, settings data.TemplateSettings) error {
+	if !settings.Pin {
+		return nil
+	}
+	return data.WithPinsLock(func(pins map[string]data.EmptyStruct) error {
+		pins[noteName] = data.EmptyStruct{}
+		return nil
+	})
+}
+
+// mergeTagLine adds tags to the note's first-line tags, or gives it a tag
+// line if it has none. The tag line goes after any front matter.
+func mergeTagLine(content string, tags []string) string {
+	if len(tags) == 0 {
+		return content
+	}
+	if _, body, ok := data.SplitFrontMatter(content); ok {
+		return content[:len(content)-len(body)] + mergeTagLine(body, tags)
+	}
+	first, rest, _ := strings.Cut(content, "\n")
+	existing := data.ParseTags(strings.TrimSpace(first))
+	if existing == nil {
+		return "." + strings.Join(tags, ".") + "\n" + content
+	}
+	have := make(map[string]bool, len(existing))
+	for _, tag := range existing {
+		have[tag] = true
+	}
+	line := strings.TrimRight(first, " \r")
+	for _, tag := range tags {
+		if !have[tag] {
+			line += "." + tag
+			have[tag] = true
+		}
+	}
+	if !strings.Contains(content, "\n") {
+		return line
 	}
-	meta.LastVisited = time.Now().Format("060102.150405")
-	index[noteName] = meta
-	return data.SaveIndexWithTags(index)
+	return line + "\n" + rest
 }
@@ -1,11 +1,35 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"gote/src/data"
 )
 
+// PurgeError reports that a note was trashed but purging expired trash
+// afterwards failed. The delete itself succeeded.
+type PurgeError struct {
+	Err error
+}
+
+func (e *PurgeError) Error() string {
+	return "purging old trash failed: " + e.Err.Error()
+}
+
+func (e *PurgeError) Unwrap() error {
+	return e.Err
+}
+
+// IsPurgeError reports whether err is only a failed purge after a delete
+func IsPurgeError(err error) bool {
+	var purgeErr *PurgeError
+	return errors.As(err, &purgeErr)
+}
+
+// DeleteNote moves a note to the trash, then purges trash past the configured
+// retention. A failed purge is returned as a *PurgeError.
 func DeleteNote(noteName string) error {
 	index, err := data.LoadIndex()
 	if err != nil {
@@ -15,7 +39,40 @@ func DeleteNote(noteName string) error {
 	if !exists {
 		return fmt.Errorf("note not found: %s", noteName)
 	}
-	return data.TrashNote(actualName, noteMeta)
+	if err := data.TrashNote(actualName, noteMeta); err != nil {
+		return err
+	}
+	if _, err := PurgeExpiredTrash(); err != nil {
+		return &PurgeError{Err: err}
+	}
+	return nil
+}
+
+// ListTrash returns the trashed notes, most recent first, after purging
+// any past the configured retention
+func ListTrash() ([]data.TrashEntry, error) {
+	if _, err := PurgeExpiredTrash(); err != nil {
+		return nil, err
+	}
+	return data.ListTrash()
+}
+
+// FindTrashed returns the trashed notes called noteName, most recent first
+func FindTrashed(noteName string) ([]data.TrashEntry, error) {
+	if _, err := PurgeExpiredTrash(); err != nil {
+		return nil, err
+	}
+	return data.FindTrashed(noteName)
+}
+
+// PurgeExpiredTrash permanently deletes notes trashed more than trashDays
+// ago. Returns how many were deleted.
+func PurgeExpiredTrash() (int, error) {
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		return 0, fmt.Errorf("loading config: %w", err)
+	}
+	return data.PurgeExpiredTrash(time.Duration(cfg.TrashDays) * 24 * time.Hour)
 }
 
 func RecoverNote(noteName string) error {
@@ -25,3 +82,12 @@ func RecoverNote(noteName string) error {
 	}
 	return data.RecoverNote(noteName, cfg.NoteDir)
 }
+
+// RecoverTrashEntry restores one trashed note by id and returns its name
+func RecoverTrashEntry(id string) (string, error) {
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		return "", fmt.Errorf("loading config: %w", err)
+	}
+	return data.RecoverTrashEntry(id, cfg.NoteDir)
+}
@@ -0,0 +1,336 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gote/src/data"
+)
+
+// DefaultDebounce is how long the watcher waits for a burst of saves to settle
+const DefaultDebounce = 300 * time.Millisecond
+
+// WatchEvent describes one change the watcher applied to the index
+type WatchEvent struct {
+	Kind    string // "indexed", "removed" or "renamed"
+	Name    string
+	OldName string // set for renames
+}
+
+// WatchOptions configures Watch
+type WatchOptions struct {
+	Poll         bool          // skip native notifications and poll
+	PollInterval time.Duration // polling period (data.DefaultPollInterval if zero)
+	Debounce     time.Duration // quiet period before syncing (DefaultDebounce if zero)
+	OnStart      func(dir, backend string)
+	OnEvent      func(WatchEvent)
+	OnError      func(error)
+}
+
+// NoteChanges is a batch of edits made to note files outside gote
+type NoteChanges struct {
+	Updated []string          // created or modified note paths
+	Removed []string          // deleted note paths
+	Renamed map[string]string // old path -> new path
+}
+
+func (c NoteChanges) empty() bool {
+	return len(c.Updated) == 0 && len(c.Removed) == 0 && len(c.Renamed) == 0
+}
+
+// Watch keeps the index in sync with the notes directory until ctx is
+// cancelled. It first catches up on changes made while it wasn't running,
+// then applies edits in debounced batches.
+func Watch(ctx context.Context, opts WatchOptions) error {
+	cfg, err := data.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+	root := cfg.NoteDir
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("error creating notes directory: %w", err)
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	onEvent := opts.OnEvent
+	if onEvent == nil {
+		onEvent = func(WatchEvent) {}
+	}
+	onError := opts.OnError
+	if onError == nil {
+		onError = func(error) {}
+	}
+
+	// Start watching before the catch-up scan so nothing slips in between
+	w, err := data.NewWatcher(root, opts.Poll, opts.PollInterval)
+	if err != nil {
+		return fmt.Errorf("watching notes: %w", err)
+	}
+	defer w.Close()
+	if opts.OnStart != nil {
+		opts.OnStart(root, w.Backend())
+	}
+
+	known, err := data.ScanNotes(root)
+	if err != nil {
+		return fmt.Errorf("scanning notes: %w", err)
+	}
+	changes, err := staleNotes(root, known)
+	if err != nil {
+		return err
+	}
+	if err := applyNoteChanges(root, changes, onEvent); err != nil {
+		onError(err)
+	}
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(opts.Debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case path, ok := <-w.Events():
+			if !ok {
+				return nil
+			}
+			pending[path] = true
+			timer.Reset(opts.Debounce)
+		case err := <-w.Errors():
+			onError(err)
+		case <-timer.C:
+			changes := collectChanges(root, pending, known)
+			pending = make(map[string]bool)
+			if err := applyNoteChanges(root, changes, onEvent); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// staleNotes compares the notes on disk against the index: files that are
+// new or modified since they were indexed, and entries whose file is gone
+func staleNotes(root string, files map[string]os.FileInfo) (NoteChanges, error) {
+	index, err := data.LoadIndex()
+	if err != nil {
+		return NoteChanges{}, fmt.Errorf("loading index: %w", err)
+	}
+	var changes NoteChanges
+	for path, info := range files {
+		meta, ok := index[data.NoteName(root, path)]
+		if !ok || meta.FilePath != path || meta.Modified != info.ModTime().Format(timeFmt) {
+			changes.Updated = append(changes.Updated, path)
+		}
+	}
+	for _, meta := range index {
+		if _, ok := files[meta.FilePath]; !ok {
+			changes.Removed = append(changes.Removed, meta.FilePath)
+		}
+	}
+	sort.Strings(changes.Updated)
+	sort.Strings(changes.Removed)
+	return changes, nil
+}
+
+// collectChanges turns a set of raw event paths into note changes, updating
+// known (the last seen state of every note file) to match. A deleted path
+// and a new path that are the same file on disk are reported as a rename.
+func collectChanges(root string, pending map[string]bool, known map[string]os.FileInfo) NoteChanges {
+	var removed []string
+	current := make(map[string]os.FileInfo)
+	for path := range pending {
+		info, err := os.Stat(path)
+		if err != nil {
+			// Gone: the path may be a note or a whole notebook
+			for k := range known {
+				if k == path || strings.HasPrefix(k, path+string(filepath.Separator)) {
+					removed = append(removed, k)
+				}
+			}
+			continue
+		}
+		if info.IsDir() {
+			files, err := data.ScanNotes(path)
+			if err != nil {
+				continue
+			}
+			for p, fi := range files {
+				if data.IsNoteFile(root, p) {
+					current[p] = fi
+				}
+			}
+			// Notes removed from a directory that still exists
+			for k := range known {
+				if strings.HasPrefix(k, path+string(filepath.Separator)) {
+					if _, ok := files[k]; !ok {
+						removed = append(removed, k)
+					}
+				}
+			}
+			continue
+		}
+		if info.Mode().IsRegular() && data.IsNoteFile(root, path) {
+			current[path] = info
+		}
+	}
+
+	changes := NoteChanges{Renamed: make(map[string]string)}
+	sort.Strings(removed)
+	for _, old := range removed {
+		oldInfo := known[old]
+		delete(known, old)
+		if _, stillThere := current[old]; stillThere {
+			continue
+		}
+		renamed := false
+		for path, info := range current {
+			if _, seen := known[path]; seen {
+				continue
+			}
+			if os.SameFile(oldInfo, info) {
+				changes.Renamed[old] = path
+				known[path] = info
+				delete(current, path)
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			changes.Removed = append(changes.Removed, old)
+		}
+	}
+
+	for path, info := range current {
+		prev, ok := known[path]
+		known[path] = info
+		if ok && prev.Size() == info.Size() && prev.ModTime().Equal(info.ModTime()) && os.SameFile(prev, info) {
+			continue // nothing changed, e.g. a duplicate event
+		}
+		changes.Updated = append(changes.Updated, path)
+	}
+	sort.Strings(changes.Updated)
+	return changes
+}
+
+// applyNoteChanges updates the index, FTS index, pins and history for a
+// batch of changes, holding the index lock for the whole batch
+func applyNoteChanges(root string, changes NoteChanges, onEvent func(WatchEvent)) error {
+	if changes.empty() {
+		return nil
+	}
+	var events []WatchEvent
+	err := data.WithIndexLock(func(index map[string]data.NoteMeta) error {
+		pinRenames := make(map[string]string)
+		var unpinned []string
+
+		olds := make([]string, 0, len(changes.Renamed))
+		for old := range changes.Renamed {
+			olds = append(olds, old)
+		}
+		sort.Strings(olds)
+		for _, oldPath := range olds {
+			newPath := changes.Renamed[oldPath]
+			oldName, newName := data.NoteName(root, oldPath), data.NoteName(root, newPath)
+			prev, existed := index[oldName]
+			delete(index, oldName)
+			if err := data.RemoveDocFTS(oldName); err != nil {
+				return fmt.Errorf("removing from FTS index: %w", err)
+			}
+			if err := indexNoteFile(index, root, newPath, prev, existed); err != nil {
+				return err
+			}
+			if existed {
+				if err := data.RenameHistory(oldName, newName); err != nil {
+					return fmt.Errorf("error moving note history: %w", err)
+				}
+				pinRenames[oldName] = newName
+			}
+			events = append(events, WatchEvent{Kind: "renamed", Name: newName, OldName: oldName})
+		}
+
+		for _, path := range changes.Removed {
+			name := data.NoteName(root, path)
+			if _, ok := index[name]; !ok {
+				continue
+			}
+			delete(index, name)
+			if err := data.RemoveDocFTS(name); err != nil {
+				return fmt.Errorf("removing from FTS index: %w", err)
+			}
+			unpinned = append(unpinned, name)
+			events = append(events, WatchEvent{Kind: "removed", Name: name})
+		}
+
+		for _, path := range changes.Updated {
+			name := data.NoteName(root, path)
+			prev, existed := index[name]
+			if err := indexNoteFile(index, root, path, prev, existed); err != nil {
+				if os.IsNotExist(err) {
+					continue // deleted again before we got to it
+				}
+				return err
+			}
+			events = append(events, WatchEvent{Kind: "indexed", Name: name})
+		}
+
+		if len(pinRenames) == 0 && len(unpinned) == 0 {
+			return nil
+		}
+		return data.WithPinsLock(func(pins map[string]data.EmptyStruct) error {
+			for old, name := range pinRenames {
+				if _, pinned := pins[old]; pinned {
+					delete(pins, old)
+					pins[name] = data.EmptyStruct{}
+				}
+			}
+			for _, name := range unpinned {
+				delete(pins, name)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for _, ev := range events {
+		onEvent(ev)
+	}
+	return nil
+}
+
+// indexNoteFile rebuilds the metadata and FTS entry of the note at path,
+// keeping Created and LastVisited from prev
+func indexNoteFile(index map[string]data.NoteMeta, root, path string, prev data.NoteMeta, existed bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	name := data.NoteName(root, path)
+	meta, err := data.BuildNoteMeta(path, info)
+	if err != nil {
+		return err
+	}
+	meta.Title = name
+	if existed {
+		if prev.Created != "" {
+			meta.Created = prev.Created
+		}
+		meta.LastVisited = prev.LastVisited
+	}
+	index[name] = meta
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading note for FTS: %w", err)
+	}
+	if err := data.IndexDocFTS(name, path, string(content)); err != nil {
+		return fmt.Errorf("updating FTS index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gote/src/data"
+)
+
+// waitFor polls cond until it holds or the deadline passes
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func indexHas(name string) func() bool {
+	return func() bool {
+		index, _ := data.LoadIndex()
+		_, ok := index[name]
+		return ok
+	}
+}
+
+func TestWatch(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		name := "native"
+		if poll {
+			name = "polling"
+		}
+		t.Run(name, func(t *testing.T) {
+			_, notesDir, cleanup := testEnv(t)
+			defer cleanup()
+
+			createTestNote(t, notesDir, "keep", "Keep me")
+			createTestNote(t, notesDir, "pinned", "Pinned note")
+			createTestNote(t, notesDir, "doomed", "Delete me")
+			// Written while the watcher isn't running: picked up on start
+			os.WriteFile(filepath.Join(notesDir, "offline.md"), []byte("made offline"), 0644)
+			if err := PinNote("pinned"); err != nil {
+				t.Fatal(err)
+			}
+			index, _ := data.LoadIndex()
+			created := index["pinned"].Created
+
+			ctx, cancel := context.WithCancel(context.Background())
+			started := make(chan struct{})
+			done := make(chan error, 1)
+			go func() {
+				done <- Watch(ctx, WatchOptions{
+					Poll:         poll,
+					PollInterval: 20 * time.Millisecond,
+					Debounce:     30 * time.Millisecond,
+					OnStart:      func(string, string) { close(started) },
+					OnError:      func(err error) { t.Errorf("watch error: %v", err) },
+				})
+			}()
+			<-started
+			waitFor(t, "catch-up of offline note", indexHas("offline"))
+
+			t.Run("create in new notebook", func(t *testing.T) {
+				os.MkdirAll(filepath.Join(notesDir, "work"), 0755)
+				os.WriteFile(filepath.Join(notesDir, "work", "plan.md"), []byte(".work\nquarterly plan"), 0644)
+				waitFor(t, "work/plan indexed", indexHas("work/plan"))
+				index, _ := data.LoadIndex()
+				if tags := index["work/plan"].Tags; len(tags) != 1 || tags[0] != "work" {
+					t.Errorf("tags = %v", tags)
+				}
+				waitFor(t, "FTS entry", func() bool {
+					results, _ := SearchNotesFullText("quarterly", -1)
+					return len(results) == 1
+				})
+			})
+
+			t.Run("modify", func(t *testing.T) {
+				os.WriteFile(filepath.Join(notesDir, "keep.md"), []byte("Keep me around with zeppelins"), 0644)
+				waitFor(t, "keep reindexed", func() bool {
+					results, _ := SearchNotesFullText("zeppelins", -1)
+					return len(results) == 1 && results[0].Title == "keep"
+				})
+			})
+
+			t.Run("delete", func(t *testing.T) {
+				os.Remove(filepath.Join(notesDir, "doomed.md"))
+				waitFor(t, "doomed removed", func() bool { return !indexHas("doomed")() })
+			})
+
+			t.Run("rename keeps pins and created", func(t *testing.T) {
+				os.Rename(filepath.Join(notesDir, "pinned.md"), filepath.Join(notesDir, "moved.md"))
+				waitFor(t, "moved indexed", indexHas("moved"))
+				if indexHas("pinned")() {
+					t.Error("old name still indexed")
+				}
+				index, _ := data.LoadIndex()
+				if got := index["moved"].Created; got != created {
+					t.Errorf("Created = %q, want %q", got, created)
+				}
+				pins, _ := data.LoadPins()
+				if _, ok := pins["moved"]; !ok {
+					t.Errorf("pin not carried over: %v", pins)
+				}
+			})
+
+			t.Run("ignores hidden and non-note files", func(t *testing.T) {
+				os.MkdirAll(filepath.Join(notesDir, ".git"), 0755)
+				os.WriteFile(filepath.Join(notesDir, ".git", "HEAD.md"), []byte("x"), 0644)
+				os.WriteFile(filepath.Join(notesDir, "scratch.txt"), []byte("x"), 0644)
+				os.WriteFile(filepath.Join(notesDir, "marker.md"), []byte("x"), 0644)
+				waitFor(t, "marker indexed", indexHas("marker"))
+				index, _ := data.LoadIndex()
+				for name := range index {
+					if name == ".git/HEAD" || name == "scratch" {
+						t.Errorf("indexed %q", name)
+					}
+				}
+			})
+
+			cancel()
+			if err := <-done; err != nil {
+				t.Errorf("Watch returned %v", err)
+			}
+		})
+	}
+}
+
+func TestCollectChanges(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string) string {
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(name), 0644)
+		return p
+	}
+	a, b := write("a.md"), write("nb/b.md")
+	known, err := data.ScanNotes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rename a.md, drop the whole notebook, add a new note
+	renamed := filepath.Join(dir, "renamed.md")
+	os.Rename(a, renamed)
+	os.RemoveAll(filepath.Join(dir, "nb"))
+	c := write("c.md")
+
+	changes := collectChanges(dir, map[string]bool{
+		a: true, renamed: true, filepath.Join(dir, "nb"): true, c: true,
+	}, known)
+	if changes.Renamed[a] != renamed {
+		t.Errorf("Renamed = %v", changes.Renamed)
+	}
+	if len(changes.Removed) != 1 || changes.Removed[0] != b {
+		t.Errorf("Removed = %v", changes.Removed)
+	}
+	if len(changes.Updated) != 1 || changes.Updated[0] != c {
+		t.Errorf("Updated = %v", changes.Updated)
+	}
+
+	// A repeated event for an unchanged file is a no-op
+	if again := collectChanges(dir, map[string]bool{c: true}, known); !again.empty() {
+		t.Errorf("expected no changes, got %+v", again)
+	}
+}
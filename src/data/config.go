@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 type Config struct {
@@ -13,6 +14,13 @@ type Config struct {
 	Interface       string `json:"interface"`       // "default", "minimal", "tui"
 	TimestampNotes  string `json:"timestampNotes"`  // "none", "date", "datetime"
 	DefaultPageSize int    `json:"defaultPageSize"` // default number of results to show
+	HistoryVersions int    `json:"historyVersions"` // max snapshots kept per note
+	HistoryDays     int    `json:"historyDays"`     // drop snapshots older than this, 0 = keep
+	TrashDays       int    `json:"trashDays"`       // purge trashed notes older than this, 0 = keep
+	JournalNotebook string `json:"journalNotebook"` // notebook for daily/weekly/monthly notes, "/" = top level
+	DailyTemplate   string `json:"dailyTemplate"`   // template for new daily notes
+	WeeklyTemplate  string `json:"weeklyTemplate"`  // template for new weekly notes
+	MonthlyTemplate string `json:"monthlyTemplate"` // template for new monthly notes
 }
 
 // IsTUI returns true if the interface mode is "tui"
@@ -45,6 +53,22 @@ func (c Config) PageSize() int {
 	return c.DefaultPageSize
 }
 
+// HistoryLimit returns the effective number of snapshots kept per note
+func (c Config) HistoryLimit() int {
+	if c.HistoryVersions <= 0 {
+		return 50
+	}
+	return c.HistoryVersions
+}
+
+// JournalDir returns the notebook periodic notes go in, "" for the top level
+func (c Config) JournalDir() string {
+	if c.JournalNotebook == "" {
+		return "journal"
+	}
+	return strings.Trim(c.JournalNotebook, "/")
+}
+
 // GoteDir returns the gote config directory. It's a variable so tests can override it.
 var GoteDir = func() string {
 	homeDir, err := os.UserHomeDir()
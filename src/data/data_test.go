@@ -4,7 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 // testDir creates a temp directory and returns a cleanup function
@@ -83,6 +85,28 @@ func TestValidateNoteName(t *testing.T) {
 	}
 }
 
+func TestEditorArgs(t *testing.T) {
+	tests := []struct {
+		editor string
+		line   int
+		want   string
+	}{
+		{"vim", 0, "n.md"},
+		{"/usr/bin/nvim", 12, "+12 n.md"},
+		{"nano", 3, "+3 n.md"},
+		{"code", 7, "--goto n.md:7"},
+		{"subl", 7, "n.md:7"},
+		{"gedit", 7, "+7 n.md"},
+		{"unknown-editor", 7, "n.md"},
+	}
+	for _, tt := range tests {
+		got := strings.Join(editorArgs(tt.editor, "n.md", tt.line), " ")
+		if got != tt.want {
+			t.Errorf("editorArgs(%q, %d) = %q, want %q", tt.editor, tt.line, got, tt.want)
+		}
+	}
+}
+
 // --- Index tests ---
 
 func TestIndexOperations(t *testing.T) {
@@ -174,6 +198,53 @@ It has multiple lines.`
 	}
 }
 
+func TestParseFrontMatter(t *testing.T) {
+	content := "---\ntags: [Work, .project]\naliases:\n  - Q3 plan\n  - 'roadmap'\nstatus: open # for now\nowner: \"alice\"\nreviewers: [bob, carol]\nnested:\n  key: ignored\n---\n.urgent\nBody"
+	fm, body := ParseFrontMatter(content)
+	if body != ".urgent\nBody" {
+		t.Errorf("body = %q", body)
+	}
+	if !reflect.DeepEqual(fm.Tags, []string{"work", "project"}) {
+		t.Errorf("Tags = %v", fm.Tags)
+	}
+	if !reflect.DeepEqual(fm.Aliases, []string{"Q3 plan", "roadmap"}) {
+		t.Errorf("Aliases = %v", fm.Aliases)
+	}
+	want := map[string]string{"status": "open", "owner": "alice", "reviewers": "bob, carol", "nested": ""}
+	if !reflect.DeepEqual(fm.Properties, want) {
+		t.Errorf("Properties = %v, want %v", fm.Properties, want)
+	}
+
+	for _, text := range []string{"no front matter", "---\nunclosed: yes\n", "\n---\nstatus: late\n---\n"} {
+		if fm, body := ParseFrontMatter(text); body != text || fm.Properties != nil {
+			t.Errorf("%q: got %+v, %q", text, fm, body)
+		}
+	}
+
+	t.Run("BuildNoteMeta merges front matter and tag line", func(t *testing.T) {
+		dir, cleanup := testDir(t)
+		defer cleanup()
+		notePath := filepath.Join(dir, "plan.md")
+		os.WriteFile(notePath, []byte(content), 0644)
+		info, _ := os.Stat(notePath)
+		meta, err := BuildNoteMeta(notePath, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(meta.Tags, []string{"urgent", "work", "project"}) {
+			t.Errorf("Tags = %v", meta.Tags)
+		}
+		if meta.Properties["status"] != "open" || len(meta.Aliases) != 2 {
+			t.Errorf("meta = %+v", meta)
+		}
+
+		index := map[string]NoteMeta{"plan": meta, "other": {}}
+		if key, _, ok := LookupNote(index, "q3 plan"); !ok || key != "plan" {
+			t.Errorf("alias lookup = %q, %v", key, ok)
+		}
+	})
+}
+
 // --- Tags tests ---
 
 func TestTagsOperations(t *testing.T) {
@@ -243,10 +314,205 @@ func TestTagsOperations(t *testing.T) {
 			t.Errorf("personal count = %d, want 1", tags["personal"].Count)
 		}
 	})
+
+	t.Run("hierarchical tags roll up", func(t *testing.T) {
+		tags := BuildTagsIndex(map[string]NoteMeta{
+			"a": {FilePath: "/a.md", Tags: []string{"work/clients/acme", "work/clients/globex"}},
+			"b": {FilePath: "/b.md", Tags: []string{"work/clients"}},
+			"c": {FilePath: "/c.md", Tags: []string{"work"}},
+		})
+		want := map[string][2]int{ // count, total
+			"work":                {1, 3},
+			"work/clients":        {1, 2},
+			"work/clients/acme":   {1, 1},
+			"work/clients/globex": {1, 1},
+		}
+		if len(tags) != len(want) {
+			t.Errorf("tags = %v", tags)
+		}
+		for tag, w := range want {
+			if got := tags[tag]; got.Count != w[0] || got.Total != w[1] {
+				t.Errorf("%s: count %d total %d, want %v", tag, got.Count, got.Total, w)
+			}
+		}
+	})
+}
+
+func TestTagHierarchy(t *testing.T) {
+	if got := NormalizeTag(" Work//Clients/ "); got != "work/clients" {
+		t.Errorf("NormalizeTag = %q", got)
+	}
+	if got := TagAncestors("work/clients/acme"); !reflect.DeepEqual(got, []string{"work/clients", "work"}) {
+		t.Errorf("TagAncestors = %v", got)
+	}
+	if TagAncestors("work") != nil {
+		t.Error("top-level tag has no ancestors")
+	}
+	tests := []struct {
+		tag, filter string
+		exact, want bool
+	}{
+		{"work/clients/acme", "work", false, true},
+		{"work/clients/acme", "work/clients", false, true},
+		{"work/clients/acme", "work", true, false},
+		{"work", "work", true, true},
+		{"workshop", "work", false, false},
+		{"work", "work/clients", false, false},
+	}
+	for _, tt := range tests {
+		if got := TagMatches(tt.tag, tt.filter, tt.exact); got != tt.want {
+			t.Errorf("TagMatches(%q, %q, %v) = %v", tt.tag, tt.filter, tt.exact, got)
+		}
+	}
+	if got := ParseTags(".Work/Clients/Acme.urgent"); !reflect.DeepEqual(got, []string{"work/clients/acme", "urgent"}) {
+		t.Errorf("ParseTags = %v", got)
+	}
+}
+
+func TestRewriteTags(t *testing.T) {
+	rename := func(tag string) (string, bool) {
+		if TagMatches(tag, "work", false) {
+			return "job" + tag[len("work"):], true
+		}
+		return tag, tag != "old"
+	}
+	tests := []struct {
+		name, text, want string
+	}{
+		{"tag line", ".work.home\nbody .work", ".job.home\nbody .work"},
+		{"decoration", ".#Work.[home] | .work/clients\r\n", ".#job.[home] | .job/clients\r\n"},
+		{"removed", ".home.old.work\n", ".home.job\n"},
+		{"empty line dropped", ".old\n# Title\n", "# Title\n"},
+		{"duplicates dropped", ".job.work.job/x\n", ".job.job/x\n"},
+		{"not a tag line", "# Title\n.work\n", "# Title\n.work\n"},
+		{"inline list", "---\ntags: [work, \"old\", home]\n---\n.work\n", "---\ntags: [job, home]\n---\n.job\n"},
+		{"block list", "---\ntags:\n  - work/a  # main\n  - old\n  - 'home'\nstatus: work\n---\nx", "---\ntags:\n  - job/a  # main\n  - 'home'\nstatus: work\n---\nx"},
+		{"scalar", "---\ntag: old\naliases: [work]\n---\n", "---\naliases: [work]\n---\n"},
+	}
+	for _, tt := range tests {
+		if got := RewriteTags(tt.text, rename); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTasks(t *testing.T) {
+	text := ".work\n- [ ] call Ann #Client/Acme due:2026-03-01\n* [x] ship it\n```\n- [ ] in code\n```\n  1. [X] nested @due(2026-04-02)\n- [] not a task\n-[ ] nor this\n"
+	want := []Task{
+		{Line: 2, Text: "call Ann #Client/Acme due:2026-03-01", Tags: []string{"client/acme"}, Due: "2026-03-01"},
+		{Line: 3, Text: "ship it", Done: true},
+		{Line: 7, Text: "nested @due(2026-04-02)", Done: true, Due: "2026-04-02"},
+	}
+	if got := ParseTasks(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTasks = %+v, want %+v", got, want)
+	}
+
+	tests := []struct{ line, want string }{
+		{"- [ ] a [ ] b", "- [x] a [ ] b"},
+		{"  * [x] done\r", "  * [ ] done\r"},
+		{"1. [X] C# #lang", "1. [ ] C# #lang"},
+	}
+	for _, tt := range tests {
+		if got, ok := ToggleTaskLine(tt.line); !ok || got != tt.want {
+			t.Errorf("ToggleTaskLine(%q) = %q, %v", tt.line, got, ok)
+		}
+	}
+	if _, ok := ToggleTaskLine("plain"); ok {
+		t.Error("plain line toggled")
+	}
+	if task, _ := ParseTaskLine("- [ ] learn C# #lang"); !reflect.DeepEqual(task.Tags, []string{"lang"}) {
+		t.Errorf("tags = %v", task.Tags)
+	}
 }
 
 // --- Pins tests ---
 
+func TestInlineTags(t *testing.T) {
+	text := "Plan #Work/Clients and #idea, not C# or #2024\n`#code` ```\n```\n#fenced\n```\n#idea again"
+	if got, want := InlineTags(text), []string{"work/clients", "idea"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("InlineTags = %v, want %v", got, want)
+	}
+}
+
+func TestReadEnex(t *testing.T) {
+	enex := `<?xml version="1.0" encoding="UTF-8"?>
+<en-export><note><title> Trip </title><created>20230510T083000Z</created>
+<tag>travel</tag><tag>plans</tag>
+<content><![CDATA[<en-note><div>hi</div></en-note>]]></content>
+<resource><data encoding="base64">
+YQ==
+</data><mime>image/png</mime><resource-attributes><file-name>a.png</file-name></resource-attributes></resource>
+</note><note><title>Second</title><content><![CDATA[<en-note/>]]></content></note></en-export>`
+	var notes []EnexNote
+	if err := ReadEnex(strings.NewReader(enex), func(n EnexNote) error {
+		notes = append(notes, n)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("got %d notes, want 2", len(notes))
+	}
+	n := notes[0]
+	if n.Title != "Trip" || !reflect.DeepEqual(n.Tags, []string{"travel", "plans"}) || n.Content != "<en-note><div>hi</div></en-note>" {
+		t.Errorf("note = %+v", n)
+	}
+	if !n.Created.Equal(time.Date(2023, 5, 10, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("created = %v", n.Created)
+	}
+	// The hash is the MD5 of "a", as <en-media> refers to it
+	if len(n.Resources) != 1 || n.Resources[0].Hash != "0cc175b9c0f1b6a831c399e269772661" ||
+		string(n.Resources[0].Data) != "a" || n.Resources[0].FileName != "a.png" {
+		t.Errorf("resources = %+v", n.Resources)
+	}
+}
+
+func TestENMLToMarkdown(t *testing.T) {
+	media := func(hash, mime string) string {
+		if hash == "abc" {
+			return "![pic](attachments/pic.png)"
+		}
+		return ""
+	}
+	tests := []struct {
+		name  string
+		enml  string
+		want  string
+		probs int
+	}{
+		{"inline", `<en-note><div>A <b>bold</b> &amp; <i>it</i>&nbsp;<a href="https://x.io">link</a></div></en-note>`,
+			"A **bold** & *it* [link](https://x.io)\n", 0},
+		{"lines and paragraphs", `<en-note><h2>Title</h2><div>one</div><div>two</div><div><br/></div><p>three</p></en-note>`,
+			"## Title\n\none\ntwo\n\nthree\n", 0},
+		{"checkboxes", `<en-note><div><en-todo checked="true"/>done</div><div><en-todo/>open</div></en-note>`,
+			"- [x] done\n- [ ] open\n", 0},
+		{"checklist", `<en-note><ul style="--en-todo:true;"><li style="--en-checked:true;">a</li><li>b</li></ul></en-note>`,
+			"- [x] a\n- [ ] b\n", 0},
+		{"nested lists", `<en-note><ul><li>a<ul><li>b</li></ul></li></ul><ol><li>x</li><li>y</li></ol></en-note>`,
+			"- a\n  - b\n\n1. x\n2. y\n", 0},
+		{"code block", `<en-note><div style="-en-codeblock:true"><div>x := 1</div><div>  y</div></div></en-note>`,
+			"```\nx := 1\n  y\n```\n", 0},
+		{"table", `<en-note><table><tr><td>a</td><td>b</td></tr><tr><td>1</td><td>2</td></tr></table></en-note>`,
+			"| a | b |\n| --- | --- |\n| 1 | 2 |\n", 0},
+		{"quote", `<en-note><blockquote>said</blockquote></en-note>`, "> said\n", 0},
+		{"note link", `<en-note><div><a href="evernote:///view/1/s1/g/g/">Other</a></div></en-note>`, "[[Other]]\n", 0},
+		{"media", `<en-note><en-media hash="abc" type="image/png"/><en-media hash="gone"/></en-note>`,
+			"![pic](attachments/pic.png)\n", 1},
+		{"encrypted", `<en-note><div>kept</div><en-crypt cipher="AES">c2VjcmV0</en-crypt></en-note>`, "kept\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problems := ENMLToMarkdown(tt.enml, media)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if len(problems) != tt.probs {
+				t.Errorf("problems = %v, want %d", problems, tt.probs)
+			}
+		})
+	}
+}
+
 func TestPinsOperations(t *testing.T) {
 	dir, cleanup := testDir(t)
 	defer cleanup()
@@ -333,9 +599,12 @@ func TestTrashOperations(t *testing.T) {
 		}
 
 		// Verify it's in trash
-		trashPath := filepath.Join(TrashPath(), "test-note.md")
-		if _, err := os.Stat(trashPath); os.IsNotExist(err) {
-			t.Error("Note should be in trash")
+		trashed, err := FindTrashed("test-note")
+		if err != nil || len(trashed) != 1 {
+			t.Fatalf("Note should be in trash, got %v (%v)", trashed, err)
+		}
+		if _, err := os.Stat(filepath.Join(TrashPath(), trashed[0].File)); os.IsNotExist(err) {
+			t.Error("Trashed file should exist")
 		}
 
 		// Verify index is updated
@@ -395,6 +664,168 @@ func TestTrashOperations(t *testing.T) {
 			t.Errorf("Expected at least 2 results, got %d", len(results))
 		}
 	})
+
+	t.Run("legacy trash files are adopted", func(t *testing.T) {
+		entries, err := LoadTrash()
+		if err != nil {
+			t.Fatalf("LoadTrash failed: %v", err)
+		}
+		again, _ := LoadTrash()
+		for id, e := range entries {
+			if e.Name == "trashed1" {
+				// Listing the trash above recorded it in the manifest
+				if e.Adopted || again[id].Name != "trashed1" {
+					t.Errorf("legacy entry should be recorded with a stable id: %+v", e)
+				}
+				if _, err := RecoverTrashEntry(id, notesDir); err != nil {
+					t.Fatalf("recovering legacy entry failed: %v", err)
+				}
+			}
+		}
+		if _, err := os.Stat(filepath.Join(notesDir, "trashed1.md")); err != nil {
+			t.Errorf("legacy note not recovered: %v", err)
+		}
+	})
+}
+
+func TestTrashAdoptionTime(t *testing.T) {
+	dir, cleanup := testDir(t)
+	defer cleanup()
+
+	origGoteDir := GoteDir
+	GoteDir = func() string { return dir }
+	defer func() { GoteDir = origGoteDir }()
+
+	os.MkdirAll(TrashPath(), 0755)
+	legacy := filepath.Join(TrashPath(), "ancient.md")
+	os.WriteFile(legacy, []byte("content"), 0644)
+	old := time.Now().AddDate(-1, 0, 0)
+	os.Chtimes(legacy, old, old)
+
+	if n, err := PurgeExpiredTrash(30 * 24 * time.Hour); err != nil || n != 0 {
+		t.Fatalf("PurgeExpiredTrash = %d, %v; an adopted file should not be expired", n, err)
+	}
+	entries, err := LoadTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("entries = %+v, want the adopted file", entries)
+	}
+	for _, e := range entries {
+		if e.Adopted || time.Since(e.DeletedAt) > time.Minute {
+			t.Errorf("adopted entry should be in the manifest, deleted now: %+v", e)
+		}
+	}
+
+	again, _ := LoadTrash()
+	for id, e := range entries {
+		if !again[id].DeletedAt.Equal(e.DeletedAt) {
+			t.Errorf("deletion time changed on reload: %v, then %v", e.DeletedAt, again[id].DeletedAt)
+		}
+	}
+}
+
+func TestTrashManifest(t *testing.T) {
+	dir, cleanup := testDir(t)
+	defer cleanup()
+
+	origGoteDir := GoteDir
+	GoteDir = func() string { return dir }
+	defer func() { GoteDir = origGoteDir }()
+
+	notesDir := filepath.Join(dir, "notes")
+	os.MkdirAll(filepath.Join(notesDir, "work"), 0755)
+	notePath := filepath.Join(notesDir, "work", "dup.md")
+
+	trash := func(content, created string, pinned bool) {
+		t.Helper()
+		os.WriteFile(notePath, []byte(content), 0644)
+		info, _ := os.Stat(notePath)
+		meta, _ := buildNoteMeta("work/dup", notePath, info)
+		meta.Created = created
+		meta.LastVisited = created
+		SaveIndex(map[string]NoteMeta{"work/dup": meta})
+		if pinned {
+			SavePins(map[string]EmptyStruct{"work/dup": {}})
+		}
+		if err := TrashNote("work/dup", meta); err != nil {
+			t.Fatalf("TrashNote failed: %v", err)
+		}
+	}
+	trash("first", "240101.090000", true)
+	trash("second", "250101.090000", false)
+
+	t.Run("same-named notes are both kept", func(t *testing.T) {
+		matches, err := FindTrashed("dup")
+		if err != nil {
+			t.Fatalf("FindTrashed failed: %v", err)
+		}
+		if len(matches) != 2 || matches[0].ID == matches[1].ID {
+			t.Fatalf("expected two entries, got %+v", matches)
+		}
+		if err := RecoverNote("work/dup", notesDir); err == nil {
+			t.Error("RecoverNote should refuse an ambiguous name")
+		}
+	})
+
+	t.Run("recover restores location, times and pin", func(t *testing.T) {
+		matches, _ := FindTrashed("work/dup")
+		first := matches[len(matches)-1] // oldest last
+		name, err := RecoverTrashEntry(first.ID, notesDir)
+		if err != nil {
+			t.Fatalf("RecoverTrashEntry failed: %v", err)
+		}
+		if name != "work/dup" {
+			t.Errorf("name = %q", name)
+		}
+		content, _ := os.ReadFile(notePath)
+		if string(content) != "first" {
+			t.Errorf("recovered content = %q", content)
+		}
+		index, _ := LoadIndex()
+		if meta := index["work/dup"]; meta.Created != "240101.090000" || meta.LastVisited != "240101.090000" {
+			t.Errorf("times not restored: %+v", meta)
+		}
+		pins, _ := LoadPins()
+		if _, ok := pins["work/dup"]; !ok {
+			t.Error("pin not restored")
+		}
+	})
+
+	t.Run("recover refuses to overwrite", func(t *testing.T) {
+		matches, _ := FindTrashed("work/dup")
+		if len(matches) != 1 {
+			t.Fatalf("expected one entry left, got %+v", matches)
+		}
+		if _, err := RecoverTrashEntry(matches[0].ID, notesDir); err == nil {
+			t.Error("expected an error recovering over an existing note")
+		}
+		content, _ := os.ReadFile(notePath)
+		if string(content) != "first" {
+			t.Errorf("existing note overwritten: %q", content)
+		}
+	})
+
+	t.Run("PurgeExpiredTrash", func(t *testing.T) {
+		if n, _ := PurgeExpiredTrash(0); n != 0 {
+			t.Errorf("zero retention purged %d", n)
+		}
+		WithTrashLock(func(entries map[string]TrashEntry) error {
+			for id, e := range entries {
+				e.DeletedAt = time.Now().Add(-48 * time.Hour)
+				entries[id] = e
+			}
+			return nil
+		})
+		n, err := PurgeExpiredTrash(24 * time.Hour)
+		if err != nil || n != 1 {
+			t.Errorf("PurgeExpiredTrash = %d, %v; want 1", n, err)
+		}
+		if list, _ := ListTrash(); len(list) != 0 {
+			t.Errorf("trash not empty: %+v", list)
+		}
+	})
 }
 
 // --- Template validation tests ---
@@ -451,6 +882,76 @@ func TestTemplateNameValidation(t *testing.T) {
 
 // --- Config tests ---
 
+func TestParseTemplateSettings(t *testing.T) {
+	t.Run("settings are parsed and stripped", func(t *testing.T) {
+		content := "---\nname: {{date \"060102\"}} standup\ntags: [meeting, Work]\ntimestamp: Date\npin: true\n---\n# {{title}}\n"
+		settings, body, err := ParseTemplateSettings(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settings.Name != `{{date "060102"}} standup` || settings.NameLine != 2 {
+			t.Errorf("name = %q on line %d", settings.Name, settings.NameLine)
+		}
+		if strings.Join(settings.Tags, ",") != "meeting,work" {
+			t.Errorf("tags = %v", settings.Tags)
+		}
+		if settings.Timestamp != "date" || !settings.Pin {
+			t.Errorf("settings = %+v", settings)
+		}
+		if body != "# {{title}}\n" {
+			t.Errorf("body = %q", body)
+		}
+	})
+
+	t.Run("tag line syntax", func(t *testing.T) {
+		settings, _, _ := ParseTemplateSettings("---\ntags: .a.b\n---\n")
+		if strings.Join(settings.Tags, ",") != "a,b" {
+			t.Errorf("tags = %v", settings.Tags)
+		}
+	})
+
+	t.Run("note front matter stays in the body", func(t *testing.T) {
+		content := "---\nstatus: open\npin: true\nowner:\n  - alice\ntags:\n  - a\n  - b\n---\n{{bad}}\n"
+		settings, body, err := ParseTemplateSettings(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !settings.Pin || strings.Join(settings.Tags, ",") != "a,b" {
+			t.Errorf("settings = %+v", settings)
+		}
+		if want := "---\nstatus: open\nowner:\n  - alice\n---\n{{bad}}\n"; body != want {
+			t.Errorf("body = %q, want %q", body, want)
+		}
+		// Body line 6 is file line 10
+		if settings.BodyLine+5 != 10 {
+			t.Errorf("BodyLine = %d", settings.BodyLine)
+		}
+	})
+
+	t.Run("no front matter", func(t *testing.T) {
+		settings, body, err := ParseTemplateSettings("# Title\n---\n")
+		if err != nil || !settings.Empty() || body != "# Title\n---\n" {
+			t.Errorf("got %+v, %q, %v", settings, body, err)
+		}
+	})
+
+	t.Run("errors name the line", func(t *testing.T) {
+		tests := map[string]string{
+			"---\npin: maybe\n---\n":      "line 2:",
+			"---\n\n  indented\n---\n":    "line 3:",
+			"---\ntimestamp: week\n---\n": "line 2:",
+			"---\njust text\n---\n":       "line 2:",
+			"---\nname: x\n":              "not closed",
+		}
+		for content, want := range tests {
+			_, _, err := ParseTemplateSettings(content)
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("%q: err = %v, want %q", content, err, want)
+			}
+		}
+	})
+}
+
 func TestConfigOperations(t *testing.T) {
 	dir, cleanup := testDir(t)
 	defer cleanup()
@@ -499,3 +1000,26 @@ func TestConfigOperations(t *testing.T) {
 		}
 	})
 }
+
+func TestIsNoteFile(t *testing.T) {
+	root := filepath.Join("home", "gotes")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"note.md", true},
+		{"work/standup.md", true},
+		{"note.txt", false},
+		{".note.md.swp", false},
+		{".git/HEAD.md", false},
+		{"work/.hidden/x.md", false},
+	}
+	for _, tt := range tests {
+		if got := IsNoteFile(root, filepath.Join(root, filepath.FromSlash(tt.path))); got != tt.want {
+			t.Errorf("IsNoteFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if IsNoteFile(root, filepath.Join("elsewhere", "note.md")) {
+		t.Error("file outside root should not be a note")
+	}
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DataFile is one of gote's JSON files, with the shape it must parse into
+type DataFile struct {
+	Name string
+	Path string
+	new  func() interface{}
+}
+
+// DataFiles returns every JSON data file gote keeps in GoteDir
+func DataFiles() []DataFile {
+	return []DataFile{
+		{"config.json", configPath(), func() interface{} { return &Config{} }},
+		{"index.json", IndexPath(), func() interface{} { return &map[string]NoteMeta{} }},
+		{"tags.json", TagsPath(), func() interface{} { return &map[string]TagMeta{} }},
+		{"links.json", LinksPath(), func() interface{} { return &map[string]LinkMeta{} }},
+		{"pins.json", PinsPath(), func() interface{} { return &map[string]EmptyStruct{} }},
+		{"trash/manifest.json", TrashManifestPath(), func() interface{} { return &map[string]TrashEntry{} }},
+		// Either the current or the legacy per-document format
+		{"fts.json", FTSPath(), func() interface{} { return &map[string]json.RawMessage{} }},
+	}
+}
+
+// Check returns an error if the file exists but can't be parsed
+func (f DataFile) Check() error {
+	raw, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, f.new())
+}
+
+// QuarantineFile moves a damaged file aside so it can be rebuilt, keeping it
+// for inspection. Returns the new path.
+func QuarantineFile(path string) (string, error) {
+	dest := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("060102.150405"))
+	if err := os.Rename(path, dest); err != nil {
+		return "", fmt.Errorf("moving aside %s: %w", path, err)
+	}
+	return dest, nil
+}
+
+// CountBadFTSLogLines returns how many journal lines can't be parsed, e.g.
+// torn writes from a crash. They are skipped on load and dropped by CompactFTS.
+func CountBadFTSLogLines() (int, error) {
+	raw, err := os.ReadFile(FTSLogPath())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading FTS journal: %w", err)
+	}
+	bad := 0
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 64*1024), len(raw)+1)
+	for scanner.Scan() {
+		var entry ftsLogEntry
+		if len(bytes.TrimSpace(scanner.Bytes())) > 0 && json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			bad++
+		}
+	}
+	return bad, scanner.Err()
+}
+
+// CompactFTS folds the journal into fts.json
+func CompactFTS() error {
+	return withFTSLock(func() error {
+		idx, err := loadFTSLocked()
+		if err != nil {
+			return err
+		}
+		return SaveFTS(idx)
+	})
+}
@@ -0,0 +1,459 @@
+package data
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// EnexNote is a note from an Evernote .enex export
+type EnexNote struct {
+	Title     string
+	Content   string // ENML, Evernote's XHTML
+	Created   time.Time
+	Updated   time.Time
+	Tags      []string
+	Resources []EnexResource
+}
+
+// EnexResource is a file attached to an Evernote note. Hash is the MD5 the
+// note's <en-media> elements refer to it by.
+type EnexResource struct {
+	Hash     string
+	Mime     string
+	FileName string
+	Data     []byte
+}
+
+// enexNote mirrors a <note> element
+type enexNote struct {
+	Title     string   `xml:"title"`
+	Content   string   `xml:"content"`
+	Created   string   `xml:"created"`
+	Updated   string   `xml:"updated"`
+	Tags      []string `xml:"tag"`
+	Resources []struct {
+		Data     string `xml:"data"`
+		Mime     string `xml:"mime"`
+		FileName string `xml:"resource-attributes>file-name"`
+	} `xml:"resource"`
+}
+
+// ReadEnex calls fn with each note in an .enex export, one at a time so
+// large exports aren't held in memory
+func ReadEnex(r io.Reader, fn func(EnexNote) error) error {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid ENEX file: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+		var raw enexNote
+		if err := dec.DecodeElement(&raw, &start); err != nil {
+			return fmt.Errorf("invalid ENEX note: %w", err)
+		}
+
+		note := EnexNote{
+			Title:   strings.TrimSpace(raw.Title),
+			Content: raw.Content,
+			Created: parseEnexTime(raw.Created),
+			Updated: parseEnexTime(raw.Updated),
+			Tags:    raw.Tags,
+		}
+		for _, res := range raw.Resources {
+			// Base64 data is wrapped over many lines
+			decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(res.Data), ""))
+			if err != nil {
+				continue
+			}
+			sum := md5.Sum(decoded)
+			note.Resources = append(note.Resources, EnexResource{
+				Hash:     hex.EncodeToString(sum[:]),
+				Mime:     strings.TrimSpace(res.Mime),
+				FileName: strings.TrimSpace(res.FileName),
+				Data:     decoded,
+			})
+		}
+		if err := fn(note); err != nil {
+			return err
+		}
+	}
+}
+
+// parseEnexTime reads Evernote's "20231004T120000Z" timestamps
+func parseEnexTime(s string) time.Time {
+	t, err := time.Parse("20060102T150405Z", strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// enmlList is an open <ul> or <ol>
+type enmlList struct {
+	ordered bool
+	todo    bool // Evernote's checklist style
+	n       int
+	indent  int // width of the current item's marker
+}
+
+// enmlWriter renders ENML as Markdown
+type enmlWriter struct {
+	b        strings.Builder
+	atStart  bool // at the start of a line, before its prefix
+	lists    []enmlList
+	quote    int
+	pre      int
+	divs     []bool // open <div>s, true for Evernote code blocks
+	skip     int    // inside an element whose content is dropped
+	links    []string
+	cell     int // cells written in the current table row, or -1 outside tables
+	rows     int
+	media    func(hash, mime string) string
+	problems []string
+}
+
+// ENMLToMarkdown converts an Evernote note's ENML to Markdown. media returns
+// the Markdown for an attached file, or "" if the note doesn't have it.
+// Anything that could not be converted is described in the returned problems.
+func ENMLToMarkdown(enml string, media func(hash, mime string) string) (string, []string) {
+	w := &enmlWriter{atStart: true, cell: -1, media: media}
+	dec := xml.NewDecoder(strings.NewReader(enml))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			w.problems = append(w.problems, "content ends early: "+err.Error())
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if w.skip > 0 {
+				w.skip++
+				continue
+			}
+			w.start(t)
+		case xml.EndElement:
+			if w.skip > 0 {
+				w.skip--
+				continue
+			}
+			w.end(t.Name.Local)
+		case xml.CharData:
+			if w.skip == 0 {
+				w.text(string(t))
+			}
+		}
+	}
+	return tidyMarkdown(w.b.String()), w.problems
+}
+
+func xmlAttr(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if strings.EqualFold(a.Name.Local, name) {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// prefix is what continues the current line's blockquotes and list items
+func (w *enmlWriter) prefix(lists []enmlList) string {
+	p := strings.Repeat("> ", w.quote)
+	for _, l := range lists {
+		p += strings.Repeat(" ", l.indent)
+	}
+	return p
+}
+
+func (w *enmlWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	if w.atStart {
+		w.b.WriteString(w.prefix(w.lists))
+		w.atStart = false
+	}
+	w.b.WriteString(s)
+}
+
+// newline ends the current line; inside a table cell it's a space instead
+func (w *enmlWriter) newline() {
+	if w.cell > 0 {
+		w.write(" ")
+		return
+	}
+	w.b.WriteString("\n")
+	w.atStart = true
+}
+
+// breakLine starts a new line unless already at the start of one
+func (w *enmlWriter) breakLine() {
+	if !w.atStart {
+		w.newline()
+	}
+}
+
+// breakBlock separates blocks with a blank line, or just a line break in lists
+func (w *enmlWriter) breakBlock() {
+	w.breakLine()
+	if len(w.lists) == 0 && w.cell < 0 {
+		w.b.WriteString(strings.TrimRight(w.prefix(nil), " ") + "\n")
+	}
+}
+
+func (w *enmlWriter) start(t xml.StartElement) {
+	name := strings.ToLower(t.Name.Local)
+	style := strings.ReplaceAll(strings.ToLower(xmlAttr(t, "style")), " ", "")
+	switch name {
+	case "p", "h1", "h2", "h3", "h4", "h5", "h6":
+		w.breakBlock()
+		if len(name) == 2 && name[0] == 'h' {
+			w.write(strings.Repeat("#", int(name[1]-'0')) + " ")
+		}
+	case "div":
+		codeBlock := strings.Contains(style, "-en-codeblock:true")
+		w.divs = append(w.divs, codeBlock)
+		if codeBlock {
+			w.breakBlock()
+			w.write("```")
+			w.newline()
+			w.pre++
+			return
+		}
+		w.breakLine()
+	case "br":
+		w.newline()
+	case "hr":
+		w.breakBlock()
+		w.write("---")
+		w.breakBlock()
+	case "blockquote":
+		w.breakBlock()
+		w.quote++
+	case "pre":
+		w.breakBlock()
+		w.write("```")
+		w.newline()
+		w.pre++
+	case "ul", "ol":
+		if len(w.lists) == 0 {
+			w.breakBlock()
+		}
+		w.lists = append(w.lists, enmlList{ordered: name == "ol", todo: strings.Contains(style, "--en-todo:true")})
+	case "li":
+		w.breakLine()
+		if len(w.lists) == 0 {
+			w.lists = append(w.lists, enmlList{})
+		}
+		l := &w.lists[len(w.lists)-1]
+		l.n++
+		marker := "- "
+		if l.ordered {
+			marker = fmt.Sprintf("%d. ", l.n)
+		}
+		if l.todo {
+			marker += "[ ] "
+			if strings.Contains(style, "--en-checked:true") {
+				marker = "- [x] "
+			}
+		}
+		w.b.WriteString(w.prefix(w.lists[:len(w.lists)-1]) + marker)
+		w.atStart = false
+		l.indent = len(marker)
+	case "en-todo":
+		box := "[ ] "
+		if strings.EqualFold(xmlAttr(t, "checked"), "true") {
+			box = "[x] "
+		}
+		// A checkbox outside a list needs a list marker to be a task
+		if w.atStart || len(w.lists) == 0 {
+			box = "- " + box
+		}
+		w.write(box)
+	case "b", "strong":
+		w.write("**")
+	case "i", "em":
+		w.write("*")
+	case "s", "strike", "del":
+		w.write("~~")
+	case "code":
+		if w.pre == 0 {
+			w.write("`")
+		}
+	case "a":
+		href := xmlAttr(t, "href")
+		w.links = append(w.links, href)
+		// Links between Evernote notes are by ID; their text is the note's title
+		if strings.HasPrefix(href, "evernote:") {
+			w.write("[[")
+			return
+		}
+		w.write("[")
+	case "img":
+		if src := xmlAttr(t, "src"); src != "" && !strings.HasPrefix(src, "data:") {
+			w.write(fmt.Sprintf("![%s](%s)", xmlAttr(t, "alt"), src))
+		} else {
+			w.problems = append(w.problems, "inline image not imported")
+		}
+	case "en-media":
+		hash := xmlAttr(t, "hash")
+		if link := w.media(hash, xmlAttr(t, "type")); link != "" {
+			w.write(link)
+		} else {
+			w.problems = append(w.problems, "missing attachment "+hash)
+		}
+	case "en-crypt":
+		w.problems = append(w.problems, "encrypted text not imported")
+		w.skip = 1
+	case "table":
+		w.breakBlock()
+		w.rows = 0
+	case "tr":
+		w.breakLine()
+		w.cell = 0
+	case "td", "th":
+		if w.cell == 0 {
+			w.write("| ")
+		} else {
+			w.write(" | ")
+		}
+		w.cell++
+	case "style", "script", "title", "head":
+		w.skip = 1
+	}
+}
+
+func (w *enmlWriter) end(name string) {
+	switch strings.ToLower(name) {
+	case "p", "h1", "h2", "h3", "h4", "h5", "h6":
+		w.breakBlock()
+	case "div":
+		if n := len(w.divs); n > 0 {
+			codeBlock := w.divs[n-1]
+			w.divs = w.divs[:n-1]
+			if codeBlock {
+				w.pre = max(w.pre-1, 0)
+				w.breakLine()
+				w.write("```")
+				w.breakBlock()
+				return
+			}
+		}
+		w.breakLine()
+	case "blockquote":
+		w.breakLine()
+		w.quote = max(w.quote-1, 0)
+		w.breakBlock()
+	case "pre":
+		w.pre = max(w.pre-1, 0)
+		w.breakLine()
+		w.write("```")
+		w.breakBlock()
+	case "ul", "ol":
+		if len(w.lists) > 0 {
+			w.lists = w.lists[:len(w.lists)-1]
+		}
+		if len(w.lists) == 0 {
+			w.breakBlock()
+		}
+	case "li":
+		w.breakLine()
+	case "b", "strong":
+		w.write("**")
+	case "i", "em":
+		w.write("*")
+	case "s", "strike", "del":
+		w.write("~~")
+	case "code":
+		if w.pre == 0 {
+			w.write("`")
+		}
+	case "a":
+		href := ""
+		if n := len(w.links); n > 0 {
+			href = w.links[n-1]
+			w.links = w.links[:n-1]
+		}
+		if strings.HasPrefix(href, "evernote:") {
+			w.write("]]")
+			return
+		}
+		w.write("](" + href + ")")
+	case "tr":
+		cells := w.cell
+		w.cell = 0
+		w.write(" |")
+		w.newline()
+		if w.rows == 0 {
+			w.write("|" + strings.Repeat(" --- |", max(cells, 1)))
+			w.newline()
+		}
+		w.rows++
+		w.cell = -1
+	case "table":
+		w.cell = -1
+		w.breakBlock()
+	}
+}
+
+func (w *enmlWriter) text(s string) {
+	if w.pre > 0 {
+		lines := strings.Split(s, "\n")
+		for i, line := range lines {
+			if i > 0 {
+				w.newline()
+			}
+			w.write(line)
+		}
+		return
+	}
+	// Outside preformatted text, runs of whitespace are one space
+	collapsed := strings.Join(strings.Fields(s), " ")
+	if collapsed == "" {
+		if s != "" && !w.atStart && !strings.HasSuffix(w.b.String(), " ") {
+			w.write(" ")
+		}
+		return
+	}
+	if strings.TrimLeftFunc(s, unicode.IsSpace) != s && !w.atStart && !strings.HasSuffix(w.b.String(), " ") {
+		collapsed = " " + collapsed
+	}
+	if strings.TrimRightFunc(s, unicode.IsSpace) != s {
+		collapsed += " "
+	}
+	w.write(collapsed)
+}
+
+var blankLinesRegex = regexp.MustCompile(`\n(?:[ >]*\n){2,}`)
+
+// tidyMarkdown trims trailing spaces and collapses runs of blank lines
+func tidyMarkdown(md string) string {
+	lines := strings.Split(md, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	md = strings.Join(lines, "\n")
+	md = blankLinesRegex.ReplaceAllString(md, "\n\n")
+	return strings.TrimSpace(md) + "\n"
+}
@@ -0,0 +1,129 @@
+package data
+
+import (
+	"strings"
+)
+
+// FrontMatter is the metadata in a note's optional leading YAML block:
+//
+//	---
+//	tags: [project, work]
+//	aliases: [q3 plan]
+//	status: open
+//	owner: alice
+//	---
+//
+// Only the parts of YAML notes use are understood: "key: value" pairs,
+// [inline, lists] and "- item" lists, with optional quotes. Anything else is
+// ignored rather than failing the note.
+type FrontMatter struct {
+	Tags       []string
+	Aliases    []string
+	Properties map[string]string // other keys, lowercased; lists joined with ", "
+}
+
+// SplitFrontMatter separates a leading "---" block from the rest of the
+// text. ok is false, and body is text, if there is none.
+func SplitFrontMatter(text string) (block []string, body string, ok bool) {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return nil, text, false
+	}
+	for i := 1; i < len(lines); i++ {
+		if trimmed := strings.TrimSpace(lines[i]); trimmed == "---" || trimmed == "..." {
+			for _, line := range lines[1:i] {
+				block = append(block, strings.TrimRight(line, "\r\n"))
+			}
+			return block, strings.Join(lines[i+1:], ""), true
+		}
+	}
+	return nil, text, false
+}
+
+// ParseFrontMatter reads a note's front matter, returning it and the text
+// after it
+func ParseFrontMatter(text string) (FrontMatter, string) {
+	var fm FrontMatter
+	block, body, ok := SplitFrontMatter(text)
+	if !ok {
+		return fm, text
+	}
+	for key, values := range parseYAMLBlock(block) {
+		switch key {
+		case "tags", "tag":
+			for _, v := range values {
+				if tag := NormalizeTag(strings.Trim(v, ".# ")); tag != "" {
+					fm.Tags = append(fm.Tags, tag)
+				}
+			}
+		case "aliases", "alias":
+			fm.Aliases = append(fm.Aliases, values...)
+		default:
+			if fm.Properties == nil {
+				fm.Properties = make(map[string]string)
+			}
+			fm.Properties[key] = strings.Join(values, ", ")
+		}
+	}
+	return fm, body
+}
+
+// parseYAMLBlock reads the simple YAML subset described on FrontMatter into
+// lowercased keys and their values
+func parseYAMLBlock(lines []string) map[string][]string {
+	values := make(map[string][]string)
+	var listKey string // key whose "- item" lines follow
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey != "" {
+				if item := yamlScalar(strings.TrimPrefix(trimmed, "-")); item != "" {
+					values[listKey] = append(values[listKey], item)
+				}
+			}
+			continue
+		}
+		listKey = ""
+		if line != strings.TrimLeft(line, " \t") {
+			continue // nested mappings aren't supported
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch {
+		case key == "":
+		case value == "":
+			listKey = key
+			values[key] = nil
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			var items []string
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = yamlScalar(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			values[key] = items
+		default:
+			values[key] = []string{yamlScalar(value)}
+		}
+	}
+	return values
+}
+
+// yamlScalar trims a value and its quotes, and drops a trailing comment
+func yamlScalar(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
@@ -1,48 +1,342 @@
 package data
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"unicode"
 
 	"github.com/kljensen/snowball"
 )
 
-// DocTerms stores stemmed term frequencies for one document
+// ftsVersion is the on-disk format version of fts.json
+const ftsVersion = 2
+
+// ftsCompactSize is the journal size after which it is folded into fts.json
+const ftsCompactSize = 256 * 1024
+
+// DocTerms stores stemmed term frequencies and positions for one document
 type DocTerms struct {
-	Title    string         `json:"title"`
-	FilePath string         `json:"filePath"`
-	Terms    map[string]int `json:"terms"`
-	Length   int            `json:"length"`
+	Title     string           `json:"title"`
+	FilePath  string           `json:"filePath"`
+	Terms     map[string]int   `json:"terms"`
+	Positions map[string][]int `json:"positions,omitempty"`
+	Length    int              `json:"length"`
+}
+
+// Posting records a term's occurrences in one document. Positions index the
+// doc's token stream (title first, then content) and are empty for docs
+// migrated from the old format whose note file was missing.
+type Posting struct {
+	TF        int   `json:"tf"`
+	Positions []int `json:"pos,omitempty"`
+}
+
+// FTSDoc stores per-document data used for ranking and incremental updates
+type FTSDoc struct {
+	Title    string   `json:"title"`
+	FilePath string   `json:"filePath"`
+	Length   int      `json:"length"`
+	Terms    []string `json:"terms"` // distinct terms, so the doc's postings can be dropped
+}
+
+// FTSIndex is an inverted index: term -> doc title -> posting.
+// Document frequency is len(Postings[term]); TotalLength caches the sum of doc lengths.
+type FTSIndex struct {
+	Version     int                           `json:"version"`
+	Docs        map[string]FTSDoc             `json:"docs"`
+	Postings    map[string]map[string]Posting `json:"postings"`
+	TotalLength int                           `json:"totalLength"`
+}
+
+// ftsLogEntry is one line of the FTS journal: either a document upsert or a removal
+type ftsLogEntry struct {
+	Put *DocTerms `json:"put,omitempty"`
+	Del string    `json:"del,omitempty"`
+}
+
+// NewFTSIndex returns an empty index
+func NewFTSIndex() *FTSIndex {
+	return &FTSIndex{
+		Version:  ftsVersion,
+		Docs:     make(map[string]FTSDoc),
+		Postings: make(map[string]map[string]Posting),
+	}
+}
+
+// AddDoc inserts or replaces a document, touching only its postings
+func (idx *FTSIndex) AddDoc(doc DocTerms) {
+	idx.RemoveDoc(doc.Title)
+
+	terms := make([]string, 0, len(doc.Terms))
+	for term, tf := range doc.Terms {
+		postings := idx.Postings[term]
+		if postings == nil {
+			postings = make(map[string]Posting)
+			idx.Postings[term] = postings
+		}
+		postings[doc.Title] = Posting{TF: tf, Positions: doc.Positions[term]}
+		terms = append(terms, term)
+	}
+	idx.Docs[doc.Title] = FTSDoc{
+		Title:    doc.Title,
+		FilePath: doc.FilePath,
+		Length:   doc.Length,
+		Terms:    terms,
+	}
+	idx.TotalLength += doc.Length
+}
+
+// RemoveDoc drops a document and its postings
+func (idx *FTSIndex) RemoveDoc(title string) {
+	doc, ok := idx.Docs[title]
+	if !ok {
+		return
+	}
+	for _, term := range doc.Terms {
+		postings := idx.Postings[term]
+		delete(postings, title)
+		if len(postings) == 0 {
+			delete(idx.Postings, term)
+		}
+	}
+	delete(idx.Docs, title)
+	idx.TotalLength -= doc.Length
+}
+
+// DocFreq returns the number of documents containing term
+func (idx *FTSIndex) DocFreq(term string) int {
+	return len(idx.Postings[term])
+}
+
+// SimilarTerms returns indexed terms within MaxTypos edits of term, closest
+// and most common first. Used to expand a misspelled query term.
+func (idx *FTSIndex) SimilarTerms(term string) []string {
+	maxDist := MaxTypos(len(term))
+	if maxDist == 0 {
+		return nil
+	}
+	type candidate struct {
+		term string
+		dist int
+		df   int
+	}
+	var found []candidate
+	runes := []rune(term)
+	for t, postings := range idx.Postings {
+		if t == term || abs(len(t)-len(term)) > maxDist {
+			continue
+		}
+		if d := EditDistance(runes, []rune(t)); d <= maxDist {
+			found = append(found, candidate{t, d, len(postings)})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].dist != found[j].dist {
+			return found[i].dist < found[j].dist
+		}
+		if found[i].df != found[j].df {
+			return found[i].df > found[j].df
+		}
+		return found[i].term < found[j].term
+	})
+	terms := make([]string, len(found))
+	for i, c := range found {
+		terms[i] = c.term
+	}
+	return terms
 }
 
-// FTSIndex maps note title to its term data
-type FTSIndex map[string]DocTerms
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// AvgLength returns the average document length
+func (idx *FTSIndex) AvgLength() float64 {
+	if len(idx.Docs) == 0 {
+		return 0
+	}
+	return float64(idx.TotalLength) / float64(len(idx.Docs))
+}
 
 func FTSPath() string {
 	return filepath.Join(GoteDir(), "fts.json")
 }
 
-func LoadFTS() (FTSIndex, error) {
-	idx := make(FTSIndex)
+// FTSLogPath returns the journal of updates not yet folded into fts.json
+func FTSLogPath() string {
+	return filepath.Join(GoteDir(), "fts.log")
+}
+
+// LoadFTS reads fts.json and replays the journal on top of it.
+// Indexes in the old per-document format are migrated and saved.
+func LoadFTS() (*FTSIndex, error) {
+	idx, legacy, err := loadFTSBase()
+	if err != nil {
+		return nil, err
+	}
+	if legacy != nil {
+		err := withFTSLock(func() error {
+			idx, err = loadFTSLocked()
+			return err
+		})
+		return idx, err
+	}
+	if err := replayFTSLog(idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// loadFTSLocked is LoadFTS for callers holding the FTS lock
+func loadFTSLocked() (*FTSIndex, error) {
+	idx, legacy, err := loadFTSBase()
+	if err != nil {
+		return nil, err
+	}
+	if legacy != nil {
+		return migrateFTS(legacy)
+	}
+	if err := replayFTSLog(idx); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// loadFTSBase reads fts.json. An index in the old per-document format is
+// returned as legacy, title -> DocTerms, for migrateFTS.
+func loadFTSBase() (*FTSIndex, map[string]DocTerms, error) {
 	data, err := os.ReadFile(FTSPath())
 	if os.IsNotExist(err) {
-		return idx, nil
+		return NewFTSIndex(), nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("reading FTS index: %w", err)
+		return nil, nil, fmt.Errorf("reading FTS index: %w", err)
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, nil, fmt.Errorf("parsing FTS index: %w", err)
 	}
-	if err := json.Unmarshal(data, &idx); err != nil {
-		return nil, fmt.Errorf("parsing FTS index: %w", err)
+	if _, ok := probe["version"]; !ok {
+		var legacy map[string]DocTerms
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return nil, nil, fmt.Errorf("parsing FTS index: %w", err)
+		}
+		return nil, legacy, nil
+	}
+
+	idx := NewFTSIndex()
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, nil, fmt.Errorf("parsing FTS index: %w", err)
+	}
+	return idx, nil, nil
+}
+
+// migrateFTS converts a title -> DocTerms index into posting lists and saves
+// it. The old format has no term positions, so notes still on disk are
+// indexed afresh from their files. Callers must hold the FTS lock.
+func migrateFTS(legacy map[string]DocTerms) (*FTSIndex, error) {
+	idx := NewFTSIndex()
+	for title, doc := range legacy {
+		if content, err := os.ReadFile(doc.FilePath); err == nil {
+			doc = BuildDocTerms(title, doc.FilePath, string(content))
+		}
+		doc.Title = title
+		idx.AddDoc(doc)
+	}
+	if err := replayFTSLog(idx); err != nil {
+		return nil, err
+	}
+	if err := SaveFTS(idx); err != nil {
+		return nil, fmt.Errorf("migrating FTS index: %w", err)
 	}
 	return idx, nil
 }
 
-func SaveFTS(idx FTSIndex) error {
-	return AtomicWriteJSON(FTSPath(), idx)
+func replayFTSLog(idx *FTSIndex) error {
+	data, err := os.ReadFile(FTSLogPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading FTS journal: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		var entry ftsLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // torn write from a crash; skip it
+		}
+		if entry.Put != nil {
+			idx.AddDoc(*entry.Put)
+		} else if entry.Del != "" {
+			idx.RemoveDoc(entry.Del)
+		}
+	}
+	return scanner.Err()
+}
+
+// SaveFTS writes the full index to fts.json and clears the journal
+func SaveFTS(idx *FTSIndex) error {
+	idx.Version = ftsVersion
+	if err := AtomicWriteJSON(FTSPath(), idx); err != nil {
+		return err
+	}
+	if err := os.Remove(FTSLogPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("clearing FTS journal: %w", err)
+	}
+	return nil
+}
+
+// appendFTSLog records an update in the journal, compacting it into fts.json
+// once it grows past ftsCompactSize. Callers must hold the FTS lock.
+func appendFTSLog(entry ftsLogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding FTS journal entry: %w", err)
+	}
+	f, err := os.OpenFile(FTSLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("opening FTS journal: %w", err)
+	}
+	_, writeErr := f.Write(append(line, '\n'))
+	info, statErr := f.Stat()
+	if err := f.Close(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	if writeErr != nil {
+		return fmt.Errorf("writing FTS journal: %w", writeErr)
+	}
+
+	if statErr == nil && info.Size() > ftsCompactSize {
+		idx, err := loadFTSLocked()
+		if err != nil {
+			return err
+		}
+		return SaveFTS(idx)
+	}
+	return nil
+}
+
+// withFTSLock runs fn with exclusive access to the FTS files
+func withFTSLock(fn func() error) error {
+	lock, err := LockFile(FTSPath())
+	if err != nil {
+		return fmt.Errorf("acquiring FTS lock: %w", err)
+	}
+	defer lock.Unlock()
+	return fn()
 }
 
 var stopWords = map[string]bool{
@@ -61,79 +355,114 @@ var stopWords = map[string]bool{
 	"than": true, "also": true, "just": true, "about": true, "over": true,
 }
 
+// TokenSpan is a stemmed token and its byte range in the original text
+type TokenSpan struct {
+	Stem       string
+	Start, End int
+}
+
 // Tokenize splits text into stemmed tokens, filtering stop words
 func Tokenize(text string) []string {
-	// Split on non-alphanumeric characters
-	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
-		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
-	})
+	spans := TokenizeSpans(text)
+	if len(spans) == 0 {
+		return nil
+	}
+	tokens := make([]string, len(spans))
+	for i, s := range spans {
+		tokens[i] = s.Stem
+	}
+	return tokens
+}
 
-	var tokens []string
-	for _, w := range words {
+// TokenizeSpans is Tokenize, keeping each token's position in text
+func TokenizeSpans(text string) []TokenSpan {
+	var spans []TokenSpan
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		w := strings.ToLower(text[start:end])
+		s := start
+		start = -1
 		if len(w) < 2 || stopWords[w] {
-			continue
+			return
 		}
 		stemmed, err := snowball.Stem(w, "english", false)
 		if err != nil || stemmed == "" {
 			stemmed = w
 		}
-		tokens = append(tokens, stemmed)
+		spans = append(spans, TokenSpan{Stem: stemmed, Start: s, End: end})
 	}
-	return tokens
+	// Split on non-alphanumeric characters
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+		} else {
+			flush(i)
+		}
+	}
+	flush(len(text))
+	return spans
 }
 
-// BuildDocTerms creates term frequency data for a document.
-// Title tokens are repeated 3x for weighting.
+// BuildDocTerms creates term frequency and position data for a document.
+// Title tokens are repeated 3x for weighting. Content positions start one
+// past the title so phrases never match across the title boundary.
 func BuildDocTerms(title, filePath, content string) DocTerms {
 	titleTokens := Tokenize(title)
 	contentTokens := Tokenize(content)
 
 	terms := make(map[string]int)
+	positions := make(map[string][]int)
 	// Title weight: count title tokens 3x
-	for _, t := range titleTokens {
+	for i, t := range titleTokens {
 		terms[t] += 3
+		positions[t] = append(positions[t], i)
 	}
-	for _, t := range contentTokens {
+	offset := len(titleTokens) + 1
+	for i, t := range contentTokens {
 		terms[t]++
+		positions[t] = append(positions[t], offset+i)
 	}
 
 	return DocTerms{
-		Title:    title,
-		FilePath: filePath,
-		Terms:    terms,
-		Length:   len(titleTokens)*3 + len(contentTokens),
+		Title:     title,
+		FilePath:  filePath,
+		Terms:     terms,
+		Positions: positions,
+		Length:    len(titleTokens)*3 + len(contentTokens),
 	}
 }
 
 // IndexDocFTS updates a single document in the FTS index
 func IndexDocFTS(title, filePath, content string) error {
-	idx, err := LoadFTS()
-	if err != nil {
-		return err
-	}
-	idx[title] = BuildDocTerms(title, filePath, content)
-	return SaveFTS(idx)
+	doc := BuildDocTerms(title, filePath, content)
+	return withFTSLock(func() error {
+		return appendFTSLog(ftsLogEntry{Put: &doc})
+	})
 }
 
 // IndexAllFTS rebuilds the entire FTS index from note files
 func IndexAllFTS(notesDir string, index map[string]NoteMeta) error {
-	idx := make(FTSIndex)
+	idx := NewFTSIndex()
 	for title, meta := range index {
 		content, err := os.ReadFile(meta.FilePath)
 		if err != nil {
 			continue // skip unreadable files
 		}
-		idx[title] = BuildDocTerms(title, meta.FilePath, string(content))
+		idx.AddDoc(BuildDocTerms(title, meta.FilePath, string(content)))
 	}
-	return SaveFTS(idx)
+	return withFTSLock(func() error {
+		return SaveFTS(idx)
+	})
 }
 
 // RemoveDocFTS removes a document from the FTS index
 func RemoveDocFTS(title string) error {
-	idx, err := LoadFTS()
-	if err != nil {
-		return err
-	}
-	delete(idx, title)
-	return SaveFTS(idx)
+	return withFTSLock(func() error {
+		return appendFTSLog(ftsLogEntry{Del: title})
+	})
 }
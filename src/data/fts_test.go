@@ -3,6 +3,8 @@ package data
 import (
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -19,20 +21,19 @@ func TestLoadSaveFTS(t *testing.T) {
 		if err != nil {
 			t.Fatalf("LoadFTS failed: %v", err)
 		}
-		if len(idx) != 0 {
-			t.Errorf("expected empty map, got %d entries", len(idx))
+		if len(idx.Docs) != 0 {
+			t.Errorf("expected empty map, got %d entries", len(idx.Docs))
 		}
 	})
 
 	t.Run("SaveFTS and LoadFTS roundtrip", func(t *testing.T) {
-		idx := FTSIndex{
-			"test-note": {
-				Title:    "test-note",
-				FilePath: "/path/to/test-note.md",
-				Terms:    map[string]int{"hello": 3, "world": 1},
-				Length:   4,
-			},
-		}
+		idx := NewFTSIndex()
+		idx.AddDoc(DocTerms{
+			Title:    "test-note",
+			FilePath: "/path/to/test-note.md",
+			Terms:    map[string]int{"hello": 3, "world": 1},
+			Length:   4,
+		})
 		if err := SaveFTS(idx); err != nil {
 			t.Fatalf("SaveFTS failed: %v", err)
 		}
@@ -41,19 +42,88 @@ func TestLoadSaveFTS(t *testing.T) {
 		if err != nil {
 			t.Fatalf("LoadFTS failed: %v", err)
 		}
-		if len(loaded) != 1 {
-			t.Fatalf("expected 1 entry, got %d", len(loaded))
+		if len(loaded.Docs) != 1 {
+			t.Fatalf("expected 1 entry, got %d", len(loaded.Docs))
 		}
-		doc := loaded["test-note"]
+		doc := loaded.Docs["test-note"]
 		if doc.Title != "test-note" {
 			t.Errorf("Title = %q, want test-note", doc.Title)
 		}
-		if doc.Terms["hello"] != 3 {
-			t.Errorf("hello count = %d, want 3", doc.Terms["hello"])
+		if loaded.Postings["hello"]["test-note"].TF != 3 {
+			t.Errorf("hello count = %d, want 3", loaded.Postings["hello"]["test-note"].TF)
+		}
+		if loaded.TotalLength != 4 {
+			t.Errorf("TotalLength = %d, want 4", loaded.TotalLength)
+		}
+	})
+
+	t.Run("migrates per-document format", func(t *testing.T) {
+		legacy := `{"old-note": {"title": "old-note", "filePath": "/old-note.md", "terms": {"alpha": 2, "beta": 1}, "length": 3}}`
+		os.WriteFile(FTSPath(), []byte(legacy), 0644)
+
+		loaded, err := LoadFTS()
+		if err != nil {
+			t.Fatalf("LoadFTS failed: %v", err)
+		}
+		if loaded.Postings["alpha"]["old-note"].TF != 2 {
+			t.Errorf("alpha posting = %+v, want tf 2", loaded.Postings["alpha"]["old-note"])
+		}
+		if loaded.DocFreq("beta") != 1 {
+			t.Errorf("DocFreq(beta) = %d, want 1", loaded.DocFreq("beta"))
+		}
+
+		raw, _ := os.ReadFile(FTSPath())
+		if !strings.Contains(string(raw), `"version"`) {
+			t.Error("migrated index should be saved in the new format")
+		}
+	})
+
+	t.Run("migration adds positions from note files", func(t *testing.T) {
+		notePath := filepath.Join(dir, "standup.md")
+		os.WriteFile(notePath, []byte("daily standup notes"), 0644)
+		legacy := `{"standup": {"title": "standup", "filePath": ` + strconv.Quote(notePath) + `, "terms": {"daili": 1}, "length": 1}}`
+		os.WriteFile(FTSPath(), []byte(legacy), 0644)
+
+		loaded, err := LoadFTS()
+		if err != nil {
+			t.Fatalf("LoadFTS failed: %v", err)
+		}
+		if got := loaded.Postings["note"]["standup"].Positions; len(got) != 1 {
+			t.Errorf("note positions = %v, want one", got)
+		}
+		if _, err := os.Stat(FTSPath() + ".lock"); !os.IsNotExist(err) {
+			t.Error("FTS lock left behind")
 		}
 	})
 }
 
+func TestFTSIndexPostings(t *testing.T) {
+	idx := NewFTSIndex()
+	idx.AddDoc(DocTerms{Title: "a", Terms: map[string]int{"x": 1, "y": 2}, Length: 3})
+	idx.AddDoc(DocTerms{Title: "b", Terms: map[string]int{"x": 4}, Length: 4})
+
+	if idx.DocFreq("x") != 2 {
+		t.Errorf("DocFreq(x) = %d, want 2", idx.DocFreq("x"))
+	}
+	if idx.AvgLength() != 3.5 {
+		t.Errorf("AvgLength = %v, want 3.5", idx.AvgLength())
+	}
+
+	// Replacing a doc drops its stale postings
+	idx.AddDoc(DocTerms{Title: "a", Terms: map[string]int{"z": 1}, Length: 1})
+	if _, ok := idx.Postings["y"]; ok {
+		t.Error("term y should be gone after replacing doc a")
+	}
+	if idx.DocFreq("x") != 1 || idx.TotalLength != 5 {
+		t.Errorf("DocFreq(x) = %d, TotalLength = %d; want 1, 5", idx.DocFreq("x"), idx.TotalLength)
+	}
+
+	idx.RemoveDoc("b")
+	if len(idx.Postings) != 1 || len(idx.Docs) != 1 {
+		t.Errorf("expected only doc a with term z, got %v", idx.Postings)
+	}
+}
+
 func TestTokenize(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -131,6 +201,20 @@ func TestTokenize(t *testing.T) {
 	}
 }
 
+func TestTokenizeSpans(t *testing.T) {
+	text := "The Planning, of releases"
+	spans := TokenizeSpans(text)
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %v", spans)
+	}
+	if got := text[spans[0].Start:spans[0].End]; got != "Planning" {
+		t.Errorf("span 0 = %q, want Planning", got)
+	}
+	if spans[0].Stem != "plan" || spans[1].Stem != "releas" {
+		t.Errorf("stems = %q, %q", spans[0].Stem, spans[1].Stem)
+	}
+}
+
 func TestBuildDocTerms(t *testing.T) {
 	doc := BuildDocTerms("Meeting Notes", "/notes/meeting.md", "The meeting was about running the project")
 
@@ -162,9 +246,17 @@ func TestIndexDocFTS(t *testing.T) {
 	if err != nil {
 		t.Fatalf("LoadFTS failed: %v", err)
 	}
-	if _, ok := idx["test-note"]; !ok {
+	if _, ok := idx.Docs["test-note"]; !ok {
 		t.Error("expected test-note in FTS index")
 	}
+
+	// Single-note updates go to the journal rather than rewriting fts.json
+	if _, err := os.Stat(FTSPath()); !os.IsNotExist(err) {
+		t.Error("fts.json should not be rewritten for a single update")
+	}
+	if _, err := os.Stat(FTSLogPath()); err != nil {
+		t.Errorf("expected FTS journal: %v", err)
+	}
 }
 
 func TestRemoveDocFTS(t *testing.T) {
@@ -186,7 +278,7 @@ func TestRemoveDocFTS(t *testing.T) {
 	if err != nil {
 		t.Fatalf("LoadFTS failed: %v", err)
 	}
-	if _, ok := idx["test-note"]; ok {
+	if _, ok := idx.Docs["test-note"]; ok {
 		t.Error("test-note should be removed from FTS index")
 	}
 }
@@ -222,7 +314,10 @@ func TestIndexAllFTS(t *testing.T) {
 	if err != nil {
 		t.Fatalf("LoadFTS failed: %v", err)
 	}
-	if len(idx) != 2 {
-		t.Errorf("expected 2 entries, got %d", len(idx))
+	if len(idx.Docs) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(idx.Docs))
+	}
+	if _, err := os.Stat(FTSLogPath()); !os.IsNotExist(err) {
+		t.Error("full rebuild should clear the FTS journal")
 	}
 }
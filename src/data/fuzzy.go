@@ -0,0 +1,205 @@
+package data
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Fuzzy scoring weights, loosely following fzf
+const (
+	fuzzyMatchScore    = 16 // per matched character
+	fuzzyBoundaryBonus = 8  // match at the start of a word
+	fuzzyConsecutive   = 8  // match directly after the previous one
+	fuzzyGapStart      = 5  // penalty for opening a gap
+	fuzzyGapExtend     = 1  // penalty per further skipped character
+	fuzzyMaxSpread     = 3  // a subsequence may span at most this many times its length
+)
+
+// FuzzyMatch is a candidate string and how well it matched
+type FuzzyMatch struct {
+	Text  string
+	Score int
+}
+
+// FuzzyScore scores how well pattern matches text. Each word of pattern must
+// occur in text either as a subsequence (so "mtg" matches "meeting") or
+// within MaxTypos edits of a word of text (so "metting" does too). Matching is
+// case-insensitive. Higher scores are better; ok is false if any word misses.
+func FuzzyScore(pattern, text string) (score int, ok bool) {
+	words := splitWords([]rune(strings.ToLower(pattern)))
+	if len(words) == 0 {
+		return 0, false
+	}
+	lower := []rune(strings.ToLower(text))
+	for _, w := range words {
+		s, found := fuzzyWordScore(w, lower)
+		if !found {
+			return 0, false
+		}
+		score += s
+	}
+	return score, true
+}
+
+func fuzzyWordScore(pattern, text []rune) (int, bool) {
+	if s, ok := subsequenceScore(pattern, text); ok {
+		return s, true
+	}
+
+	// Typo tolerance: compare against each word of text, and its prefix of
+	// the same length so "metting" still finds "meetings"
+	maxDist := MaxTypos(len(pattern))
+	if maxDist == 0 {
+		return 0, false
+	}
+	best := maxDist + 1
+	for _, word := range splitWords(text) {
+		d := EditDistance(pattern, word)
+		if len(word) > len(pattern) {
+			d = min(d, EditDistance(pattern, word[:len(pattern)]))
+		}
+		best = min(best, d)
+	}
+	if best > maxDist {
+		return 0, false
+	}
+	// Rank typo matches below any clean subsequence match of the same word
+	return max(1, len(pattern)*fuzzyMatchScore/2-best*fuzzyMatchScore), true
+}
+
+// subsequenceScore finds the best-scoring occurrence of pattern as a
+// subsequence of text, trying each possible starting point
+func subsequenceScore(pattern, text []rune) (int, bool) {
+	if len(pattern) == 0 || len(pattern) > len(text) {
+		return 0, false
+	}
+	// Very short patterns only match contiguously, or "go" would hit every "g...o"
+	maxSpan := fuzzyMaxSpread * len(pattern)
+	if len(pattern) < 3 {
+		maxSpan = len(pattern)
+	}
+	best, found := 0, false
+	for start := range text {
+		if text[start] != pattern[0] {
+			continue
+		}
+		score, end, ok := scoreFrom(pattern, text, start)
+		if !ok {
+			break // no later start can match either
+		}
+		if end-start+1 > maxSpan {
+			continue
+		}
+		if !found || score > best {
+			best, found = score, true
+		}
+	}
+	return best, found
+}
+
+// scoreFrom greedily matches pattern in text beginning at start
+func scoreFrom(pattern, text []rune, start int) (score, end int, ok bool) {
+	pi, prev := 0, -1
+	for i := start; i < len(text) && pi < len(pattern); i++ {
+		if text[i] != pattern[pi] {
+			continue
+		}
+		score += fuzzyMatchScore
+		if i == 0 || !isWordRune(text[i-1]) {
+			score += fuzzyBoundaryBonus
+		}
+		if prev >= 0 {
+			if gap := i - prev - 1; gap == 0 {
+				score += fuzzyConsecutive
+			} else {
+				score -= fuzzyGapStart + (gap-1)*fuzzyGapExtend
+			}
+		}
+		prev = i
+		pi++
+	}
+	return score, prev, pi == len(pattern)
+}
+
+// FuzzyRank returns the candidates matching pattern, best first. Ties go to
+// the shorter candidate, then alphabetical order.
+func FuzzyRank(pattern string, candidates []string) []FuzzyMatch {
+	var matches []FuzzyMatch
+	for _, c := range candidates {
+		if score, ok := FuzzyScore(pattern, c); ok {
+			matches = append(matches, FuzzyMatch{Text: c, Score: score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if len(matches[i].Text) != len(matches[j].Text) {
+			return len(matches[i].Text) < len(matches[j].Text)
+		}
+		return matches[i].Text < matches[j].Text
+	})
+	return matches
+}
+
+// MaxTypos returns how many edits a word of length n may contain and still match
+func MaxTypos(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// EditDistance returns the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and adjacent transpositions each cost 1.
+func EditDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func splitWords(text []rune) [][]rune {
+	var words [][]rune
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+		} else if start >= 0 {
+			words = append(words, text[start:i])
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, text[start:])
+	}
+	return words
+}
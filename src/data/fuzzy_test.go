@@ -0,0 +1,109 @@
+package data
+
+import (
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		pattern string
+		text    string
+		want    bool
+	}{
+		{"meeting", "Team Meeting", true},
+		{"mtg notes", "meeting notes", true},
+		{"meting", "meeting notes", true},
+		{"metting", "weekly meetings", true},
+		{"nots", "meeting notes", true},
+		{"xyz", "meeting notes", false},
+		{"go", "grocery list", false},                        // short patterns must be contiguous
+		{"plan", "a photo of the old villa at night", false}, // too spread out
+		{"meeting budget", "meeting notes", false},
+		{"", "anything", false},
+	}
+	for _, tt := range tests {
+		if _, ok := FuzzyScore(tt.pattern, tt.text); ok != tt.want {
+			t.Errorf("FuzzyScore(%q, %q) ok = %v, want %v", tt.pattern, tt.text, ok, tt.want)
+		}
+	}
+
+	exact, _ := FuzzyScore("meet", "meet ups")
+	sparse, _ := FuzzyScore("meet", "m e e t")
+	typo, _ := FuzzyScore("meet", "meat")
+	if !(exact > sparse && sparse > typo) {
+		t.Errorf("expected exact > sparse > typo, got %d, %d, %d", exact, sparse, typo)
+	}
+}
+
+func TestFuzzyRank(t *testing.T) {
+	ranked := FuzzyRank("mtg", []string{"meeting", "mtg notes", "budget", "my team gathering"})
+	if len(ranked) < 2 {
+		t.Fatalf("expected at least 2 matches, got %v", ranked)
+	}
+	if ranked[0].Text != "mtg notes" {
+		t.Errorf("expected contiguous match first, got %v", ranked)
+	}
+	for _, m := range ranked {
+		if m.Text == "budget" {
+			t.Errorf("budget should not match: %v", ranked)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"meting", "meeting", 1},
+		{"teh", "the", 1}, // transposition
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := EditDistance([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLookupNoteFuzzy(t *testing.T) {
+	index := map[string]NoteMeta{
+		"meeting notes": {Title: "meeting notes"},
+		"Groceries":     {Title: "Groceries"},
+		"plan a":        {Title: "plan a"},
+		"plan b":        {Title: "plan b"},
+	}
+
+	if key, _, ok := LookupNoteFuzzy(index, "groceries"); !ok || key != "Groceries" {
+		t.Errorf("case-insensitive lookup = %q, %v", key, ok)
+	}
+	if key, _, ok := LookupNoteFuzzy(index, "meting nots"); !ok || key != "meeting notes" {
+		t.Errorf("fuzzy lookup = %q, %v", key, ok)
+	}
+	if _, _, ok := LookupNoteFuzzy(index, "plan"); ok {
+		t.Error("ambiguous lookup should fail")
+	}
+	if _, _, ok := LookupNote(index, "meting nots"); ok {
+		t.Error("LookupNote should stay exact")
+	}
+}
+
+func TestSimilarTerms(t *testing.T) {
+	idx := NewFTSIndex()
+	idx.AddDoc(BuildDocTerms("a", "/a.md", "release planning"))
+	idx.AddDoc(BuildDocTerms("b", "/b.md", "release"))
+
+	got := idx.SimilarTerms(Tokenize("relase")[0])
+	if len(got) == 0 || got[0] != "releas" {
+		t.Errorf("SimilarTerms(relase) = %v, want releas first", got)
+	}
+	if got := idx.SimilarTerms("zzzzzz"); len(got) != 0 {
+		t.Errorf("expected no similar terms, got %v", got)
+	}
+	if got := idx.SimilarTerms("pln"); len(got) != 0 {
+		t.Errorf("short terms should not expand, got %v", got)
+	}
+}
@@ -0,0 +1,224 @@
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Snapshot files are named <seq>-<time>.md. The sequence number orders a
+// note's versions, so saves within the same millisecond, or a clock set
+// back, can't reorder them; the time is only for display and pruning.
+const snapshotTimeFmt = "060102.150405.000"
+
+// Snapshot is one saved version of a note
+type Snapshot struct {
+	Seq       int       // version number from the file name, kept when older versions are pruned
+	Timestamp time.Time // when the snapshot was taken
+	Path      string    // snapshot file path
+}
+
+// snapshotName is the file name of a note's seq'th snapshot
+func snapshotName(seq int, ts time.Time) string {
+	return fmt.Sprintf("%06d-%s.md", seq, ts.Format(snapshotTimeFmt))
+}
+
+// parseSnapshotName reads a snapshot file name. Names without a sequence
+// number, from before there was one, get 0; ListSnapshots numbers them.
+func parseSnapshotName(name string) (seq int, ts time.Time, ok bool) {
+	stem, ok := strings.CutSuffix(name, ".md")
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	if prefix, rest, found := strings.Cut(stem, "-"); found {
+		n, err := strconv.Atoi(prefix)
+		if err != nil {
+			return 0, time.Time{}, false
+		}
+		seq, stem = n, rest
+	}
+	ts, err := time.ParseInLocation(snapshotTimeFmt, stem, time.Local)
+	return seq, ts, err == nil
+}
+
+// HistoryPath returns the root directory of the snapshot store
+func HistoryPath() string {
+	return filepath.Join(GoteDir(), "history")
+}
+
+// noteHistoryDir returns the snapshot directory for a note
+func noteHistoryDir(noteName string) string {
+	return filepath.Join(HistoryPath(), noteName)
+}
+
+// ListSnapshots returns a note's snapshots, oldest first
+func ListSnapshots(noteName string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(noteHistoryDir(noteName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	var snapshots []Snapshot
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		seq, ts, ok := parseSnapshotName(e.Name())
+		if !ok {
+			continue // not a snapshot file
+		}
+		snapshots = append(snapshots, Snapshot{
+			Seq:       seq,
+			Timestamp: ts,
+			Path:      filepath.Join(noteHistoryDir(noteName), e.Name()),
+		})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Seq != snapshots[j].Seq {
+			return snapshots[i].Seq < snapshots[j].Seq
+		}
+		return snapshots[i].Timestamp.Before(snapshots[j].Timestamp)
+	})
+	if len(snapshots) > 0 && snapshots[0].Seq == 0 {
+		if err := numberSnapshots(noteName, snapshots); err != nil {
+			return nil, err
+		}
+	}
+	return snapshots, nil
+}
+
+// numberSnapshots renames a note's snapshots, sorted oldest first, to
+// sequence numbers 1, 2, ... so snapshots from before there were numbers get
+// one. Renaming newest first keeps two snapshots from the same millisecond
+// from landing on one name.
+func numberSnapshots(noteName string, snapshots []Snapshot) error {
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		s := &snapshots[i]
+		path := filepath.Join(noteHistoryDir(noteName), snapshotName(i+1, s.Timestamp))
+		if path != s.Path {
+			if err := os.Rename(s.Path, path); err != nil {
+				return fmt.Errorf("numbering snapshots: %w", err)
+			}
+		}
+		s.Seq, s.Path = i+1, path
+	}
+	return nil
+}
+
+// SaveSnapshot records content as a new version of the note unless it matches
+// the latest snapshot. Returns true if a snapshot was written.
+func SaveSnapshot(noteName string, content []byte) (bool, error) {
+	snapshots, err := ListSnapshots(noteName)
+	if err != nil {
+		return false, err
+	}
+	if len(snapshots) == 0 && len(content) == 0 {
+		return false, nil // nothing worth keeping yet
+	}
+	seq := 1
+	if len(snapshots) > 0 {
+		latest := snapshots[len(snapshots)-1]
+		prev, err := os.ReadFile(latest.Path)
+		if err == nil && bytes.Equal(prev, content) {
+			return false, nil
+		}
+		seq = latest.Seq + 1
+	}
+
+	dir := noteHistoryDir(noteName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, fmt.Errorf("creating history directory: %w", err)
+	}
+	path := filepath.Join(dir, snapshotName(seq, time.Now()))
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return false, fmt.Errorf("writing snapshot: %w", err)
+	}
+	return true, nil
+}
+
+// PruneHistory removes a note's oldest snapshots beyond maxVersions and any
+// older than maxAge. A zero limit disables that rule. The newest snapshot is always kept.
+func PruneHistory(noteName string, maxVersions int, maxAge time.Duration) error {
+	snapshots, err := ListSnapshots(noteName)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Time{}
+	if maxAge > 0 {
+		cutoff = time.Now().Add(-maxAge)
+	}
+	for i, s := range snapshots {
+		if i == len(snapshots)-1 {
+			break
+		}
+		tooMany := maxVersions > 0 && len(snapshots)-i > maxVersions
+		tooOld := maxAge > 0 && s.Timestamp.Before(cutoff)
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(s.Path); err != nil {
+			return fmt.Errorf("removing snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+// RenameHistory moves a note's snapshots to follow a rename
+func RenameHistory(oldName, newName string) error {
+	oldDir := noteHistoryDir(oldName)
+	oldInfo, err := os.Stat(oldDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+	newDir := noteHistoryDir(newName)
+	if err := os.MkdirAll(newDir, 0755); err != nil {
+		return fmt.Errorf("creating history directory: %w", err)
+	}
+	if newInfo, err := os.Stat(newDir); err == nil && os.SameFile(oldInfo, newInfo) {
+		return nil // only the case changed, on a case-insensitive file system
+	}
+	// Move snapshots one by one: newDir may already hold history, and oldDir
+	// may also hold the history of notes in a notebook of the same name
+	if err := moveSnapshots(oldName, newName); err != nil {
+		return err
+	}
+	removeEmptyDirs(oldDir, HistoryPath())
+	return nil
+}
+
+// moveSnapshots moves oldName's snapshots after those newName has, numbering
+// them on from its latest so the moved note's own versions end up newest
+func moveSnapshots(oldName, newName string) error {
+	snapshots, err := ListSnapshots(oldName)
+	if err != nil {
+		return err
+	}
+	existing, err := ListSnapshots(newName)
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(existing) > 0 {
+		next = existing[len(existing)-1].Seq + 1
+	}
+	for _, s := range snapshots {
+		if err := os.Rename(s.Path, filepath.Join(noteHistoryDir(newName), snapshotName(next, s.Timestamp))); err != nil {
+			return fmt.Errorf("moving snapshot: %w", err)
+		}
+		next++
+	}
+	return nil
+}
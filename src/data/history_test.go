@@ -0,0 +1,128 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryOperations(t *testing.T) {
+	dir, cleanup := testDir(t)
+	defer cleanup()
+
+	origGoteDir := GoteDir
+	GoteDir = func() string { return dir }
+	defer func() { GoteDir = origGoteDir }()
+
+	t.Run("empty first snapshot is skipped", func(t *testing.T) {
+		saved, err := SaveSnapshot("note", nil)
+		if err != nil {
+			t.Fatalf("SaveSnapshot failed: %v", err)
+		}
+		if saved {
+			t.Error("empty content should not create the first snapshot")
+		}
+	})
+
+	t.Run("identical content is deduplicated", func(t *testing.T) {
+		for _, content := range []string{"one", "one", "two", "three"} {
+			if _, err := SaveSnapshot("note", []byte(content)); err != nil {
+				t.Fatalf("SaveSnapshot failed: %v", err)
+			}
+		}
+		snapshots, err := ListSnapshots("note")
+		if err != nil {
+			t.Fatalf("ListSnapshots failed: %v", err)
+		}
+		if len(snapshots) != 3 {
+			t.Fatalf("expected 3 snapshots, got %d", len(snapshots))
+		}
+		first, _ := os.ReadFile(snapshots[0].Path)
+		if string(first) != "one" || snapshots[0].Seq != 1 {
+			t.Errorf("v1 = %q (version %d), want \"one\"", first, snapshots[0].Seq)
+		}
+	})
+
+	t.Run("PruneHistory keeps newest versions", func(t *testing.T) {
+		if err := PruneHistory("note", 2, 0); err != nil {
+			t.Fatalf("PruneHistory failed: %v", err)
+		}
+		snapshots, _ := ListSnapshots("note")
+		if len(snapshots) != 2 {
+			t.Fatalf("expected 2 snapshots, got %d", len(snapshots))
+		}
+		last, _ := os.ReadFile(snapshots[1].Path)
+		if string(last) != "three" {
+			t.Errorf("newest snapshot = %q, want three", last)
+		}
+	})
+
+	t.Run("versions keep their numbers after pruning", func(t *testing.T) {
+		snapshots, _ := ListSnapshots("note")
+		if len(snapshots) != 2 || snapshots[0].Seq != 2 || snapshots[1].Seq != 3 {
+			t.Errorf("versions after pruning = %+v, want 2 and 3", snapshots)
+		}
+	})
+
+	t.Run("PruneHistory by age never drops newest", func(t *testing.T) {
+		if err := PruneHistory("note", 0, time.Nanosecond); err != nil {
+			t.Fatalf("PruneHistory failed: %v", err)
+		}
+		snapshots, _ := ListSnapshots("note")
+		if len(snapshots) != 1 {
+			t.Errorf("expected 1 snapshot, got %d", len(snapshots))
+		}
+	})
+
+	t.Run("RenameHistory follows rename", func(t *testing.T) {
+		if err := RenameHistory("note", "renamed"); err != nil {
+			t.Fatalf("RenameHistory failed: %v", err)
+		}
+		old, _ := ListSnapshots("note")
+		renamed, _ := ListSnapshots("renamed")
+		if len(old) != 0 || len(renamed) != 1 {
+			t.Errorf("old=%d renamed=%d, want 0 and 1", len(old), len(renamed))
+		}
+	})
+
+	t.Run("rapid saves keep their order without future timestamps", func(t *testing.T) {
+		// A snapshot from before sequence numbers sorts first
+		os.MkdirAll(noteHistoryDir("fast"), 0755)
+		legacy := time.Now().Add(-time.Hour).Format(snapshotTimeFmt) + ".md"
+		os.WriteFile(filepath.Join(noteHistoryDir("fast"), legacy), []byte("v0"), 0644)
+		for i := 1; i <= 5; i++ {
+			if _, err := SaveSnapshot("fast", []byte(fmt.Sprint("v", i))); err != nil {
+				t.Fatalf("SaveSnapshot failed: %v", err)
+			}
+		}
+		snapshots, _ := ListSnapshots("fast")
+		if len(snapshots) != 6 {
+			t.Fatalf("expected 6 snapshots, got %d", len(snapshots))
+		}
+		for i, s := range snapshots {
+			content, _ := os.ReadFile(s.Path)
+			if want := fmt.Sprint("v", i); string(content) != want || s.Seq != i+1 {
+				t.Errorf("version %d = %q, want %q", s.Seq, content, want)
+			}
+			if s.Timestamp.After(time.Now()) {
+				t.Errorf("version %d stamped in the future: %s", s.Seq, s.Timestamp)
+			}
+		}
+	})
+
+	t.Run("RenameHistory puts merged versions after existing ones", func(t *testing.T) {
+		SaveSnapshot("older", []byte("moved"))
+		if err := RenameHistory("older", "fast"); err != nil {
+			t.Fatalf("RenameHistory failed: %v", err)
+		}
+		snapshots, _ := ListSnapshots("fast")
+		if len(snapshots) != 7 {
+			t.Fatalf("expected 7 snapshots, got %d", len(snapshots))
+		}
+		if newest, _ := os.ReadFile(snapshots[6].Path); string(newest) != "moved" {
+			t.Errorf("newest = %q, want the moved note's version", newest)
+		}
+	})
+}
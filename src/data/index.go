@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"unicode/utf8"
 )
@@ -19,6 +20,12 @@ type NoteMeta struct {
 	WordCount   int      `json:"wordCount"`
 	CharCount   int      `json:"charCount"`
 	Tags        []string `json:"tags"`
+	Links       []string `json:"links,omitempty"`
+	Tasks       []Task   `json:"tasks,omitempty"`
+
+	// From the note's front matter
+	Aliases    []string          `json:"aliases,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
 }
 
 func IndexPath() string {
@@ -44,13 +51,16 @@ func SaveIndex(index map[string]NoteMeta) error {
 	return AtomicWriteJSON(IndexPath(), index)
 }
 
-// SaveIndexWithTags atomically saves the index and updates the tags index.
+// SaveIndexWithTags atomically saves the index and updates the tags and links indexes.
 // Use this instead of separate SaveIndex + UpdateTagsIndex calls.
 func SaveIndexWithTags(index map[string]NoteMeta) error {
 	if err := SaveIndex(index); err != nil {
 		return err
 	}
-	return UpdateTagsIndex(index)
+	if err := UpdateTagsIndex(index); err != nil {
+		return err
+	}
+	return UpdateLinksIndex(index)
 }
 
 func IndexNotes(notesDir string) error {
@@ -64,11 +74,18 @@ func IndexNotes(notesDir string) error {
 			return err
 		}
 
-		if info.IsDir() || filepath.Ext(path) != ".md" || info.Mode()&os.ModeSymlink != 0 {
+		if info.IsDir() {
+			// Hidden directories (.git, .obsidian, ...) are not notebooks
+			if path != notesDir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".md" || info.Mode()&os.ModeSymlink != 0 {
 			return nil
 		}
 
-		meta, err := BuildNoteMeta(path, info)
+		meta, err := buildNoteMeta(NoteName(notesDir, path), path, info)
 		if err != nil {
 			return err
 		}
@@ -100,11 +117,9 @@ func IndexNotes(notesDir string) error {
 	return FormatTagsFile()
 }
 
+// IndexNote reindexes one note, holding the index lock, and updates its FTS
+// entry. The created and last visited times already indexed are kept.
 func IndexNote(notePath string) error {
-	index, err := LoadIndex()
-	if err != nil {
-		return fmt.Errorf("loading index: %w", err)
-	}
 	info, err := os.Stat(notePath)
 	if err != nil {
 		return err
@@ -115,17 +130,19 @@ func IndexNote(notePath string) error {
 		return err
 	}
 
-	if existing, ok := index[meta.Title]; ok {
-		if existing.Created != "" {
-			meta.Created = existing.Created
-		}
-		if existing.LastVisited != "" {
-			meta.LastVisited = existing.LastVisited
+	err = WithIndexLock(func(index map[string]NoteMeta) error {
+		if existing, ok := index[meta.Title]; ok {
+			if existing.Created != "" {
+				meta.Created = existing.Created
+			}
+			if existing.LastVisited != "" {
+				meta.LastVisited = existing.LastVisited
+			}
 		}
-	}
-
-	index[meta.Title] = meta
-	if err := SaveIndexWithTags(index); err != nil {
+		index[meta.Title] = meta
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
@@ -136,33 +153,56 @@ func IndexNote(notePath string) error {
 	return IndexDocFTS(meta.Title, meta.FilePath, string(content))
 }
 
+// BuildNoteMeta reads a note and builds its metadata. The title is the note's
+// name relative to the configured noteDir, e.g. "work/standup".
 func BuildNoteMeta(notePath string, info os.FileInfo) (NoteMeta, error) {
+	return buildNoteMeta(NoteName(configuredNoteDir(), notePath), notePath, info)
+}
+
+func buildNoteMeta(title, notePath string, info os.FileInfo) (NoteMeta, error) {
 	data, err := os.ReadFile(notePath)
 	if err != nil {
 		return NoteMeta{}, err
 	}
 	text := string(data)
-	title := strings.TrimSuffix(filepath.Base(notePath), ".md")
 	wordCount := len(strings.Fields(text))
 	charCount := utf8.RuneCountInString(text)
 	created := GetBirthtime(info).Format("060102.150405")
 	modified := info.ModTime().Format("060102.150405")
+
+	fm, _ := ParseFrontMatter(text)
+	meta := NoteMeta{
+		FilePath:   notePath,
+		Title:      title,
+		Created:    created,
+		Modified:   modified,
+		WordCount:  wordCount,
+		CharCount:  charCount,
+		Tags:       NoteTags(text),
+		Links:      ParseLinks(text, title),
+		Tasks:      ParseTasks(text),
+		Aliases:    fm.Aliases,
+		Properties: fm.Properties,
+	}
+	return meta, nil
+}
+
+// NoteTags returns the tags a note declares. The .tag line is the first line
+// after any front matter, and both add tags.
+func NoteTags(text string) []string {
+	fm, body := ParseFrontMatter(text)
 	firstLine := ""
-	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner := bufio.NewScanner(strings.NewReader(body))
 	if scanner.Scan() {
 		firstLine = scanner.Text()
 	}
 	tags := ParseTags(firstLine)
-	meta := NoteMeta{
-		FilePath:  notePath,
-		Title:     title,
-		Created:   created,
-		Modified:  modified,
-		WordCount: wordCount,
-		CharCount: charCount,
-		Tags:      tags,
+	for _, tag := range fm.Tags {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
 	}
-	return meta, nil
+	return tags
 }
 
 func ParseTags(line string) []string {
@@ -177,15 +217,25 @@ func ParseTags(line string) []string {
 	parts := strings.Split(clean, ".")
 	var tags []string
 	for _, part := range parts {
-		tag := strings.ToLower(part)
-		tag = strings.TrimSpace(tag)
-		if tag != "" {
+		if tag := NormalizeTag(part); tag != "" {
 			tags = append(tags, tag)
 		}
 	}
 	return tags
 }
 
+// NormalizeTag lowercases a tag and tidies its hierarchy separators, so
+// " Work//Clients/ " becomes "work/clients"
+func NormalizeTag(tag string) string {
+	var levels []string
+	for _, level := range strings.Split(strings.ToLower(tag), "/") {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, level)
+		}
+	}
+	return strings.Join(levels, "/")
+}
+
 func FormatIndexFile() error {
 	return FormatJSONFile(IndexPath())
 }
@@ -232,6 +282,9 @@ func WithPinsLock(fn func(map[string]EmptyStruct) error) error {
 }
 
 // LookupNote finds a note by name (case-insensitive). Returns actual key, metadata, and found bool.
+// A name without a notebook also finds a note inside one ("standup" finds
+// "work/standup") as long as only one note has that name. Failing that, a
+// note whose front matter lists name as an alias is found.
 func LookupNote(index map[string]NoteMeta, name string) (string, NoteMeta, bool) {
 	if meta, ok := index[name]; ok {
 		return name, meta, true
@@ -241,5 +294,57 @@ func LookupNote(index map[string]NoteMeta, name string) (string, NoteMeta, bool)
 			return key, meta, true
 		}
 	}
-	return "", NoteMeta{}, false
+	if strings.Contains(name, "/") {
+		return "", NoteMeta{}, false
+	}
+	found := ""
+	for key := range index {
+		if strings.EqualFold(BaseName(key), name) {
+			if found != "" {
+				return "", NoteMeta{}, false // ambiguous
+			}
+			found = key
+		}
+	}
+	if found == "" {
+		return lookupAlias(index, name)
+	}
+	return found, index[found], true
+}
+
+// lookupAlias finds the one note with name among its front matter aliases
+func lookupAlias(index map[string]NoteMeta, name string) (string, NoteMeta, bool) {
+	found := ""
+	for key, meta := range index {
+		for _, alias := range meta.Aliases {
+			if strings.EqualFold(alias, name) {
+				if found != "" && found != key {
+					return "", NoteMeta{}, false // ambiguous
+				}
+				found = key
+			}
+		}
+	}
+	if found == "" {
+		return "", NoteMeta{}, false
+	}
+	return found, index[found], true
+}
+
+// LookupNoteFuzzy is LookupNote with a fuzzy fallback: if no title matches
+// exactly, the best fuzzy match is used, provided it beats every other candidate.
+// Use it only where acting on a near miss is harmless.
+func LookupNoteFuzzy(index map[string]NoteMeta, name string) (string, NoteMeta, bool) {
+	if key, meta, ok := LookupNote(index, name); ok {
+		return key, meta, true
+	}
+	titles := make([]string, 0, len(index))
+	for key := range index {
+		titles = append(titles, key)
+	}
+	ranked := FuzzyRank(name, titles)
+	if len(ranked) == 0 || (len(ranked) > 1 && ranked[1].Score == ranked[0].Score) {
+		return "", NoteMeta{}, false
+	}
+	return ranked[0].Text, index[ranked[0].Text], true
 }
@@ -0,0 +1,291 @@
+package data
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LinkMeta stores the outgoing links and backlinks of one note
+type LinkMeta struct {
+	Note      string   `json:"note"`
+	Links     []string `json:"links"`
+	Backlinks []string `json:"backlinks"`
+}
+
+// wikiLinkRegex matches [[target]], [[target|alias]] and [[target#heading]]
+var wikiLinkRegex = regexp.MustCompile(`\[\[([^\[\]|#]+)([#|][^\[\]]*)?\]\]`)
+
+// mdLinkRegex matches [text](target.md) links to local notes
+var mdLinkRegex = regexp.MustCompile(`\[([^\[\]]*)\]\(([^()\s]+)\.md(#[^()\s]*)?\)`)
+
+// embedRegex matches Obsidian's ![[file]] and ![[file|size]] embeds
+var embedRegex = regexp.MustCompile(`!\[\[([^\[\]|#]+)([#|][^\[\]]*)?\]\]`)
+
+// ReplaceEmbeds replaces each ![[embed]] in text with what replace returns
+// for the embedded file or note
+func ReplaceEmbeds(text string, replace func(target string) string) string {
+	return embedRegex.ReplaceAllStringFunc(text, func(match string) string {
+		return replace(strings.TrimSpace(embedRegex.FindStringSubmatch(match)[1]))
+	})
+}
+
+func LinksPath() string {
+	return filepath.Join(GoteDir(), "links.json")
+}
+
+// ParseLinks returns the note names referenced by [[wiki]] and [text](note.md) links
+// in the note from, deduplicated in order of first appearance. Markdown links
+// are resolved from from's notebook, so they name notes by their full path.
+func ParseLinks(text, from string) []string {
+	var links []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		links = append(links, name)
+	}
+
+	for _, m := range wikiLinkRegex.FindAllStringSubmatch(text, -1) {
+		add(m[1])
+	}
+	for _, m := range mdLinkRegex.FindAllStringSubmatch(text, -1) {
+		if name, ok := mdLinkNote(from, m[2]); ok {
+			add(name)
+		}
+	}
+	return links
+}
+
+// mdLinkNote is the note a [text](target.md) link in the note from points
+// to. target, without ".md", is a path from from's notebook, or from the
+// notes folder if it starts with "/", and may be percent-encoded. ok is
+// false for links to other sites.
+func mdLinkNote(from, target string) (string, bool) {
+	if strings.Contains(target, "://") {
+		return "", false
+	}
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(path.Clean(target), "/"), true
+	}
+	return path.Join(NotebookOf(from), target), true
+}
+
+// mdLinkTarget is the target, without ".md", of a Markdown link from the note
+// from to the note name, written the way the target it replaces was: from
+// the notes folder if that started with "/", else relative. It's escaped, as
+// a space or parenthesis would end the link.
+func mdLinkTarget(from, name, old string) string {
+	target := "/" + name
+	if !strings.HasPrefix(old, "/") {
+		target = relativeNotePath(NotebookOf(from), name)
+		if strings.HasPrefix(old, "./") && !strings.HasPrefix(target, "../") {
+			target = "./" + target
+		}
+	}
+	return (&url.URL{Path: target}).EscapedPath()
+}
+
+// relativeNotePath is the path from notebook to the note name
+func relativeNotePath(notebook, name string) string {
+	var dirs []string
+	if notebook != "" {
+		dirs = strings.Split(notebook, "/")
+	}
+	parts := strings.Split(name, "/")
+	common := 0
+	for common < len(dirs) && common < len(parts)-1 && strings.EqualFold(dirs[common], parts[common]) {
+		common++
+	}
+	return strings.Repeat("../", len(dirs)-common) + strings.Join(parts[common:], "/")
+}
+
+// RewriteLinks replaces every [[oldName]] and [text](oldName.md) reference in text,
+// the content of the note from, with newName, preserving aliases and heading anchors.
+// Matching is case-insensitive. Links that name a notebook note by its base
+// name alone ([[standup]] for work/standup) stay short unless the note moved
+// to another notebook. Markdown links get the path from from's notebook.
+func RewriteLinks(text, from, oldName, newName string) string {
+	text = wikiLinkRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := wikiLinkRegex.FindStringSubmatch(match)
+		target := strings.TrimSpace(m[1])
+		switch {
+		case strings.EqualFold(target, oldName):
+			return "[[" + newName + m[2] + "]]"
+		case !strings.Contains(target, "/") && strings.EqualFold(target, BaseName(oldName)):
+			if NotebookOf(oldName) == NotebookOf(newName) {
+				return "[[" + BaseName(newName) + m[2] + "]]"
+			}
+			return "[[" + newName + m[2] + "]]"
+		}
+		return match
+	})
+	return mdLinkRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := mdLinkRegex.FindStringSubmatch(match)
+		if name, ok := mdLinkNote(from, m[2]); !ok || !strings.EqualFold(name, oldName) {
+			return match
+		}
+		return "[" + m[1] + "](" + mdLinkTarget(from, newName, m[2]) + ".md" + m[3] + ")"
+	})
+}
+
+// RebaseLinks updates the relative Markdown links in text, the content of a
+// note moving from oldFrom to newFrom, so they reach the same notes from its
+// new notebook
+func RebaseLinks(text, oldFrom, newFrom string) string {
+	if NotebookOf(oldFrom) == NotebookOf(newFrom) {
+		return text
+	}
+	return mdLinkRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := mdLinkRegex.FindStringSubmatch(match)
+		name, ok := mdLinkNote(oldFrom, m[2])
+		if !ok || strings.HasPrefix(m[2], "/") {
+			return match
+		}
+		return "[" + m[1] + "](" + mdLinkTarget(newFrom, name, m[2]) + ".md" + m[3] + ")"
+	})
+}
+
+// LinkSpan is a link found by FindLinks. Start and End are byte offsets of
+// the whole link in the text searched.
+type LinkSpan struct {
+	Start, End int
+	Target     string // the linked note's name
+	Anchor     string // "#heading", or ""
+	Embed      bool   // a ![[file]] embed
+}
+
+// FindLinks returns the [[wiki]] links, embeds and [text](note.md) links in
+// text, part of the note from, in the order they appear
+func FindLinks(text, from string) []LinkSpan {
+	var spans []LinkSpan
+	for _, loc := range wikiLinkRegex.FindAllStringSubmatchIndex(text, -1) {
+		span := LinkSpan{Start: loc[0], End: loc[1], Target: strings.TrimSpace(text[loc[2]:loc[3]])}
+		if loc[4] >= 0 && text[loc[4]] == '#' {
+			span.Anchor, _, _ = strings.Cut(text[loc[4]:loc[5]], "|")
+		}
+		if span.Start > 0 && text[span.Start-1] == '!' {
+			span.Start--
+			span.Embed = true
+		}
+		spans = append(spans, span)
+	}
+	for _, loc := range mdLinkRegex.FindAllStringSubmatchIndex(text, -1) {
+		name, ok := mdLinkNote(from, text[loc[4]:loc[5]])
+		if !ok {
+			continue
+		}
+		span := LinkSpan{Start: loc[0], End: loc[1], Target: name}
+		if loc[6] >= 0 {
+			span.Anchor = text[loc[6]:loc[7]]
+		}
+		spans = append(spans, span)
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	return spans
+}
+
+// ReplaceLinks replaces each [[wiki]] and [text](note.md) link in text with
+// what replace returns for it. replace gets the linked note's name, the
+// link's label ("" for a bare [[note]]) and its #heading anchor, if any.
+// Markdown links are resolved from the notebook of from, the note text is in.
+func ReplaceLinks(text, from string, replace func(target, label, anchor string) string) string {
+	text = wikiLinkRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := wikiLinkRegex.FindStringSubmatch(match)
+		anchor, label, _ := strings.Cut(m[2], "|")
+		if strings.HasPrefix(m[2], "|") {
+			anchor, label = "", m[2][1:]
+		}
+		return replace(strings.TrimSpace(m[1]), strings.TrimSpace(label), anchor)
+	})
+	return mdLinkRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := mdLinkRegex.FindStringSubmatch(match)
+		name, ok := mdLinkNote(from, m[2])
+		if !ok {
+			return match
+		}
+		return replace(name, m[1], m[3])
+	})
+}
+
+// UpdateLinksIndex rebuilds links.json from the index
+func UpdateLinksIndex(notes map[string]NoteMeta) error {
+	return AtomicWriteJSON(LinksPath(), BuildLinksIndex(notes))
+}
+
+// BuildLinksIndex returns the contents of links.json for the given index. Link
+// targets are resolved to index keys case-insensitively; links to missing
+// notes are kept as written.
+func BuildLinksIndex(notes map[string]NoteMeta) map[string]LinkMeta {
+	lowerToKey := make(map[string]string, len(notes))
+	baseToKey := make(map[string]string) // "" marks a base name shared by several notes
+	for key := range notes {
+		lowerToKey[strings.ToLower(key)] = key
+		base := strings.ToLower(BaseName(key))
+		if _, dup := baseToKey[base]; dup {
+			baseToKey[base] = ""
+		} else {
+			baseToKey[base] = key
+		}
+	}
+
+	linkMap := make(map[string]LinkMeta)
+	for key, note := range notes {
+		for _, link := range note.Links {
+			target := link
+			if actual, ok := lowerToKey[strings.ToLower(link)]; ok {
+				target = actual
+			} else if actual := baseToKey[strings.ToLower(link)]; actual != "" && !strings.Contains(link, "/") {
+				// [[standup]] reaches work/standup when the name is unique
+				target = actual
+			}
+
+			lm := linkMap[key]
+			lm.Note = key
+			lm.Links = append(lm.Links, target)
+			linkMap[key] = lm
+
+			tm := linkMap[target]
+			tm.Note = target
+			tm.Backlinks = append(tm.Backlinks, key)
+			linkMap[target] = tm
+		}
+	}
+	for key, lm := range linkMap {
+		sort.Strings(lm.Backlinks)
+		linkMap[key] = lm
+	}
+	return linkMap
+}
+
+func LoadLinks() (map[string]LinkMeta, error) {
+	data, err := os.ReadFile(LinksPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]LinkMeta), nil
+		}
+		return nil, err
+	}
+
+	var links map[string]LinkMeta
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func FormatLinksFile() error {
+	return FormatJSONFile(LinksPath())
+}
@@ -0,0 +1,201 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"wiki link", "see [[other note]] here", []string{"other note"}},
+		{"alias and heading", "[[a|Alias]] and [[b#Section]]", []string{"a", "b"}},
+		{"markdown link", "[text](other.md)", []string{"other"}},
+		{"escaped markdown link", "[text](my%20note.md)", []string{"my note"}},
+		{"external link ignored", "[site](https://example.com/x.md)", nil},
+		{"dedupe case-insensitive", "[[Note]] [[note]]", []string{"Note"}},
+		{"no links", "plain text", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseLinks(tt.input, "")
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLinks(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteLinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"wiki link", "see [[old]]", "see [[new name]]"},
+		{"keeps alias", "[[Old|label]]", "[[new name|label]]"},
+		{"keeps heading", "[[old#intro]]", "[[new name#intro]]"},
+		{"markdown link", "[x](old.md)", "[x](new%20name.md)"},
+		{"relative path and anchor", "[x](./old.md#top)", "[x](./new%20name.md#top)"},
+		{"percent-encoded stays encoded", "[x](old%20note.md)", "[x](old%20note.md)"},
+		{"other links untouched", "[[older]] [y](older.md)", "[[older]] [y](older.md)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RewriteLinks(tt.input, "", "old", "new name")
+			if got != tt.want {
+				t.Errorf("RewriteLinks(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("notebook notes", func(t *testing.T) {
+		text := "[[work/standup]] [[standup|daily]] [x](standup.md)"
+		if got := RewriteLinks(text, "work/idea", "work/standup", "work/daily"); got != "[[work/daily]] [[daily|daily]] [x](daily.md)" {
+			t.Errorf("rename within notebook = %q", got)
+		}
+		if got := RewriteLinks("[[standup]]", "", "work/standup", "personal/standup"); got != "[[personal/standup]]" {
+			t.Errorf("move to another notebook = %q", got)
+		}
+	})
+
+	t.Run("rewritten links still parse", func(t *testing.T) {
+		got := RewriteLinks("[x](old.md)", "", "old", "plan (v2)")
+		if got != "[x](plan%20%28v2%29.md)" || !reflect.DeepEqual(ParseLinks(got, ""), []string{"plan (v2)"}) {
+			t.Errorf("got %q, links %v", got, ParseLinks(got, ""))
+		}
+	})
+
+	t.Run("percent-encoded target", func(t *testing.T) {
+		got := RewriteLinks("[x](old%20note.md)", "", "old note", "new note")
+		if got != "[x](new%20note.md)" {
+			t.Errorf("got %q, want [x](new%%20note.md)", got)
+		}
+	})
+}
+
+func TestNotebookMarkdownLinks(t *testing.T) {
+	text := "[a](plan.md) [b](../home/plan.md) [c](/home/plan.md) [d](./sub/x.md) [[plan]]"
+	if got, want := ParseLinks(text, "work/idea"), []string{"plan", "work/plan", "home/plan", "work/sub/x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLinks = %v, want %v", got, want)
+	}
+
+	t.Run("target moves to another notebook", func(t *testing.T) {
+		got := RewriteLinks("[a](plan.md) [b](/work/plan.md) [c](../home/plan.md)", "work/idea", "work/plan", "archive/plan")
+		if want := "[a](../archive/plan.md) [b](/archive/plan.md) [c](../home/plan.md)"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		got = RewriteLinks("[a](work/plan.md)", "index", "work/plan", "work/deep/plan")
+		if want := "[a](work/deep/plan.md)"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("same base name in another notebook is another note", func(t *testing.T) {
+		if got := RewriteLinks("[a](../home/plan.md)", "work/idea", "work/plan", "work/done"); got != "[a](../home/plan.md)" {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("linking note moves", func(t *testing.T) {
+		got := RebaseLinks("[a](plan.md) [b](/home/plan.md) [c](https://x.io/a.md) [[plan]]", "work/idea", "archive/old/idea")
+		if want := "[a](../../work/plan.md) [b](/home/plan.md) [c](https://x.io/a.md) [[plan]]"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+}
+
+func TestUpdateLinksIndex(t *testing.T) {
+	dir, cleanup := testDir(t)
+	defer cleanup()
+
+	origGoteDir := GoteDir
+	GoteDir = func() string { return dir }
+	defer func() { GoteDir = origGoteDir }()
+
+	index := map[string]NoteMeta{
+		"a": {Title: "a", Links: []string{"B", "missing"}},
+		"b": {Title: "b", Links: []string{"a"}},
+		"c": {Title: "c", Links: []string{"b"}},
+	}
+	if err := UpdateLinksIndex(index); err != nil {
+		t.Fatalf("UpdateLinksIndex failed: %v", err)
+	}
+
+	links, err := LoadLinks()
+	if err != nil {
+		t.Fatalf("LoadLinks failed: %v", err)
+	}
+	if !reflect.DeepEqual(links["a"].Links, []string{"b", "missing"}) {
+		t.Errorf("a links = %v, want [b missing]", links["a"].Links)
+	}
+	if !reflect.DeepEqual(links["b"].Backlinks, []string{"a", "c"}) {
+		t.Errorf("b backlinks = %v, want [a c]", links["b"].Backlinks)
+	}
+	if !reflect.DeepEqual(links["missing"].Backlinks, []string{"a"}) {
+		t.Errorf("missing backlinks = %v, want [a]", links["missing"].Backlinks)
+	}
+}
+
+func TestReplaceLinks(t *testing.T) {
+	replace := func(target, label, anchor string) string {
+		return "<" + target + "|" + label + "|" + anchor + ">"
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"[[note]]", "<note||>"},
+		{"[[ note |Alias]]", "<note|Alias|>"},
+		{"[[note#Part two|Alias]]", "<note|Alias|#Part two>"},
+		{"[text](work/my%20note.md#x)", "<work/my note|text|#x>"},
+		{"[site](https://example.com/x.md)", "[site](https://example.com/x.md)"},
+	}
+	for _, tt := range tests {
+		if got := ReplaceLinks(tt.input, "", replace); got != tt.want {
+			t.Errorf("ReplaceLinks(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestReplaceEmbeds(t *testing.T) {
+	got := ReplaceEmbeds("see ![[pic.png|300]] and ![[ note#part ]] but not [[link]]", func(target string) string {
+		return "<" + target + ">"
+	})
+	if want := "see <pic.png> and <note> but not [[link]]"; got != want {
+		t.Errorf("ReplaceEmbeds = %q, want %q", got, want)
+	}
+}
+
+func TestFindLinks(t *testing.T) {
+	text := "[md](sub/my%20note.md#Part) then [[faq#Big Question|FAQ]], ![[shot.png]] and [web](https://x.io/a.md)"
+	want := []struct {
+		link string
+		span LinkSpan
+	}{
+		{"[md](sub/my%20note.md#Part)", LinkSpan{Target: "work/sub/my note", Anchor: "#Part"}},
+		{"[[faq#Big Question|FAQ]]", LinkSpan{Target: "faq", Anchor: "#Big Question"}},
+		{"![[shot.png]]", LinkSpan{Target: "shot.png", Embed: true}},
+	}
+	got := FindLinks(text, "work/idea")
+	if len(got) != len(want) {
+		t.Fatalf("FindLinks = %+v, want %d links", got, len(want))
+	}
+	for i, w := range want {
+		if link := text[got[i].Start:got[i].End]; link != w.link {
+			t.Errorf("link %d spans %q, want %q", i, link, w.link)
+		}
+		got[i].Start, got[i].End = 0, 0
+		if got[i] != w.span {
+			t.Errorf("link %d = %+v, want %+v", i, got[i], w.span)
+		}
+	}
+}
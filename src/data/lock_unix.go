@@ -10,15 +10,27 @@ import (
 // LockFile acquires an exclusive lock on path.lock. Blocks until lock is acquired.
 func LockFile(path string) (*FileLock, error) {
 	lockPath := path + ".lock"
-	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
-	if err != nil {
-		return nil, err
-	}
-	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+			f.Close()
+			return nil, err
+		}
+		// The holder we waited for removed the file as it unlocked, and
+		// someone else may have locked a new one; start over on that
+		held, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if current, err := os.Stat(lockPath); err == nil && os.SameFile(held, current) {
+			return &FileLock{path: lockPath, file: f}, nil
+		}
 		f.Close()
-		return nil, err
 	}
-	return &FileLock{path: lockPath, file: f}, nil
 }
 
 // Unlock releases the lock and removes the lock file.
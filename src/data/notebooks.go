@@ -0,0 +1,158 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Notebooks are directories under noteDir. A note's name is its path relative
+// to noteDir without the .md extension, always with forward slashes, e.g.
+// "work/standup". Notes at the top level have plain names as before.
+
+// NoteName returns the name of the note at notePath. Paths outside notesDir
+// fall back to the bare file name.
+func NoteName(notesDir, notePath string) string {
+	base := strings.TrimSuffix(filepath.Base(notePath), ".md")
+	if notesDir == "" {
+		return base
+	}
+	rel, err := filepath.Rel(notesDir, notePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return base
+	}
+	return strings.TrimSuffix(filepath.ToSlash(rel), ".md")
+}
+
+// NotePath returns the file path of the note with the given name
+func NotePath(notesDir, name string) string {
+	return filepath.Join(notesDir, filepath.FromSlash(name)+".md")
+}
+
+// NotebookOf returns the notebook part of a note name ("" for top-level notes)
+func NotebookOf(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// BaseName returns a note name without its notebook
+func BaseName(name string) string {
+	return path.Base(name)
+}
+
+// InNotebook reports whether the note name lies in notebook or one of its
+// sub-notebooks. Matching is case-insensitive; "" matches every note.
+func InNotebook(name, notebook string) bool {
+	notebook = strings.Trim(notebook, "/")
+	if notebook == "" {
+		return true
+	}
+	return len(name) > len(notebook) &&
+		strings.EqualFold(name[:len(notebook)], notebook) &&
+		name[len(notebook)] == '/'
+}
+
+// ValidateNotePath checks a note name that may include notebooks, like
+// "work/standup". Each segment must be a valid note name.
+func ValidateNotePath(name string) error {
+	if name == "" {
+		return fmt.Errorf("note name cannot be empty")
+	}
+	if strings.Contains(name, "\\") {
+		return fmt.Errorf("note name cannot contain \\ (use / for notebooks)")
+	}
+	for _, seg := range strings.Split(name, "/") {
+		if seg == "" {
+			return fmt.Errorf("note name cannot contain empty notebook segments: %s", name)
+		}
+		if strings.HasPrefix(seg, ".") {
+			return fmt.Errorf("notebook and note names cannot start with .")
+		}
+		if err := ValidateNoteName(seg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateNotebookName checks a notebook path such as "work/projects"
+func ValidateNotebookName(name string) error {
+	if strings.Trim(name, "/") == "" {
+		return fmt.Errorf("notebook name cannot be empty")
+	}
+	return ValidateNotePath(strings.Trim(name, "/"))
+}
+
+// Notebook is a directory of notes
+type Notebook struct {
+	Name  string // relative path, e.g. "work/projects"
+	Notes int    // notes directly in this notebook
+	Total int    // notes in this notebook and its sub-notebooks
+}
+
+// ListNotebooks returns every notebook directory under notesDir, sorted by
+// name, with note counts taken from the index. Hidden directories are skipped.
+func ListNotebooks(notesDir string, index map[string]NoteMeta) ([]Notebook, error) {
+	books := make(map[string]*Notebook)
+	err := filepath.Walk(notesDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || p == notesDir {
+			return nil
+		}
+		if strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		name := NoteName(notesDir, p+".md")
+		books[name] = &Notebook{Name: name}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading notebooks: %w", err)
+	}
+
+	for name := range index {
+		nb := NotebookOf(name)
+		if b, ok := books[nb]; ok {
+			b.Notes++
+		}
+		for ; nb != ""; nb = NotebookOf(nb) {
+			if b, ok := books[nb]; ok {
+				b.Total++
+			}
+		}
+	}
+
+	list := make([]Notebook, 0, len(books))
+	for _, b := range books {
+		list = append(list, *b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// configuredNoteDir returns noteDir from config.json without creating the
+// file, or "" if it can't be read
+func configuredNoteDir() string {
+	raw, err := os.ReadFile(configPath())
+	if err != nil {
+		return ""
+	}
+	var cfg struct {
+		NoteDir string `json:"noteDir"`
+	}
+	if json.Unmarshal(raw, &cfg) != nil {
+		return ""
+	}
+	return cfg.NoteDir
+}
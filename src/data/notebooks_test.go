@@ -0,0 +1,157 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNoteNames(t *testing.T) {
+	notesDir := filepath.Join("home", "gotes")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(notesDir, "todo.md"), "todo"},
+		{filepath.Join(notesDir, "work", "standup.md"), "work/standup"},
+		{filepath.Join(notesDir, "a", "b", "c.md"), "a/b/c"},
+		{filepath.Join("elsewhere", "x.md"), "x"},
+	}
+	for _, tt := range tests {
+		if got := NoteName(notesDir, tt.path); got != tt.want {
+			t.Errorf("NoteName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+		if got := NotePath(notesDir, tt.want); tt.want != "x" && got != tt.path {
+			t.Errorf("NotePath(%q) = %q, want %q", tt.want, got, tt.path)
+		}
+	}
+
+	if NotebookOf("work/standup") != "work" || NotebookOf("todo") != "" || BaseName("a/b/c") != "c" {
+		t.Error("NotebookOf/BaseName split names incorrectly")
+	}
+}
+
+func TestInNotebook(t *testing.T) {
+	tests := []struct {
+		name, notebook string
+		want           bool
+	}{
+		{"work/standup", "work", true},
+		{"work/team/retro", "work", true},
+		{"work/team/retro", "work/team/", true},
+		{"Work/standup", "work", true},
+		{"workshop/notes", "work", false},
+		{"work", "work", false},
+		{"todo", "", true},
+	}
+	for _, tt := range tests {
+		if got := InNotebook(tt.name, tt.notebook); got != tt.want {
+			t.Errorf("InNotebook(%q, %q) = %v, want %v", tt.name, tt.notebook, got, tt.want)
+		}
+	}
+}
+
+func TestValidateNotePath(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{"standup", false},
+		{"work/standup", false},
+		{"work/team/retro notes", false},
+		{"", true},
+		{"/standup", true},
+		{"work//standup", true},
+		{"work/", true},
+		{"../escape", true},
+		{"work/../escape", true},
+		{".hidden/note", true},
+		{"work\\standup", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateNotePath(tt.input); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateNotePath(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNotebookIndexing(t *testing.T) {
+	dir, cleanup := testDir(t)
+	defer cleanup()
+
+	origGoteDir := GoteDir
+	GoteDir = func() string { return dir }
+	defer func() { GoteDir = origGoteDir }()
+
+	notesDir := filepath.Join(dir, "notes")
+	for _, p := range []string{"work/standup", "personal/standup", "work/team/retro", "todo"} {
+		path := NotePath(notesDir, p)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte("content of "+p), 0644)
+	}
+	os.MkdirAll(filepath.Join(notesDir, "empty"), 0755)
+	os.MkdirAll(filepath.Join(notesDir, ".git"), 0755)
+	os.WriteFile(filepath.Join(notesDir, ".git", "ignored.md"), []byte("x"), 0644)
+
+	if err := IndexNotes(notesDir); err != nil {
+		t.Fatalf("IndexNotes failed: %v", err)
+	}
+	index, _ := LoadIndex()
+
+	t.Run("same file name in two notebooks", func(t *testing.T) {
+		if len(index) != 4 {
+			t.Fatalf("expected 4 notes, got %d: %v", len(index), index)
+		}
+		for _, key := range []string{"work/standup", "personal/standup", "work/team/retro", "todo"} {
+			meta, ok := index[key]
+			if !ok || meta.Title != key {
+				t.Errorf("missing or mistitled %q: %+v", key, meta)
+			}
+		}
+	})
+
+	t.Run("LookupNote by base name", func(t *testing.T) {
+		if key, _, ok := LookupNote(index, "retro"); !ok || key != "work/team/retro" {
+			t.Errorf("LookupNote(retro) = %q, %v", key, ok)
+		}
+		if _, _, ok := LookupNote(index, "standup"); ok {
+			t.Error("ambiguous base name should not resolve")
+		}
+	})
+
+	t.Run("ListNotebooks", func(t *testing.T) {
+		books, err := ListNotebooks(notesDir, index)
+		if err != nil {
+			t.Fatalf("ListNotebooks failed: %v", err)
+		}
+		want := map[string][2]int{"empty": {0, 0}, "personal": {1, 1}, "work": {1, 2}, "work/team": {1, 1}}
+		if len(books) != len(want) {
+			t.Fatalf("notebooks = %+v", books)
+		}
+		for _, b := range books {
+			if w := want[b.Name]; b.Notes != w[0] || b.Total != w[1] {
+				t.Errorf("%s = %d/%d, want %d/%d", b.Name, b.Notes, b.Total, w[0], w[1])
+			}
+		}
+	})
+
+	t.Run("trash and recover keep the notebook", func(t *testing.T) {
+		if err := TrashNote("work/standup", index["work/standup"]); err != nil {
+			t.Fatalf("TrashNote failed: %v", err)
+		}
+		trashed, _ := ListTrashedNotes()
+		if len(trashed) != 1 || trashed[0] != "work/standup" {
+			t.Errorf("trash = %v", trashed)
+		}
+		if err := RecoverNote("work/standup", notesDir); err != nil {
+			t.Fatalf("RecoverNote failed: %v", err)
+		}
+		if _, err := os.Stat(NotePath(notesDir, "work/standup")); err != nil {
+			t.Errorf("note not restored into its notebook: %v", err)
+		}
+		loaded, _ := LoadIndex()
+		if _, ok := loaded["work/standup"]; !ok {
+			t.Error("recovered note missing from index")
+		}
+	})
+}
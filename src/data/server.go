@@ -0,0 +1,9 @@
+package data
+
+import "path/filepath"
+
+// ServerAddrPath is where a running "gote serve" records its address, so
+// other gote commands can open notes on it
+func ServerAddrPath() string {
+	return filepath.Join(GoteDir(), "serve.addr")
+}
@@ -4,12 +4,70 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
 )
 
+// TagMeta is one tag in tags.json. Tags are hierarchical, "work/clients/acme"
+// being below "work/clients" and "work"; every level has an entry, even if
+// no note uses it directly.
 type TagMeta struct {
 	Tag   string   `json:"tag"`
-	Notes []string `json:"notes"`
-	Count int      `json:"count"`
+	Notes []string `json:"notes"` // notes tagged with exactly this tag
+	Count int      `json:"count"` // len(Notes)
+	Total int      `json:"total"` // notes tagged with this tag or any below it
+}
+
+// TagAncestors returns the tags above tag, nearest first:
+// "work/clients/acme" gives "work/clients", "work"
+func TagAncestors(tag string) []string {
+	var ancestors []string
+	for i := strings.LastIndex(tag, "/"); i > 0; i = strings.LastIndex(tag, "/") {
+		tag = tag[:i]
+		ancestors = append(ancestors, tag)
+	}
+	return ancestors
+}
+
+// TagMatches reports whether a note's tag satisfies filter: the tag itself
+// or, unless exact, any tag below it ("work" matches "work/clients/acme")
+func TagMatches(tag, filter string, exact bool) bool {
+	if tag == filter {
+		return true
+	}
+	return !exact && strings.HasPrefix(tag, filter+"/")
+}
+
+// inlineCodeRegex matches `code` spans, whose #words aren't tags
+var inlineCodeRegex = regexp.MustCompile("`[^`\n]*`")
+
+// InlineTags returns the #tags written in text, as Obsidian and Bear use
+// them, skipping code. "#2024" alone is a number, not a tag.
+func InlineTags(text string) []string {
+	var tags []string
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, m := range taskTagRegex.FindAllStringSubmatch(inlineCodeRegex.ReplaceAllString(line, ""), -1) {
+			tag := NormalizeTag(m[1])
+			if tag == "" || !strings.ContainsFunc(tag, func(r rune) bool { return !unicode.IsDigit(r) }) {
+				continue
+			}
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
 }
 
 func TagsPath() string {
@@ -17,17 +75,34 @@ func TagsPath() string {
 }
 
 func UpdateTagsIndex(notes map[string]NoteMeta) error {
+	return AtomicWriteJSON(TagsPath(), BuildTagsIndex(notes))
+}
+
+// BuildTagsIndex returns the contents of tags.json for the given index,
+// rolling each note up into the totals of its tags' ancestors
+func BuildTagsIndex(notes map[string]NoteMeta) map[string]TagMeta {
 	tagMap := make(map[string]TagMeta)
 	for _, note := range notes {
+		counted := make(map[string]bool) // a note counts once per level
 		for _, tag := range note.Tags {
 			tm := tagMap[tag]
 			tm.Tag = tag
 			tm.Notes = append(tm.Notes, note.FilePath)
 			tm.Count++
 			tagMap[tag] = tm
+			for _, level := range append([]string{tag}, TagAncestors(tag)...) {
+				if counted[level] {
+					continue
+				}
+				counted[level] = true
+				tm := tagMap[level]
+				tm.Tag = level
+				tm.Total++
+				tagMap[level] = tm
+			}
 		}
 	}
-	return AtomicWriteJSON(TagsPath(), tagMap)
+	return tagMap
 }
 
 func LoadTags() (map[string]TagMeta, error) {
@@ -49,3 +124,140 @@ func LoadTags() (map[string]TagMeta, error) {
 func FormatTagsFile() error {
 	return FormatJSONFile(TagsPath())
 }
+
+// TagMapper gives a tag's new name, or false if the tag should be removed
+type TagMapper func(tag string) (string, bool)
+
+// RewriteTags applies mapTag to the tags a note declares in its .tag line and
+// front matter, leaving the rest of text alone. Decoration around a tag such as
+// "#" or "[]" is kept, tags that become duplicates are dropped, and a .tag line
+// left with no tags is removed.
+func RewriteTags(text string, mapTag TagMapper) string {
+	lines := strings.SplitAfter(text, "\n")
+	bodyStart := 0
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == "---" {
+		for i := 1; i < len(lines); i++ {
+			if trimmed := strings.TrimSpace(lines[i]); trimmed == "---" || trimmed == "..." {
+				rewriteFrontMatterTags(lines[1:i], mapTag)
+				bodyStart = i + 1
+				break
+			}
+		}
+	}
+	if bodyStart < len(lines) && strings.HasPrefix(lines[bodyStart], ".") {
+		line := lines[bodyStart]
+		content := strings.TrimRight(line, "\r\n")
+		eol := line[len(content):]
+		segments := strings.Split(content, ".")
+		kept := segments[:1]
+		seen := make(map[string]bool)
+		for _, segment := range segments[1:] {
+			if segment, ok := rewriteTagToken(segment, mapTag, seen); ok {
+				kept = append(kept, segment)
+			}
+		}
+		if updated := strings.Join(kept, "."); updated != content {
+			lines[bodyStart] = updated + eol
+			if len(ParseTags(updated)) == 0 {
+				lines[bodyStart] = ""
+			}
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// rewriteFrontMatterTags rewrites the "tags:" entry of a front matter block in
+// place, whether it is a scalar, an [inline, list] or "- item" lines. Dropped
+// list items lose their line.
+func rewriteFrontMatterTags(block []string, mapTag TagMapper) {
+	for i := 0; i < len(block); i++ {
+		line := block[i]
+		content := strings.TrimRight(line, "\r\n")
+		eol := line[len(content):]
+		if content != strings.TrimLeft(content, " \t") {
+			continue
+		}
+		key, value, ok := strings.Cut(content, ":")
+		if !ok {
+			continue
+		}
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "tags" && key != "tag" {
+			continue
+		}
+		seen := make(map[string]bool)
+		prefix := content[:len(content)-len(value)]
+		trimmed := strings.TrimSpace(value)
+		switch {
+		case trimmed == "":
+			for i+1 < len(block) {
+				item := strings.TrimRight(block[i+1], "\r\n")
+				itemTrimmed := strings.TrimSpace(item)
+				if itemTrimmed == "" || strings.HasPrefix(itemTrimmed, "#") {
+					i++
+					continue
+				}
+				if !strings.HasPrefix(itemTrimmed, "- ") && itemTrimmed != "-" {
+					break
+				}
+				i++
+				dash := strings.Index(item, "-") + 1
+				if rewritten, ok := rewriteTagScalar(item[dash:], mapTag, seen); ok {
+					block[i] = item[:dash] + rewritten + block[i][len(item):]
+				} else {
+					block[i] = ""
+				}
+			}
+		case strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]"):
+			open, end := strings.Index(value, "["), strings.LastIndex(value, "]")
+			var items []string
+			for _, item := range strings.Split(value[open+1:end], ",") {
+				if item, ok := rewriteTagScalar(item, mapTag, seen); ok {
+					items = append(items, strings.TrimSpace(item))
+				}
+			}
+			block[i] = prefix + value[:open+1] + strings.Join(items, ", ") + value[end:] + eol
+		default:
+			if rewritten, ok := rewriteTagScalar(value, mapTag, seen); ok {
+				block[i] = prefix + rewritten + eol
+			} else {
+				block[i] = ""
+			}
+		}
+	}
+}
+
+// rewriteTagScalar rewrites a YAML tag value, keeping any trailing comment
+func rewriteTagScalar(value string, mapTag TagMapper, seen map[string]bool) (string, bool) {
+	comment := ""
+	if trimmed := strings.TrimSpace(value); trimmed == "" || (trimmed[0] != '"' && trimmed[0] != '\'') {
+		if i := strings.Index(value, " #"); i >= 0 {
+			value, comment = value[:i], value[i:]
+		}
+	}
+	value, ok := rewriteTagToken(value, mapTag, seen)
+	return value + comment, ok
+}
+
+// rewriteTagToken maps the tag in token, keeping the characters around it. ok
+// is false if the tag is dropped, or was already seen; tokens without a tag are
+// kept as they are.
+func rewriteTagToken(token string, mapTag TagMapper, seen map[string]bool) (string, bool) {
+	start := strings.IndexFunc(token, func(r rune) bool { return !strings.ContainsRune(" \t.#[|\"'", r) })
+	if start < 0 {
+		return token, true
+	}
+	end := strings.LastIndexFunc(token, func(r rune) bool { return !strings.ContainsRune(" \t]|\"'", r) }) + 1
+	tag := NormalizeTag(strings.NewReplacer("#", "", "[", "", "]", "", "|", "").Replace(token[start:end]))
+	if tag == "" {
+		return token, true
+	}
+	newTag, keep := mapTag(tag)
+	if !keep || seen[newTag] {
+		return "", false
+	}
+	seen[newTag] = true
+	if newTag == tag {
+		return token, true
+	}
+	return token[:start] + newTag + token[end:], true
+}
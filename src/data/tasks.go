@@ -0,0 +1,77 @@
+package data
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Task is a Markdown checkbox item ("- [ ] call Ann #work due:2026-03-01")
+type Task struct {
+	Line int      `json:"line"` // 1-based line in the note file
+	Text string   `json:"text"` // text after the checkbox
+	Done bool     `json:"done"`
+	Tags []string `json:"tags,omitempty"` // #tags on the line
+	Due  string   `json:"due,omitempty"`  // YYYY-MM-DD
+}
+
+// taskRegex matches "- [ ] text", "* [x] text" and numbered "1. [ ] text"
+var taskRegex = regexp.MustCompile(`^(\s*(?:[-*+]|\d+[.)])\s+\[)([ xX])(\]\s+)(.*)$`)
+
+// taskTagRegex matches #tag on a task line, but not a heading or "C#"
+var taskTagRegex = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]+)`)
+
+// taskDueRegex matches due:2026-03-01, @due(2026-03-01) and 📅 2026-03-01
+var taskDueRegex = regexp.MustCompile(`(?:\bdue:|@due\(|📅\s*)(\d{4}-\d{2}-\d{2})`)
+
+// ParseTasks returns the checkbox items in text, skipping fenced code blocks
+func ParseTasks(text string) []Task {
+	var tasks []Task
+	inFence := false
+	for i, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if task, ok := ParseTaskLine(line); ok {
+			task.Line = i + 1
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// ParseTaskLine reads a single checkbox line. Line is left unset.
+func ParseTaskLine(line string) (Task, bool) {
+	m := taskRegex.FindStringSubmatch(strings.TrimRight(line, "\r"))
+	if m == nil {
+		return Task{}, false
+	}
+	task := Task{Text: strings.TrimSpace(m[4]), Done: m[2] != " "}
+	for _, tm := range taskTagRegex.FindAllStringSubmatch(task.Text, -1) {
+		if tag := NormalizeTag(tm[1]); tag != "" {
+			task.Tags = append(task.Tags, tag)
+		}
+	}
+	if dm := taskDueRegex.FindStringSubmatch(task.Text); dm != nil {
+		task.Due = dm[1]
+	}
+	return task, true
+}
+
+// ToggleTaskLine flips a checkbox line between "[ ]" and "[x]", leaving the
+// rest of the line as it was. ok is false if line isn't a task.
+func ToggleTaskLine(line string) (string, bool) {
+	m := taskRegex.FindStringSubmatchIndex(strings.TrimRight(line, "\r"))
+	if m == nil {
+		return line, false
+	}
+	mark := "x"
+	if line[m[4]:m[5]] != " " {
+		mark = " "
+	}
+	return line[:m[4]] + mark + line[m[5]:], true
+}
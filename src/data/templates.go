@@ -5,9 +5,133 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 )
 
+// TemplateSettings are declared in a template's front matter and control how
+// notes made from it are named and set up. The block is stripped from the note.
+//
+//	---
+//	name: {{date "060102"}} standup
+//	tags: .meeting.work
+//	timestamp: none
+//	pin: true
+//	---
+type TemplateSettings struct {
+	Name      string   // note name pattern, rendered like the template body
+	Tags      []string // merged into the note's tag line
+	Timestamp string   // "none", "date" or "datetime"; "" uses the config
+	Pin       bool     // pin the note once created
+
+	NameLine int // line of the name setting, for errors
+	BodyLine int // file line the body's first line corresponds to, for errors
+}
+
+// Empty reports whether the template declares no settings
+func (s TemplateSettings) Empty() bool {
+	return s.Name == "" && len(s.Tags) == 0 && s.Timestamp == "" && !s.Pin
+}
+
+// ParseTemplateSettings splits a template's settings from its body. A
+// template without a leading "---" line has no settings. Other keys in the
+// block are note front matter (see FrontMatter) and stay in the body, in a
+// block of their own. Errors name the line.
+func ParseTemplateSettings(content string) (TemplateSettings, string, error) {
+	var settings TemplateSettings
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		settings.BodyLine = 1
+		return settings, content, nil
+	}
+	var kept []string
+	keeping := false // whether indented and "- item" lines belong to a kept key
+	tagList := false // whether "- item" lines are tags
+	for i := 1; i < len(lines); i++ {
+		raw := lines[i]
+		line := strings.TrimSpace(raw)
+		if line == "---" {
+			body := strings.Join(lines[i+1:], "")
+			settings.BodyLine = i + 2
+			if len(kept) > 0 {
+				body = "---\n" + strings.Join(kept, "") + "---\n" + body
+				settings.BodyLine -= len(kept) + 2
+			}
+			return settings, body, nil
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "-") || raw != strings.TrimLeft(raw, " \t") {
+			if tagList {
+				settings.Tags = append(settings.Tags, parseTagList(strings.TrimPrefix(line, "-"))...)
+			} else if keeping {
+				kept = append(kept, raw)
+			} else {
+				return settings, "", fmt.Errorf("line %d: unexpected %q", i+1, line)
+			}
+			continue
+		}
+		keeping, tagList = false, false
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return settings, "", fmt.Errorf("line %d: expected key: value, got %q", i+1, line)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "name":
+			settings.Name = unquote(value)
+			settings.NameLine = i + 1
+		case "tags":
+			settings.Tags = parseTagList(value)
+			tagList = value == ""
+		case "timestamp":
+			value = strings.ToLower(unquote(value))
+			if value != "none" && value != "date" && value != "datetime" {
+				return settings, "", fmt.Errorf("line %d: timestamp must be none, date or datetime, got %q", i+1, value)
+			}
+			settings.Timestamp = value
+		case "pin":
+			pin, err := strconv.ParseBool(value)
+			if err != nil {
+				return settings, "", fmt.Errorf("line %d: pin must be true or false, got %q", i+1, value)
+			}
+			settings.Pin = pin
+		default:
+			if !strings.HasSuffix(raw, "\n") {
+				raw += "\n"
+			}
+			kept = append(kept, raw)
+			keeping = true
+		}
+	}
+	return settings, "", fmt.Errorf("line 1: front matter is not closed with ---")
+}
+
+// parseTagList accepts tags as a tag line (.a.b), a list ([a, b]) or
+// separated by commas or spaces
+func parseTagList(value string) []string {
+	value = strings.Trim(unquote(value), "[]")
+	if strings.HasPrefix(value, ".") {
+		return ParseTags(value)
+	}
+	var tags []string
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if tag = NormalizeTag(strings.Trim(unquote(tag), ".#")); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// unquote strips one pair of matching quotes
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
 // TemplatesDir returns the path to the templates directory
 func TemplatesDir() string {
 	return filepath.Join(GoteDir(), "templates")
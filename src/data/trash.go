@@ -1,126 +1,407 @@
 package data
 
 import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
+// TrashEntry is one deleted note. Each deletion gets its own id and file, so
+// deleting two notes with the same name keeps both.
+type TrashEntry struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`             // note name when deleted
+	File      string    `json:"file"`             // file inside the trash directory
+	DeletedAt time.Time `json:"deletedAt"`        // when it was trashed
+	Meta      NoteMeta  `json:"meta"`             // index entry at deletion, incl. original path
+	Pinned    bool      `json:"pinned,omitempty"` // whether it was pinned
+
+	// Adopted is set on files found in the trash with no manifest entry,
+	// e.g. from versions of gote before the manifest. They have no metadata.
+	Adopted bool `json:"-"`
+}
+
 func TrashPath() string {
 	return filepath.Join(GoteDir(), "trash")
 }
 
-func TrashNote(noteName string, noteMeta NoteMeta) error {
-	notePath := noteMeta.FilePath
-	trashDir := TrashPath()
-	if err := os.MkdirAll(trashDir, 0755); err != nil {
-		return fmt.Errorf("error creating trash directory: %w", err)
+// TrashManifestPath returns the file recording what is in the trash
+func TrashManifestPath() string {
+	return filepath.Join(TrashPath(), "manifest.json")
+}
+
+// LoadTrash returns the trash manifest keyed by id. Entries whose file is
+// gone are dropped, and note files with no entry (trashed by older versions
+// of gote, which stored trash/<name>.md) are adopted with a stable id. Their
+// deletion time is unknown, so they count as deleted now and are written to
+// the manifest, which starts their retention from the first time they're seen.
+func LoadTrash() (map[string]TrashEntry, error) {
+	entries, err := ReadTrash()
+	if err != nil {
+		return nil, err
 	}
-	trashFile := filepath.Join(trashDir, filepath.Base(notePath))
-	if err := os.Rename(notePath, trashFile); err != nil {
-		return fmt.Errorf("error moving note to trash: %w", err)
+	for _, e := range entries {
+		if e.Adopted {
+			err := WithTrashLock(func(locked map[string]TrashEntry) error {
+				entries = locked
+				return nil
+			})
+			return entries, err
+		}
 	}
+	return entries, nil
+}
 
-	// Remove from FTS index
-	if err := RemoveDocFTS(noteName); err != nil {
-		return fmt.Errorf("removing from FTS index: %w", err)
+// ReadTrash is LoadTrash without recording adopted files in the manifest
+func ReadTrash() (map[string]TrashEntry, error) {
+	entries := make(map[string]TrashEntry)
+	raw, err := os.ReadFile(TrashManifestPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading trash manifest: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return nil, fmt.Errorf("parsing trash manifest: %w", err)
+		}
 	}
 
-	// Update index with lock, nesting pins lock inside
-	return WithIndexLock(func(index map[string]NoteMeta) error {
-		delete(index, noteName)
+	known := make(map[string]bool, len(entries))
+	for id, e := range entries {
+		if _, err := os.Stat(filepath.Join(TrashPath(), filepath.FromSlash(e.File))); err != nil {
+			delete(entries, id)
+			continue
+		}
+		known[e.File] = true
+	}
 
-		return WithPinsLock(func(pins map[string]EmptyStruct) error {
-			delete(pins, noteName)
+	err = filepath.Walk(TrashPath(), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+		rel, _ := filepath.Rel(TrashPath(), path)
+		rel = filepath.ToSlash(rel)
+		if known[rel] {
 			return nil
+		}
+		sum := sha1.Sum([]byte(rel))
+		id := "legacy-" + hex.EncodeToString(sum[:6])
+		entries[id] = TrashEntry{
+			ID:        id,
+			Name:      NoteName(TrashPath(), path),
+			File:      rel,
+			DeletedAt: time.Now(),
+			Adopted:   true,
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading trash: %w", err)
+	}
+	return entries, nil
+}
+
+// SaveTrash writes the trash manifest
+func SaveTrash(entries map[string]TrashEntry) error {
+	return AtomicWriteJSON(TrashManifestPath(), entries)
+}
+
+// WithTrashLock executes fn with exclusive access to the trash. Lock order
+// is trash, then index, then pins.
+func WithTrashLock(fn func(map[string]TrashEntry) error) error {
+	if err := os.MkdirAll(TrashPath(), 0755); err != nil {
+		return fmt.Errorf("error creating trash directory: %w", err)
+	}
+	lock, err := LockFile(TrashManifestPath())
+	if err != nil {
+		return fmt.Errorf("acquiring trash lock: %w", err)
+	}
+	defer lock.Unlock()
+
+	entries, err := ReadTrash()
+	if err != nil {
+		return err
+	}
+	if err := fn(entries); err != nil {
+		return err
+	}
+	return SaveTrash(entries)
+}
+
+// ListTrash returns the trashed notes, most recently deleted first
+func ListTrash() ([]TrashEntry, error) {
+	entries, err := LoadTrash()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]TrashEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].DeletedAt.Equal(list[j].DeletedAt) {
+			return list[i].DeletedAt.After(list[j].DeletedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+// FindTrashed returns the trashed notes called name, most recent first.
+// Matching is case-insensitive, and a name without a notebook also matches
+// notes trashed from one.
+func FindTrashed(name string) ([]TrashEntry, error) {
+	list, err := ListTrash()
+	if err != nil {
+		return nil, err
+	}
+	var exact, base []TrashEntry
+	for _, e := range list {
+		switch {
+		case strings.EqualFold(e.Name, name):
+			exact = append(exact, e)
+		case !strings.Contains(name, "/") && strings.EqualFold(BaseName(e.Name), name):
+			base = append(base, e)
+		}
+	}
+	if len(exact) > 0 {
+		return exact, nil
+	}
+	return base, nil
+}
+
+// newTrashID returns a unique, time-ordered id for a deletion
+func newTrashID(entries map[string]TrashEntry) string {
+	for {
+		var b [3]byte
+		rand.Read(b[:])
+		id := time.Now().Format("060102150405") + "-" + hex.EncodeToString(b[:])
+		if _, taken := entries[id]; !taken {
+			return id
+		}
+	}
+}
+
+// TrashNote moves a note into the trash, recording its metadata and pin
+// state so RecoverTrashEntry can put everything back
+func TrashNote(noteName string, noteMeta NoteMeta) error {
+	return WithTrashLock(func(entries map[string]TrashEntry) error {
+		id := newTrashID(entries)
+		entry := TrashEntry{
+			ID:        id,
+			Name:      noteName,
+			File:      id + ".md",
+			DeletedAt: time.Now(),
+			Meta:      noteMeta,
+		}
+		trashFile := filepath.Join(TrashPath(), entry.File)
+		if err := os.Rename(noteMeta.FilePath, trashFile); err != nil {
+			return fmt.Errorf("error moving note to trash: %w", err)
+		}
+
+		// Update index, FTS and pins with the index lock held, nesting pins inside
+		err := WithIndexLock(func(index map[string]NoteMeta) error {
+			if meta, ok := index[noteName]; ok {
+				entry.Meta = meta
+			}
+			delete(index, noteName)
+			if err := RemoveDocFTS(noteName); err != nil {
+				return fmt.Errorf("removing from FTS index: %w", err)
+			}
+			return WithPinsLock(func(pins map[string]EmptyStruct) error {
+				_, entry.Pinned = pins[noteName]
+				delete(pins, noteName)
+				return nil
+			})
 		})
+		if err != nil {
+			// Put the file back so the note isn't lost from both places
+			os.Rename(trashFile, noteMeta.FilePath)
+			return err
+		}
+		entries[id] = entry
+		return nil
 	})
 }
 
+// ListTrashedNotes returns the names of all trashed notes
 func ListTrashedNotes() ([]string, error) {
-	files, err := os.ReadDir(TrashPath())
+	list, err := ListTrash()
 	if err != nil {
 		return nil, err
 	}
-	noteNames := []string{}
-	for _, f := range files {
-		if !f.IsDir() {
-			noteNames = append(noteNames, strings.TrimSuffix(f.Name(), ".md"))
-		}
+	names := make([]string, 0, len(list))
+	for _, e := range list {
+		names = append(names, e.Name)
 	}
-	return noteNames, nil
+	return names, nil
 }
 
+// RecoverNote restores the trashed note called noteName. It fails if several
+// trashed notes share the name; use RecoverTrashEntry to pick one.
 func RecoverNote(noteName, notesDir string) error {
-	trashDir := TrashPath()
-	if notesDir == "" {
-		return fmt.Errorf("could not determine notes directory")
+	matches, err := FindTrashed(noteName)
+	if err != nil {
+		return err
 	}
-
-	trashedFile := filepath.Join(trashDir, noteName+".md")
-	if _, err := os.Stat(trashedFile); os.IsNotExist(err) {
+	switch len(matches) {
+	case 0:
 		return fmt.Errorf("note not found in trash: %s", noteName)
+	case 1:
+		_, err := RecoverTrashEntry(matches[0].ID, notesDir)
+		return err
+	default:
+		return fmt.Errorf("%d trashed notes are named %s", len(matches), noteName)
 	}
+}
 
-	recoveredFile := filepath.Join(notesDir, noteName+".md")
-	if err := os.Rename(trashedFile, recoveredFile); err != nil {
-		return fmt.Errorf("error restoring note: %w", err)
+// RecoverTrashEntry restores a trashed note to where it was, with its
+// created and last-visited times and pin. It refuses to overwrite a note
+// that now has the same name. Returns the restored note's name.
+func RecoverTrashEntry(id, notesDir string) (string, error) {
+	if notesDir == "" {
+		return "", fmt.Errorf("could not determine notes directory")
 	}
-
-	return WithIndexLock(func(index map[string]NoteMeta) error {
-		info, err := os.Stat(recoveredFile)
-		if err != nil {
-			return fmt.Errorf("error stating restored note: %w", err)
+	var name string
+	err := WithTrashLock(func(entries map[string]TrashEntry) error {
+		entry, ok := entries[id]
+		if !ok {
+			return fmt.Errorf("no such trash entry: %s", id)
 		}
-		meta, err := BuildNoteMeta(recoveredFile, info)
-		if err != nil {
-			return fmt.Errorf("error indexing restored note: %w", err)
+		name = entry.Name
+		recoveredFile := NotePath(notesDir, entry.Name)
+		if _, err := os.Stat(recoveredFile); err == nil {
+			return fmt.Errorf("a note named %s already exists; rename it first", entry.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(recoveredFile), 0755); err != nil {
+			return fmt.Errorf("error creating notebook: %w", err)
+		}
+		trashedFile := filepath.Join(TrashPath(), filepath.FromSlash(entry.File))
+		if err := os.Rename(trashedFile, recoveredFile); err != nil {
+			return fmt.Errorf("error restoring note: %w", err)
 		}
-		index[noteName] = meta
+		removeEmptyDirs(filepath.Dir(trashedFile), TrashPath())
+		delete(entries, id)
+
+		return WithIndexLock(func(index map[string]NoteMeta) error {
+			info, err := os.Stat(recoveredFile)
+			if err != nil {
+				return fmt.Errorf("error stating restored note: %w", err)
+			}
+			meta, err := buildNoteMeta(entry.Name, recoveredFile, info)
+			if err != nil {
+				return fmt.Errorf("error indexing restored note: %w", err)
+			}
+			if entry.Meta.Created != "" {
+				meta.Created = entry.Meta.Created
+			}
+			meta.LastVisited = entry.Meta.LastVisited
+			index[entry.Name] = meta
+
+			content, err := os.ReadFile(recoveredFile)
+			if err != nil {
+				return fmt.Errorf("reading note for FTS: %w", err)
+			}
+			if err := IndexDocFTS(entry.Name, recoveredFile, string(content)); err != nil {
+				return fmt.Errorf("updating FTS index: %w", err)
+			}
+			if !entry.Pinned {
+				return nil
+			}
+			return WithPinsLock(func(pins map[string]EmptyStruct) error {
+				pins[entry.Name] = EmptyStruct{}
+				return nil
+			})
+		})
+	})
+	return name, err
+}
+
+// RenameTrashEntry changes the name a trashed note will be recovered as
+func RenameTrashEntry(id, newName string) error {
+	if err := ValidateNotePath(newName); err != nil {
+		return err
+	}
+	return WithTrashLock(func(entries map[string]TrashEntry) error {
+		entry, ok := entries[id]
+		if !ok {
+			return fmt.Errorf("no such trash entry: %s", id)
+		}
+		entry.Name = newName
+		entries[id] = entry
 		return nil
 	})
 }
 
 func SearchTrash(query string) ([]string, error) {
-	files, err := os.ReadDir(TrashPath())
+	list, err := ListTrash()
 	if err != nil {
 		return nil, err
 	}
 	var results []string
 	queryLower := strings.ToLower(query)
-	for _, f := range files {
-		if f.IsDir() || !strings.HasSuffix(f.Name(), ".md") {
-			continue
-		}
-		title := strings.TrimSuffix(f.Name(), ".md")
-		if strings.Contains(strings.ToLower(title), queryLower) {
-			results = append(results, title)
+	for _, e := range list {
+		if strings.Contains(strings.ToLower(e.Name), queryLower) {
+			results = append(results, e.Name)
 		}
 	}
 	return results, nil
 }
 
-func EmptyTrash() (int, error) {
-	trashDir := TrashPath()
-	files, err := os.ReadDir(trashDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, nil
+// purgeTrash permanently deletes the trashed notes drop selects
+func purgeTrash(drop func(TrashEntry) bool) (int, error) {
+	count := 0
+	err := WithTrashLock(func(entries map[string]TrashEntry) error {
+		for id, e := range entries {
+			if !drop(e) {
+				continue
+			}
+			path := filepath.Join(TrashPath(), filepath.FromSlash(e.File))
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("error removing %s: %w", e.Name, err)
+			}
+			removeEmptyDirs(filepath.Dir(path), TrashPath())
+			delete(entries, id)
+			count++
 		}
-		return 0, err
+		return nil
+	})
+	return count, err
+}
+
+func EmptyTrash() (int, error) {
+	return purgeTrash(func(TrashEntry) bool { return true })
+}
+
+// PurgeExpiredTrash permanently deletes notes trashed more than maxAge ago.
+// A zero maxAge keeps everything.
+func PurgeExpiredTrash(maxAge time.Duration) (int, error) {
+	if maxAge <= 0 {
+		return 0, nil
+	}
+	if _, err := os.Stat(TrashPath()); os.IsNotExist(err) {
+		return 0, nil
 	}
+	cutoff := time.Now().Add(-maxAge)
+	return purgeTrash(func(e TrashEntry) bool { return e.DeletedAt.Before(cutoff) })
+}
 
-	count := 0
-	for _, f := range files {
-		if f.IsDir() {
-			continue
-		}
-		filePath := filepath.Join(trashDir, f.Name())
-		if err := os.Remove(filePath); err != nil {
-			return count, fmt.Errorf("error removing %s: %w", f.Name(), err)
+// removeEmptyDirs removes dir and its parents up to (not including) root
+// while they are empty
+func removeEmptyDirs(dir, root string) {
+	for dir != root && strings.HasPrefix(dir, root) {
+		if os.Remove(dir) != nil {
+			return
 		}
-		count++
+		dir = filepath.Dir(dir)
 	}
-	return count, nil
 }
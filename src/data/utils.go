@@ -19,11 +19,17 @@ func PrettyPrintJSON(v interface{}) {
 }
 
 func OpenFileInEditor(filePath, editor string) error {
+	return OpenFileInEditorAtLine(filePath, editor, 0)
+}
+
+// OpenFileInEditorAtLine opens a file with the cursor on the given 1-based line.
+// Editors without a known line syntax just open the file.
+func OpenFileInEditorAtLine(filePath, editor string, line int) error {
 	if editor == "" {
 		return fmt.Errorf("no editor specified in config")
 	}
 
-	cmd := exec.Command(editor, filePath)
+	cmd := exec.Command(editor, editorArgs(editor, filePath, line)...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -33,6 +39,23 @@ func OpenFileInEditor(filePath, editor string) error {
 	return nil
 }
 
+// editorArgs builds the command-line arguments to open filePath at line
+func editorArgs(editor, filePath string, line int) []string {
+	if line <= 0 {
+		return []string{filePath}
+	}
+	name := strings.TrimSuffix(filepath.Base(editor), ".exe")
+	switch name {
+	case "vim", "nvim", "vi", "gvim", "mvim", "nano", "emacs", "emacsclient", "micro", "kak", "ne", "joe", "mg", "gedit":
+		return []string{fmt.Sprintf("+%d", line), filePath}
+	case "code", "codium", "code-insiders", "cursor":
+		return []string{"--goto", fmt.Sprintf("%s:%d", filePath, line)}
+	case "subl", "hx", "helix", "zed":
+		return []string{fmt.Sprintf("%s:%d", filePath, line)}
+	}
+	return []string{filePath}
+}
+
 // ValidateNoteName checks if a note name is safe to use as a filename.
 func ValidateNoteName(name string) error {
 	if name == "" {
@@ -0,0 +1,155 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Watcher reports paths under the notes directory that may have changed.
+// Events are raw and undebounced; a path can be a file or a directory, and
+// may no longer exist by the time it is read.
+type Watcher interface {
+	Events() <-chan string
+	Errors() <-chan error
+	Backend() string // "inotify" or "polling"
+	Close() error
+}
+
+// DefaultPollInterval is how often the polling watcher rescans
+const DefaultPollInterval = 2 * time.Second
+
+// NewWatcher watches root recursively with the platform's native file
+// notifications, falling back to polling every interval when they are
+// unavailable or forcePoll is set.
+func NewWatcher(root string, forcePoll bool, interval time.Duration) (Watcher, error) {
+	if !forcePoll {
+		if w, err := newNativeWatcher(root); err == nil {
+			return w, nil
+		}
+	}
+	return newPollWatcher(root, interval)
+}
+
+// IsNoteFile reports whether path is a note gote should index: a .md file
+// under root that is not hidden and not inside a hidden directory
+func IsNoteFile(root, path string) bool {
+	if filepath.Ext(path) != ".md" {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(part, ".") || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// ScanNotes returns every note file under root with its file info
+func ScanNotes(root string) (map[string]os.FileInfo, error) {
+	files := make(map[string]os.FileInfo)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // removed mid-walk
+			}
+			return err
+		}
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Mode().IsRegular() && IsNoteFile(root, path) {
+			files[path] = info
+		}
+		return nil
+	})
+	return files, err
+}
+
+// pollWatcher rescans the tree on a timer and reports files whose size or
+// modification time changed, appeared or disappeared
+type pollWatcher struct {
+	root     string
+	interval time.Duration
+	events   chan string
+	errors   chan error
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newPollWatcher(root string, interval time.Duration) (*pollWatcher, error) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	initial, err := ScanNotes(root)
+	if err != nil {
+		return nil, err
+	}
+	w := &pollWatcher{
+		root:     root,
+		interval: interval,
+		events:   make(chan string, 64),
+		errors:   make(chan error, 1),
+		done:     make(chan struct{}),
+	}
+	go w.run(initial)
+	return w, nil
+}
+
+func (w *pollWatcher) run(prev map[string]os.FileInfo) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		cur, err := ScanNotes(w.root)
+		if err != nil {
+			select {
+			case w.errors <- err:
+			default:
+			}
+			continue
+		}
+		for path, info := range cur {
+			old, ok := prev[path]
+			if !ok || old.Size() != info.Size() || !old.ModTime().Equal(info.ModTime()) {
+				w.send(path)
+			}
+		}
+		for path := range prev {
+			if _, ok := cur[path]; !ok {
+				w.send(path)
+			}
+		}
+		prev = cur
+	}
+}
+
+func (w *pollWatcher) send(path string) {
+	select {
+	case w.events <- path:
+	case <-w.done:
+	}
+}
+
+func (w *pollWatcher) Events() <-chan string { return w.events }
+func (w *pollWatcher) Errors() <-chan error  { return w.errors }
+func (w *pollWatcher) Backend() string       { return "polling" }
+
+func (w *pollWatcher) Close() error {
+	w.once.Do(func() { close(w.done) })
+	return nil
+}
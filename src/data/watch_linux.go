@@ -0,0 +1,164 @@
+//go:build linux
+
+package data
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_DELETE_SELF | syscall.IN_ONLYDIR
+
+// inotifyWatcher watches every non-hidden directory under root. inotify is
+// not recursive, so new directories are added as they appear.
+type inotifyWatcher struct {
+	root   string
+	file   *os.File
+	fd     int
+	events chan string
+	errors chan error
+	done   chan struct{}
+	once   sync.Once
+
+	mu   sync.Mutex
+	dirs map[int]string // watch descriptor -> directory
+}
+
+func newNativeWatcher(root string) (Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("initializing inotify: %w", err)
+	}
+	w := &inotifyWatcher{
+		root: root,
+		// A non-blocking fd goes through the runtime poller, so Close
+		// unblocks the pending Read
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		fd:     fd,
+		events: make(chan string, 64),
+		errors: make(chan error, 1),
+		done:   make(chan struct{}),
+		dirs:   make(map[int]string),
+	}
+	if err := w.addTree(root); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+// addTree watches dir and every non-hidden directory below it
+func (w *inotifyWatcher) addTree(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != w.root && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err != nil {
+			if errors.Is(err, syscall.ENOENT) {
+				return nil
+			}
+			return fmt.Errorf("watching %s: %w", path, err)
+		}
+		w.mu.Lock()
+		w.dirs[wd] = path
+		w.mu.Unlock()
+		return nil
+	})
+}
+
+func (w *inotifyWatcher) run() {
+	defer close(w.events)
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			select {
+			case <-w.done:
+			default:
+				w.sendError(fmt.Errorf("reading inotify events: %w", err))
+			}
+			return
+		}
+
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameBytes := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+			name := string(bytes.TrimRight(nameBytes, "\x00"))
+			off += syscall.SizeofInotifyEvent + int(ev.Len)
+
+			if ev.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				// Events were dropped; have the caller rescan everything
+				w.send(w.root)
+				continue
+			}
+
+			w.mu.Lock()
+			dir, ok := w.dirs[int(ev.Wd)]
+			if ev.Mask&syscall.IN_IGNORED != 0 {
+				delete(w.dirs, int(ev.Wd))
+			}
+			w.mu.Unlock()
+			if !ok || name == "" {
+				continue
+			}
+
+			path := filepath.Join(dir, name)
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+			if ev.Mask&syscall.IN_ISDIR != 0 && ev.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				if err := w.addTree(path); err != nil {
+					w.sendError(err)
+				}
+			}
+			w.send(path)
+		}
+	}
+}
+
+func (w *inotifyWatcher) send(path string) {
+	select {
+	case w.events <- path:
+	case <-w.done:
+	}
+}
+
+func (w *inotifyWatcher) sendError(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+func (w *inotifyWatcher) Events() <-chan string { return w.events }
+func (w *inotifyWatcher) Errors() <-chan error  { return w.errors }
+func (w *inotifyWatcher) Backend() string       { return "inotify" }
+
+func (w *inotifyWatcher) Close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.file.Close()
+	})
+	return err
+}
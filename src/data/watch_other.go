@@ -0,0 +1,10 @@
+//go:build !linux
+
+package data
+
+import "errors"
+
+// newNativeWatcher is only implemented on Linux; elsewhere gote watch polls
+func newNativeWatcher(root string) (Watcher, error) {
+	return nil, errors.New("native file notifications not supported on this platform")
+}
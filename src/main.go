@@ -10,7 +10,13 @@ import (
 const Version = "0.2.0"
 
 func main() {
-	args := os.Args
+	// --json and --format may appear anywhere; what's left is the command
+	flags, err := cli.ExtractOutputFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(2)
+	}
+	args := append(os.Args[:1], flags...)
 
 	if len(args) == 1 {
 		cli.QuickCommand()
@@ -35,6 +41,12 @@ func main() {
 		}
 	case "qs":
 		cli.QuickSaveCommand(rest)
+	case "new":
+		cli.NoteCommand(rest)
+	case "append", "app":
+		cli.AppendCommand(rest, false)
+	case "prepend", "pre":
+		cli.AppendCommand(rest, true)
 
 	// Last opened note
 	case "-":
@@ -71,6 +83,10 @@ func main() {
 	// Index management
 	case "index", "idx":
 		cli.IndexCommand(rest)
+	case "watch":
+		cli.WatchCommand(rest)
+	case "doctor":
+		cli.DoctorCommand(rest)
 
 	// Tags
 	case "tag", "t":
@@ -133,8 +149,40 @@ func main() {
 		cli.DuplicateCommand(rest)
 	case "info", "i":
 		cli.InfoCommand(rest)
+	case "serve":
+		cli.ServeCommand(rest)
+	case "lsp":
+		cli.LSPCommand(rest)
+	case "cat":
+		cli.CatCommand(rest)
 	case "view", "v":
 		cli.ViewCommand(rest)
+	case "history", "hist":
+		cli.HistoryCommand(rest)
+
+	// Periodic notes
+	case "today":
+		cli.TodayCommand(rest)
+	case "week":
+		cli.WeekCommand(rest)
+	case "month":
+		cli.MonthCommand(rest)
+	case "journal", "j":
+		cli.JournalCommand(rest, cli.ActionDefaults{})
+
+	// Tasks
+	case "todo", "tasks":
+		cli.TodoCommand(rest)
+
+	// Links
+	case "links", "ln":
+		cli.LinksCommand(rest)
+	case "backlinks", "bl":
+		cli.BacklinksCommand(rest)
+
+	// Notebooks
+	case "notebook", "nb":
+		cli.NotebookCommand(rest)
 
 	// Export / Import
 	case "export", "exp":
@@ -150,4 +198,8 @@ func main() {
 	default:
 		cli.NoteCommand(args[1:])
 	}
+
+	if code := cli.ExitCode(); code != 0 {
+		os.Exit(code)
+	}
 }